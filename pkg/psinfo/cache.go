@@ -0,0 +1,167 @@
+package psinfo
+
+import (
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Identity is what a Cache knows about a process.
+type Identity struct {
+	Pid         int
+	Comm        string
+	User        string
+	UID         int
+	Cgroup      string
+	ContainerID string
+}
+
+// cacheEntry is an Identity along with the time it was recorded.
+type cacheEntry struct {
+	identity   Identity
+	cgroupRead bool
+	at         time.Time
+}
+
+// Cache remembers the identity of the processes for a while, so that the
+// plugins looking processes up by pid do not each read the proc filesystem.
+// Identities are recorded from process snapshots with Update, or read from
+// the proc filesystem on a miss. A Cache is safe for concurrent use.
+type Cache struct {
+	fs  ProcFS
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[int]cacheEntry
+	users   map[int]string
+}
+
+// sharedCaches holds the caches returned by SharedCache, by proc root.
+var (
+	sharedMu     sync.Mutex
+	sharedCaches = make(map[string]*Cache)
+)
+
+// SharedCache returns the Cache of the processes of fs, updated by the ps
+// input plugin on every gather and consulted by the plugins enriching
+// metrics with process identities. The plugins reading the same proc
+// filesystem, such as /host/proc from a container, share a Cache.
+func SharedCache(fs ProcFS) *Cache {
+	root := filepath.Clean(fs.Root)
+
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+	c, ok := sharedCaches[root]
+	if !ok {
+		c = NewCache(ProcFS{Root: root}, time.Minute)
+		sharedCaches[root] = c
+	}
+	return c
+}
+
+// NewCache returns a Cache reading fs on misses and forgetting identities
+// after ttl, which bounds how long a reused pid is mistaken for the process
+// that used it before.
+func NewCache(fs ProcFS, ttl time.Duration) *Cache {
+	return &Cache{
+		fs:      fs,
+		ttl:     ttl,
+		entries: make(map[int]cacheEntry),
+		users:   make(map[int]string),
+	}
+}
+
+// Update records the identity of the processes of a snapshot, and forgets
+// the identities that expired.
+func (c *Cache) Update(processes []Process) {
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for pid, entry := range c.entries {
+		if now.Sub(entry.at) > c.ttl {
+			delete(c.entries, pid)
+		}
+	}
+	for _, process := range processes {
+		previous := c.entries[process.Pid]
+		entry := cacheEntry{
+			identity: Identity{
+				Pid:  process.Pid,
+				Comm: process.Comm,
+				User: process.Ruser,
+				UID:  process.Ruid,
+			},
+			at: now,
+		}
+		// The cgroup of a process rarely changes, so it is kept across
+		// snapshots as long as the process is the same.
+		if previous.cgroupRead && previous.identity.Comm == process.Comm {
+			entry.identity.Cgroup = previous.identity.Cgroup
+			entry.identity.ContainerID = previous.identity.ContainerID
+			entry.cgroupRead = true
+		}
+		c.entries[process.Pid] = entry
+	}
+}
+
+// Lookup returns the identity of process pid, reading the proc filesystem
+// if it is not cached. It returns false if the process does not exist.
+func (c *Cache) Lookup(pid int) (Identity, bool) {
+	now := time.Now()
+
+	c.mu.Lock()
+	entry, ok := c.entries[pid]
+	c.mu.Unlock()
+	if ok && now.Sub(entry.at) <= c.ttl && entry.cgroupRead {
+		return entry.identity, true
+	}
+
+	if !ok || now.Sub(entry.at) > c.ttl {
+		comm, err := c.fs.ReadComm(pid)
+		if err != nil {
+			return Identity{}, false
+		}
+		uid, err := c.fs.ReadUID(pid)
+		if err != nil {
+			return Identity{}, false
+		}
+		entry = cacheEntry{
+			identity: Identity{Pid: pid, Comm: comm, UID: uid, User: c.userName(uid)},
+			at:       now,
+		}
+	}
+	if cgroup, err := c.fs.ReadCgroup(pid); err == nil {
+		entry.identity.Cgroup = cgroup
+		entry.identity.ContainerID = ContainerID(cgroup)
+	}
+	entry.cgroupRead = true
+
+	c.mu.Lock()
+	c.entries[pid] = entry
+	c.mu.Unlock()
+	return entry.identity, true
+}
+
+// userName returns the name of the user uid, or the uid itself if the user
+// is unknown.
+func (c *Cache) userName(uid int) string {
+	c.mu.Lock()
+	name, ok := c.users[uid]
+	c.mu.Unlock()
+	if ok {
+		return name
+	}
+
+	name = strconv.Itoa(uid)
+	if u, err := user.LookupId(name); err == nil {
+		name = u.Username
+	}
+
+	c.mu.Lock()
+	c.users[uid] = name
+	c.mu.Unlock()
+	return name
+}
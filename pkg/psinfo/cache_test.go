@@ -0,0 +1,16 @@
+package psinfo
+
+import "testing"
+
+func TestSharedCache(t *testing.T) {
+	host := SharedCache(ProcFS{Root: "/host/proc/"})
+	if SharedCache(ProcFS{Root: "/host/proc"}) != host {
+		t.Error("the caches of /host/proc/ and /host/proc differ")
+	}
+	if SharedCache(DefaultProcFS) == host {
+		t.Error("the caches of /proc and /host/proc are shared")
+	}
+	if root := host.fs.Root; root != "/host/proc" {
+		t.Errorf("cache reading %q, expected /host/proc", root)
+	}
+}
@@ -0,0 +1,129 @@
+package psinfo
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// containerIDRe matches the 64 hexadecimal digit id of a container in a
+// cgroup path, as laid out by docker ("/docker/<id>", "docker-<id>.scope"),
+// containerd ("cri-containerd-<id>.scope"), CRI-O ("crio-<id>.scope") or
+// podman ("libpod-<id>.scope").
+var containerIDRe = regexp.MustCompile(`(?:^|[/-])([0-9a-f]{64})(?:\.scope)?(?:/|$)`)
+
+// ContainerID returns the id of the container a process belongs to, told
+// from its cgroup path, or "" if the process does not run in a container.
+func ContainerID(cgroup string) string {
+	m := containerIDRe.FindAllStringSubmatch(cgroup, -1)
+	if m == nil {
+		return ""
+	}
+	// Nested containers list the innermost one last.
+	return m[len(m)-1][1]
+}
+
+// containerRuntimes maps the prefix a runtime gives to the cgroup of its
+// containers to the name of the runtime.
+var containerRuntimes = []struct {
+	prefix  string
+	runtime string
+}{
+	{"cri-containerd-", "containerd"},
+	{"docker-", "docker"},
+	{"crio-", "cri-o"},
+	{"libpod-", "podman"},
+}
+
+// ContainerRuntime returns the runtime of the container a process belongs
+// to, told from its cgroup path: docker, containerd, cri-o or podman. It
+// returns "" if the process does not run in a container or the runtime
+// cannot be told, as with the cgroupfs driver of Kubernetes.
+func ContainerRuntime(cgroup string) string {
+	id := ContainerID(cgroup)
+	if id == "" {
+		return ""
+	}
+
+	segments := strings.Split(cgroup, "/")
+	for i := len(segments) - 1; i >= 0; i-- {
+		segment := segments[i]
+		if !strings.Contains(segment, id) {
+			continue
+		}
+		if segment == id {
+			if i > 0 && segments[i-1] == "docker" {
+				return "docker"
+			}
+			return ""
+		}
+		for _, r := range containerRuntimes {
+			if strings.HasPrefix(segment, r.prefix) {
+				return r.runtime
+			}
+		}
+		return ""
+	}
+	return ""
+}
+
+// podUIDRe matches the uid of a Kubernetes pod in a cgroup path, as laid
+// out by the cgroupfs driver ("/kubepods/burstable/pod<uid>/") or by the
+// systemd driver ("kubepods-burstable-pod<uid>.slice", with underscores in
+// place of the dashes of the uid).
+var podUIDRe = regexp.MustCompile(`kubepods.*[/-]pod([0-9a-f]{8}[-_][0-9a-f]{4}[-_][0-9a-f]{4}[-_][0-9a-f]{4}[-_][0-9a-f]{12})(?:\.slice)?(?:/|$)`)
+
+// PodUID returns the uid of the Kubernetes pod a process belongs to, told
+// from its cgroup path, or "" if the process does not run in a pod.
+func PodUID(cgroup string) string {
+	m := podUIDRe.FindStringSubmatch(cgroup)
+	if m == nil {
+		return ""
+	}
+	return strings.ReplaceAll(m[1], "_", "-")
+}
+
+// unitSuffixes are the suffixes of the names of the systemd units holding
+// processes; slices only group units.
+var unitSuffixes = []string{".service", ".scope", ".socket", ".mount", ".swap"}
+
+// SystemdUnit returns the systemd unit a process belongs to, told from its
+// cgroup path such as "/system.slice/nginx.service", or "" if the process
+// is not in a unit. Services delegating their cgroup have child cgroups
+// below the unit, which is the innermost one in the path.
+func SystemdUnit(cgroup string) string {
+	segments := strings.Split(cgroup, "/")
+	for i := len(segments) - 1; i >= 0; i-- {
+		for _, suffix := range unitSuffixes {
+			if strings.HasSuffix(segments[i], suffix) {
+				return segments[i]
+			}
+		}
+	}
+	return ""
+}
+
+// DefaultCgroupRoot is the mount point of the unified cgroup hierarchy.
+const DefaultCgroupRoot = "/sys/fs/cgroup"
+
+// ReadCgroupValue returns the integer held by the interface file name, such
+// as memory.current, of the cgroup at path in the unified hierarchy mounted
+// at root.
+func ReadCgroupValue(root, path, name string) (int64, error) {
+	data, err := ioutil.ReadFile(filepath.Join(root, path, name))
+	if os.IsPermission(err) {
+		return 0, fmt.Errorf("%w: %v", ErrPermission, err)
+	}
+	if err != nil {
+		return 0, err
+	}
+	value, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s of cgroup %s: %v", ErrParse, name, path, err)
+	}
+	return value, nil
+}
@@ -0,0 +1,20 @@
+package psinfo
+
+import "errors"
+
+// Failure classes of process collection. Errors returned by this package,
+// and by the ps plugin, wrap one of them so that callers can tell failures
+// apart with errors.Is.
+var (
+	// ErrExecFailed is wrapped when a command could not be run or exited
+	// with an error.
+	ErrExecFailed = errors.New("command execution failed")
+	// ErrParse is wrapped when the output of a command could not be
+	// understood.
+	ErrParse = errors.New("unable to parse process information")
+	// ErrTimeout is wrapped when a command did not complete in time.
+	ErrTimeout = errors.New("command timed out")
+	// ErrPermission is wrapped when process information could not be read
+	// for lack of privileges.
+	ErrPermission = errors.New("permission denied")
+)
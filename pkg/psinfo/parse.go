@@ -0,0 +1,303 @@
+package psinfo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Column is a ps output column together with the function storing its
+// value in a Process.
+type Column struct {
+	Spec  string
+	Store func(p *Process, value string) error
+}
+
+// Columns known to the parser. The free-text ColumnComm and ColumnArgs must
+// be requested last, in that order, so that every other column is a single
+// whitespace delimited token.
+var (
+	ColumnPid    = Column{"pid", func(p *Process, v string) error { return storeInt(&p.Pid, v) }}
+	ColumnPpid   = Column{"ppid", func(p *Process, v string) error { return storeInt(&p.Ppid, v) }}
+	ColumnNlwp   = Column{"nlwp", func(p *Process, v string) error { return storeInt(&p.Nlwp, v) }}
+	ColumnRss    = Column{"rss", func(p *Process, v string) error { return storeInt(&p.Rss, v) }}
+	ColumnVsz    = Column{"vsz", func(p *Process, v string) error { return storeInt(&p.Vsz, v) }}
+	ColumnMem    = Column{"%mem", func(p *Process, v string) error { return storeFloat(&p.Mem, v) }}
+	ColumnCPU    = Column{"%cpu", func(p *Process, v string) error { return storeFloat(&p.CPU, v) }}
+	ColumnPsr    = Column{"psr", func(p *Process, v string) error { return storeInt(&p.Psr, v) }}
+	ColumnRuser  = Column{"ruser", func(p *Process, v string) error { p.Ruser = v; return nil }}
+	ColumnRuid   = Column{"ruid", func(p *Process, v string) error { return storeInt(&p.Ruid, v) }}
+	ColumnEuser  = Column{"euser", func(p *Process, v string) error { p.Euser = v; return nil }}
+	ColumnEuid   = Column{"euid", func(p *Process, v string) error { return storeInt(&p.Euid, v) }}
+	ColumnEtimes = Column{"etimes", func(p *Process, v string) error { return storeInt(&p.Etimes, v) }}
+	ColumnNice   = Column{"ni", func(p *Process, v string) error { return storeNice(&p.Nice, v) }}
+	ColumnPri    = Column{"priority", func(p *Process, v string) error { return storeInt(&p.Priority, v) }}
+	ColumnPolicy = Column{"policy", func(p *Process, v string) error { return storePolicy(&p.Policy, v) }}
+	ColumnTty    = Column{"tty", func(p *Process, v string) error { return storeTty(&p.Tty, v) }}
+	ColumnSid    = Column{"sid", func(p *Process, v string) error { return storeInt(&p.Sid, v) }}
+	ColumnPgid   = Column{"pgid", func(p *Process, v string) error { return storeInt(&p.Pgid, v) }}
+	ColumnStat   = Column{"stat", func(p *Process, v string) error { p.Stat = v; return nil }}
+	ColumnTime   = Column{"time", func(p *Process, v string) error { return storeCPUTime(&p.CPUTime, v) }}
+	ColumnComm   = Column{"comm", func(p *Process, v string) error { p.Comm = Sanitize(v); return nil }}
+	ColumnArgs   = Column{"args", func(p *Process, v string) error { p.Args = Sanitize(v); return nil }}
+)
+
+// ExtraColumn returns a column unknown to the parser whose raw value is
+// stored in Process.Extra under spec. Its values must be single whitespace
+// delimited tokens, as those of the known columns other than ColumnComm and
+// ColumnArgs.
+func ExtraColumn(spec string) Column {
+	return Column{spec, func(p *Process, v string) error {
+		if p.Extra == nil {
+			p.Extra = make(map[string]string)
+		}
+		p.Extra[spec] = Sanitize(v)
+		return nil
+	}}
+}
+
+// FormatColumns returns the ps -o argument requesting columns without
+// headers.
+func FormatColumns(columns []Column) string {
+	specs := make([]string, 0, len(columns))
+	for _, c := range columns {
+		specs = append(specs, c.Spec+"=")
+	}
+	return strings.Join(specs, ",")
+}
+
+// FixedWidth is the width, in characters, of every column but the last
+// requested by FormatFixedColumns.
+const FixedWidth = 64
+
+// FormatFixedColumns returns the ps -o argument requesting columns without
+// headers, each padded to FixedWidth characters but the last, for the ps
+// flavours supporting widths, such as procps-ng.
+func FormatFixedColumns(columns []Column) string {
+	specs := make([]string, 0, len(columns))
+	for i, c := range columns {
+		if i == len(columns)-1 {
+			specs = append(specs, c.Spec+"=")
+			continue
+		}
+		specs = append(specs, c.Spec+":"+strconv.Itoa(FixedWidth)+"=")
+	}
+	return strings.Join(specs, ",")
+}
+
+// MaxLineLength is the length in bytes beyond which a line of ps output is
+// cut. Only the trailing arguments of huge command lines are lost.
+const MaxLineLength = 64 * 1024
+
+// Parse returns the processes described by the ps output in, whose lines
+// hold the given columns. Lines that cannot be parsed are skipped, so that
+// a single malformed or oversized line never discards the whole output; an
+// error wrapping ErrParse is returned only when no line could be parsed.
+func Parse(in string, columns []Column) ([]Process, error) {
+	processes, _, err := ParseDropped(in, columns)
+	return processes, err
+}
+
+// ParseDropped is like Parse, and also returns the number of lines skipped
+// for not holding the given columns. Blank lines are not counted.
+func ParseDropped(in string, columns []Column) ([]Process, int, error) {
+	return parse(in, columns, splitColumns)
+}
+
+// ParseFixed is like ParseDropped for the output of ps requesting the
+// columns formatted by FormatFixedColumns. Values are cut at the fixed
+// offsets of the columns rather than at whitespace, so any of them may
+// hold spaces.
+func ParseFixed(in string, columns []Column) ([]Process, int, error) {
+	return parse(in, columns, splitFixed)
+}
+
+// parse returns the processes described by in, whose lines are split into
+// the values of columns by split, and the number of lines skipped.
+func parse(in string, columns []Column, split func(line string, n int) []string) ([]Process, int, error) {
+	var processes []Process
+	var dropped int
+	for _, line := range strings.Split(in, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if len(line) > MaxLineLength {
+			line = line[:MaxLineLength]
+		}
+		process, err := parseValues(split(line, len(columns)), columns)
+		if err != nil {
+			dropped++
+			continue
+		}
+		processes = append(processes, *process)
+	}
+	if len(processes) == 0 && dropped > 0 {
+		return nil, dropped, fmt.Errorf("%w: no line matches columns %s", ErrParse, FormatColumns(columns))
+	}
+
+	return processes, dropped, nil
+}
+
+// ParseLine returns the process described by a single line of ps output
+// holding the given columns, or an error wrapping ErrParse.
+func ParseLine(line string, columns []Column) (*Process, error) {
+	return parseValues(splitColumns(line, len(columns)), columns)
+}
+
+// parseValues returns the process whose values of columns are values.
+func parseValues(values []string, columns []Column) (*Process, error) {
+	if len(values) != len(columns) {
+		return nil, fmt.Errorf("%w: expected %d columns, found %d", ErrParse, len(columns), len(values))
+	}
+
+	var process Process
+	for i, c := range columns {
+		if err := c.Store(&process, values[i]); err != nil {
+			return nil, fmt.Errorf("%w: column %s: %v", ErrParse, c.Spec, err)
+		}
+	}
+
+	return &process, nil
+}
+
+// splitColumns splits line into at most n whitespace delimited values. The
+// last value holds the remainder of the line, inner whitespace included.
+func splitColumns(line string, n int) []string {
+	var values []string
+	rest := strings.TrimSpace(line)
+	for len(values) < n-1 && rest != "" {
+		end := strings.IndexFunc(rest, unicode.IsSpace)
+		if end < 0 {
+			end = len(rest)
+		}
+		values = append(values, rest[:end])
+		rest = strings.TrimLeftFunc(rest[end:], unicode.IsSpace)
+	}
+	if rest != "" {
+		values = append(values, rest)
+	}
+	return values
+}
+
+// splitFixed splits line into at most n values of FixedWidth characters
+// separated by a space, trimmed of their padding. The last value holds the
+// remainder of the line.
+func splitFixed(line string, n int) []string {
+	var values []string
+	rest := []rune(line)
+	for len(values) < n-1 && len(rest) > FixedWidth {
+		values = append(values, strings.TrimSpace(string(rest[:FixedWidth])))
+		rest = rest[FixedWidth+1:]
+	}
+	if value := strings.TrimSpace(string(rest)); value != "" {
+		values = append(values, value)
+	}
+	return values
+}
+
+// storeInt parses value as a decimal integer into dst.
+func storeInt(dst *int, value string) error {
+	var err error
+	*dst, err = strconv.Atoi(value)
+	return err
+}
+
+// storeFloat parses value as a floating point number into dst.
+func storeFloat(dst *float64, value string) error {
+	var err error
+	*dst, err = strconv.ParseFloat(value, 64)
+	return err
+}
+
+// storeNice parses value as a nice value into dst. Realtime processes have
+// no nice value and show "-", left as zero.
+func storeNice(dst *int, value string) error {
+	if value == "-" {
+		return nil
+	}
+	return storeInt(dst, value)
+}
+
+// policyAbbreviations maps the scheduling policies as shown by the policy
+// column of procps-ng to their names.
+var policyAbbreviations = map[string]string{
+	"TS":  "SCHED_OTHER",
+	"FF":  "SCHED_FIFO",
+	"RR":  "SCHED_RR",
+	"B":   "SCHED_BATCH",
+	"ISO": "SCHED_ISO",
+	"IDL": "SCHED_IDLE",
+	"DLN": "SCHED_DEADLINE",
+}
+
+// storePolicy stores into dst the name of the scheduling policy shown as
+// value, or value itself if it is unknown. Nothing is stored for the "-"
+// of processes without a policy.
+func storePolicy(dst *string, value string) error {
+	if value == "-" {
+		return nil
+	}
+	if name, ok := policyAbbreviations[value]; ok {
+		value = name
+	}
+	*dst = value
+	return nil
+}
+
+// storeTty stores into dst the terminal shown as value. Nothing is stored
+// for the "?" of procps-ng and busybox, the "??" of BSD or the "-" marking
+// processes without a terminal.
+func storeTty(dst *string, value string) error {
+	switch value {
+	case "?", "??", "-":
+		return nil
+	}
+	*dst = value
+	return nil
+}
+
+// storeCPUTime parses value, a cpu time formatted as [[dd-]hh:]mm:ss with
+// optional fractional seconds, into dst as seconds.
+func storeCPUTime(dst *float64, value string) error {
+	var days float64
+	if i := strings.IndexByte(value, '-'); i >= 0 {
+		d, err := strconv.ParseFloat(value[:i], 64)
+		if err != nil {
+			return err
+		}
+		days = d
+		value = value[i+1:]
+	}
+
+	var seconds float64
+	for _, part := range strings.Split(value, ":") {
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return err
+		}
+		seconds = seconds*60 + n
+	}
+	*dst = days*24*3600 + seconds
+	return nil
+}
+
+// Sanitize returns s with every byte that is not part of a valid UTF-8
+// sequence, and every control character, replaced by a \xNN escape, so that
+// odd command names cannot produce invalid line protocol.
+func Sanitize(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if (r == utf8.RuneError && size == 1) || unicode.IsControl(r) {
+			for _, c := range []byte(s[i : i+size]) {
+				fmt.Fprintf(&b, `\x%02x`, c)
+			}
+		} else {
+			b.WriteString(s[i : i+size])
+		}
+		i += size
+	}
+	return b.String()
+}
@@ -0,0 +1,111 @@
+package psinfo
+
+import (
+	"strings"
+	"testing"
+)
+
+// fuzzColumns are the columns the fuzz targets parse lines with: every
+// column known to the parser, and a column unknown to it.
+var fuzzColumns = []Column{
+	ColumnPid,
+	ColumnPpid,
+	ColumnNlwp,
+	ColumnRss,
+	ColumnVsz,
+	ColumnMem,
+	ColumnCPU,
+	ColumnPsr,
+	ColumnRuser,
+	ColumnRuid,
+	ColumnEuser,
+	ColumnEuid,
+	ColumnEtimes,
+	ColumnNice,
+	ColumnPri,
+	ColumnPolicy,
+	ColumnTty,
+	ColumnSid,
+	ColumnPgid,
+	ExtraColumn("wchan"),
+	ColumnStat,
+	ColumnTime,
+	ColumnComm,
+	ColumnArgs,
+}
+
+// fuzzLines are the seed lines of the fuzz targets, holding the values of
+// fuzzColumns.
+var fuzzLines = []string{
+	"1 0 1 11264 169352 0.1 0.0 3 root 0 root 0 86400 0 20 TS ? 1 1 ep_poll Ss 00:00:05 systemd /sbin/init splash",
+	"2 0 1 0 0 0.0 0.0 0 root 0 root 0 86400 0 20 TS ? 0 0 - S 00:00:00 kthreadd [kthreadd]",
+	"4242 1 12 524288 2097152 3.2 45.5 7 www-data 33 www-data 33 3600 -5 15 FF pts/3 4242 4242 - Rl+ 1-02:03:04.5 nginx nginx: worker process",
+	"7 1 1 1 1 1 1 1 u 1 u 1 1 - - - - 1 1 x D 01:02 com\x1bm ar\xffgs\twith\x00control",
+	"",
+	"   ",
+	"garbage",
+}
+
+// sanitized reports whether s is free of invalid UTF-8 and control
+// characters, as left by Sanitize.
+func sanitized(s string) bool {
+	return Sanitize(s) == s
+}
+
+// checkSanitized fails t if the free-text attributes of p are not
+// sanitized.
+func checkSanitized(t *testing.T, p Process) {
+	t.Helper()
+	if !sanitized(p.Comm) {
+		t.Errorf("comm %q is not sanitized", p.Comm)
+	}
+	if !sanitized(p.Args) {
+		t.Errorf("args %q are not sanitized", p.Args)
+	}
+	for spec, value := range p.Extra {
+		if !sanitized(value) {
+			t.Errorf("column %s value %q is not sanitized", spec, value)
+		}
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	f.Add(strings.Join(fuzzLines, "\n"))
+	for _, line := range fuzzLines {
+		f.Add(line)
+	}
+	f.Add(FormatFixedColumns(fuzzColumns))
+	f.Add(strings.Repeat("1"+strings.Repeat(" ", FixedWidth), len(fuzzColumns)))
+
+	f.Fuzz(func(t *testing.T, in string) {
+		processes, _, err := ParseDropped(in, fuzzColumns)
+		if err == nil && len(processes) == 0 && strings.TrimSpace(in) != "" {
+			t.Errorf("no process nor error for %q", in)
+		}
+		for _, p := range processes {
+			checkSanitized(t, p)
+		}
+
+		processes, _, _ = ParseFixed(in, fuzzColumns)
+		for _, p := range processes {
+			checkSanitized(t, p)
+		}
+	})
+}
+
+func FuzzParseLine(f *testing.F) {
+	for _, line := range fuzzLines {
+		f.Add(line)
+	}
+
+	f.Fuzz(func(t *testing.T, line string) {
+		p, err := ParseLine(line, fuzzColumns)
+		if err != nil {
+			if p != nil {
+				t.Errorf("process %+v returned with error %v", p, err)
+			}
+			return
+		}
+		checkSanitized(t, *p)
+	})
+}
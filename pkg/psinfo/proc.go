@@ -0,0 +1,328 @@
+package psinfo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// ProcFS reads process information from a proc filesystem mounted at Root.
+// Any directory laid out like /proc, such as a synthetic tree built on a
+// tmpfs, can be read in place of the host's.
+type ProcFS struct {
+	Root string
+}
+
+// DefaultProcFS reads the proc filesystem of the host.
+var DefaultProcFS = ProcFS{Root: "/proc"}
+
+// ReadEnviron returns the environment of process pid as read from
+// /proc/<pid>/environ of the host. See ProcFS.ReadEnviron.
+func ReadEnviron(pid int) ([]string, error) {
+	return DefaultProcFS.ReadEnviron(pid)
+}
+
+// ReadEnviron returns the environment of process pid, one KEY=VALUE entry
+// per variable in their original order.
+func (fs ProcFS) ReadEnviron(pid int) ([]string, error) {
+	data, err := fs.readFile(pid, "environ")
+	if err != nil {
+		return nil, err
+	}
+
+	var env []string
+	for _, entry := range bytes.Split(data, []byte{0}) {
+		if len(entry) > 0 {
+			env = append(env, string(entry))
+		}
+	}
+	return env, nil
+}
+
+// readFile returns the content of the file name in the directory of process
+// pid. Errors caused by missing privileges wrap ErrPermission.
+func (fs ProcFS) readFile(pid int, name string) ([]byte, error) {
+	path := filepath.Join(fs.Root, strconv.Itoa(pid), name)
+	data, err := ioutil.ReadFile(path)
+	if os.IsPermission(err) {
+		return nil, fmt.Errorf("%w: %v", ErrPermission, err)
+	}
+	return data, err
+}
+
+// Pids returns the ids of the processes listed in the proc filesystem.
+func (fs ProcFS) Pids() ([]int, error) {
+	entries, err := ioutil.ReadDir(fs.Root)
+	if err != nil {
+		return nil, err
+	}
+
+	var pids []int
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+		pids = append(pids, pid)
+	}
+	return pids, nil
+}
+
+// ReadComm returns the command name of process pid.
+func (fs ProcFS) ReadComm(pid int) (string, error) {
+	data, err := fs.readFile(pid, "comm")
+	if err != nil {
+		return "", err
+	}
+	return Sanitize(strings.TrimSuffix(string(data), "\n")), nil
+}
+
+// CountFDs returns the number of file descriptors process pid has open.
+func (fs ProcFS) CountFDs(pid int) (int, error) {
+	dir, err := os.Open(filepath.Join(fs.Root, strconv.Itoa(pid), "fd"))
+	if os.IsPermission(err) {
+		return 0, fmt.Errorf("%w: %v", ErrPermission, err)
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer dir.Close()
+
+	names, err := dir.Readdirnames(-1)
+	if err != nil {
+		return 0, err
+	}
+	return len(names), nil
+}
+
+// DeletedSuffix is appended by Linux to the target of /proc/<pid>/exe when
+// the executable was deleted or replaced since the process started.
+const DeletedSuffix = " (deleted)"
+
+// ReadExe returns the path of the executable of process pid, ending with
+// DeletedSuffix when the file is gone.
+func (fs ProcFS) ReadExe(pid int) (string, error) {
+	target, err := os.Readlink(filepath.Join(fs.Root, strconv.Itoa(pid), "exe"))
+	if os.IsPermission(err) {
+		return "", fmt.Errorf("%w: %v", ErrPermission, err)
+	}
+	return target, err
+}
+
+// OpenExe opens the executable of process pid through /proc/<pid>/exe,
+// which refers to the file the process runs even after it was replaced or
+// deleted.
+func (fs ProcFS) OpenExe(pid int) (*os.File, error) {
+	file, err := os.Open(filepath.Join(fs.Root, strconv.Itoa(pid), "exe"))
+	if os.IsPermission(err) {
+		return nil, fmt.Errorf("%w: %v", ErrPermission, err)
+	}
+	return file, err
+}
+
+// ReadFDTargets returns what each open file descriptor of process pid
+// refers to, such as a path, "socket:[inode]" or "pipe:[inode]".
+// Descriptors closed while they are being read are left out.
+func (fs ProcFS) ReadFDTargets(pid int) ([]string, error) {
+	dir := filepath.Join(fs.Root, strconv.Itoa(pid), "fd")
+	entries, err := ioutil.ReadDir(dir)
+	if os.IsPermission(err) {
+		return nil, fmt.Errorf("%w: %v", ErrPermission, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		target, err := os.Readlink(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+// ReadCmdline returns the command line of process pid with its arguments
+// separated by spaces. It is empty for kernel threads.
+func (fs ProcFS) ReadCmdline(pid int) (string, error) {
+	data, err := fs.readFile(pid, "cmdline")
+	if err != nil {
+		return "", err
+	}
+	args := strings.Split(strings.TrimRight(string(data), "\x00"), "\x00")
+	return Sanitize(strings.Join(args, " ")), nil
+}
+
+// ReadOOMScore returns the badness score of process pid, from 0 to 1000:
+// the higher the score, the likelier the kernel kills the process when it
+// runs out of memory.
+func (fs ProcFS) ReadOOMScore(pid int) (int, error) {
+	return fs.readInt(pid, "oom_score")
+}
+
+// ReadOOMScoreAdj returns the adjustment, from -1000 to 1000, added to the
+// badness score of process pid. -1000 exempts the process from being
+// killed.
+func (fs ProcFS) ReadOOMScoreAdj(pid int) (int, error) {
+	return fs.readInt(pid, "oom_score_adj")
+}
+
+// readInt returns the integer held by the file name in the directory of
+// process pid.
+func (fs ProcFS) readInt(pid int, name string) (int, error) {
+	data, err := fs.readFile(pid, name)
+	if err != nil {
+		return 0, err
+	}
+	value, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s of process %d: %v", ErrParse, name, pid, err)
+	}
+	return value, nil
+}
+
+// ReadUID returns the real user id of process pid.
+func (fs ProcFS) ReadUID(pid int) (int, error) {
+	status, err := fs.ReadStatus(pid)
+	if err != nil {
+		return 0, err
+	}
+	return status.Ruid, nil
+}
+
+// ReadCgroup returns the cgroup of process pid: its path in the unified
+// hierarchy of cgroup v2, or else in the first v1 hierarchy listed.
+func (fs ProcFS) ReadCgroup(pid int) (string, error) {
+	return fs.readCgroup(pid, "")
+}
+
+// ReadSystemdCgroup returns the cgroup systemd placed process pid in: its
+// path in the unified hierarchy of cgroup v2, or else in the name=systemd
+// v1 hierarchy, as the paths of some v1 controllers do not follow the
+// units.
+func (fs ProcFS) ReadSystemdCgroup(pid int) (string, error) {
+	return fs.readCgroup(pid, "name=systemd")
+}
+
+// ReadUnifiedCgroup returns the path of process pid in the unified
+// hierarchy of cgroup v2, or "" if the host does not mount it.
+func (fs ProcFS) ReadUnifiedCgroup(pid int) (string, error) {
+	data, err := fs.readFile(pid, "cgroup")
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if strings.HasPrefix(line, "0::") {
+			return strings.TrimPrefix(line, "0::"), nil
+		}
+	}
+	return "", nil
+}
+
+// readCgroup returns the path of process pid in the unified hierarchy of
+// cgroup v2, or else in the v1 hierarchy of controllers, or else in the
+// first v1 hierarchy listed.
+func (fs ProcFS) readCgroup(pid int, controllers string) (string, error) {
+	data, err := fs.readFile(pid, "cgroup")
+	if err != nil {
+		return "", err
+	}
+
+	var first, named string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		// Lines look like "hierarchy-ID:controllers:path".
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if parts[0] == "0" && parts[1] == "" {
+			return parts[2], nil
+		}
+		if first == "" {
+			first = parts[2]
+		}
+		if controllers != "" && parts[1] == controllers {
+			named = parts[2]
+		}
+	}
+	if named != "" {
+		return named, nil
+	}
+	return first, nil
+}
+
+// ReadState returns the state of process pid, such as "R", "S" or "D", as
+// read from /proc/<pid>/stat.
+func (fs ProcFS) ReadState(pid int) (string, error) {
+	stat, err := fs.ReadStat(pid)
+	if err != nil {
+		return "", err
+	}
+	return stat.State, nil
+}
+
+// ReadSecurityContext returns the security context the Linux security
+// module gives process pid, such as "system_u:system_r:httpd_t:s0" with
+// SELinux or "/usr/sbin/cupsd (enforce)" with AppArmor, or "" on hosts
+// without such a module.
+func (fs ProcFS) ReadSecurityContext(pid int) (string, error) {
+	data, err := fs.readFile(pid, filepath.Join("attr", "current"))
+	if err != nil {
+		// Linux fails with EINVAL when no module provides a context.
+		if errors.Is(err, syscall.EINVAL) {
+			return "", nil
+		}
+		return "", err
+	}
+	return Sanitize(strings.TrimRight(string(data), "\x00\n")), nil
+}
+
+// ReadWchan returns the kernel function process pid is blocked in, its
+// wait channel, or "" when it is not waiting. Linux hides the wait channel
+// of the processes the reader may not trace, which then read as not
+// waiting.
+func (fs ProcFS) ReadWchan(pid int) (string, error) {
+	data, err := fs.readFile(pid, "wchan")
+	if err != nil {
+		return "", err
+	}
+	wchan := strings.TrimSpace(string(data))
+	if wchan == "0" {
+		return "", nil
+	}
+	return wchan, nil
+}
+
+// ReadStack returns the kernel stack of process pid, innermost frame first,
+// with the offsets stripped from the function names. Reading it requires
+// the CAP_SYS_ADMIN capability.
+func (fs ProcFS) ReadStack(pid int) ([]string, error) {
+	data, err := fs.readFile(pid, "stack")
+	if err != nil {
+		return nil, err
+	}
+
+	var frames []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		// Lines look like "[<0>] io_schedule+0x12/0x40".
+		if i := strings.IndexByte(line, ']'); i >= 0 {
+			line = line[i+1:]
+		}
+		line = strings.TrimSpace(line)
+		if i := strings.IndexByte(line, '+'); i >= 0 {
+			line = line[:i]
+		}
+		if line != "" {
+			frames = append(frames, line)
+		}
+	}
+	return frames, nil
+}
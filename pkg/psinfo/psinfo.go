@@ -0,0 +1,57 @@
+// Package psinfo builds snapshots of the processes running on a host. It
+// holds the process model and parsers shared by the ps input plugin and
+// any other tool that needs the same view of the process table.
+package psinfo
+
+// Process holds the information collected about a single process. Only the
+// attributes present in the original ps plugin output are encoded to JSON.
+type Process struct {
+	Pid   int     `json:"pid"`
+	Ppid  int     `json:"ppid"`
+	Comm  string  `json:"command"`
+	Args  string  `json:"args"`
+	Nlwp  int     `json:"threads"`
+	Rss   int     `json:"rss"`
+	Vsz   int     `json:"vsize"`
+	Mem   float64 `json:"mem"`
+	CPU   float64 `json:"cpu"`
+	Psr   int     `json:"processor"`
+	Ruser string  `json:"user"`
+	Stat  string  `json:"status"`
+
+	Ruid   int    `json:"-"`
+	Euser  string `json:"-"`
+	Euid   int    `json:"-"`
+	Etimes int    `json:"-"`
+
+	// CPUTime is the user and system cpu time used by the process, in
+	// seconds.
+	CPUTime float64 `json:"-"`
+
+	// Nice and Priority are the nice value and the kernel priority of the
+	// process, and Policy its scheduling policy, such as SCHED_OTHER.
+	Nice     int    `json:"-"`
+	Priority int    `json:"-"`
+	Policy   string `json:"-"`
+
+	// Tty is the controlling terminal of the process, such as pts/0, empty
+	// for processes without one. Sid and Pgid are the ids of its session
+	// and process group.
+	Tty  string `json:"-"`
+	Sid  int    `json:"-"`
+	Pgid int    `json:"-"`
+
+	// Started is the time the process started, in seconds since the
+	// epoch, when known exactly rather than from Etimes.
+	Started int64 `json:"-"`
+
+	// Extra holds the raw values of the columns requested with
+	// ExtraColumn, by column name.
+	Extra map[string]string `json:"-"`
+
+	// ProcStatus and ProcStat are the /proc/<pid>/status and
+	// /proc/<pid>/stat of the process, when read, so that they are read
+	// once however many attributes are taken from them.
+	ProcStatus *Status `json:"-"`
+	ProcStat   *Stat   `json:"-"`
+}
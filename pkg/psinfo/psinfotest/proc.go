@@ -0,0 +1,205 @@
+// Package psinfotest builds synthetic proc filesystems, so that the readers
+// of /proc can be tested against processes made up for the purpose.
+package psinfotest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gpapag/telegraf-plugins/pkg/psinfo"
+)
+
+// Host holds the attributes of the host of a synthetic proc filesystem.
+type Host struct {
+	Uptime   float64 // seconds
+	BootTime int64   // seconds since the epoch
+	MemTotal int64   // KiB
+}
+
+// Process describes a process of a synthetic proc filesystem. Its
+// attributes are written as given, but for State and Threads, which default
+// to "S" and 1, and Priority, which defaults to 20.
+type Process struct {
+	Pid  int
+	Ppid int
+	Comm string
+	// Args is the command line, empty for kernel threads.
+	Args []string
+
+	State   string
+	Ruid    int
+	Euid    int
+	Threads int
+	Pgrp    int
+	Session int
+	TtyNr   int
+	// Tpgid is the foreground process group of the terminal of the
+	// process, written as -1 when zero.
+	Tpgid    int
+	Priority int
+	Nice     int
+
+	Utime     uint64 // clock ticks
+	Stime     uint64 // clock ticks
+	StartTime uint64 // clock ticks since boot
+	Vsize     uint64 // bytes
+	Rss       int64  // pages
+	Processor int
+
+	VoluntaryCtxtSwitches    uint64
+	NonvoluntaryCtxtSwitches uint64
+	Minflt                   uint64
+	Majflt                   uint64
+
+	IO psinfo.IO
+	// Cgroup is the path of the process in the unified hierarchy, none
+	// when empty.
+	Cgroup string
+	// FDs are the targets of the open file descriptors, such as
+	// "/dev/null" or "socket:[1234]".
+	FDs []string
+	// Stack is the kernel stack, innermost function first, empty for a
+	// process running in user space.
+	Stack []string
+}
+
+// Proc is a synthetic proc filesystem in a temporary directory of a test.
+type Proc struct {
+	t    testing.TB
+	Root string
+}
+
+// NewProc returns a proc filesystem holding the files describing host, in
+// a directory removed when the test t ends.
+func NewProc(t testing.TB, host Host) *Proc {
+	t.Helper()
+	p := &Proc{t: t, Root: t.TempDir()}
+	p.write("uptime", fmt.Sprintf("%.2f %.2f\n", host.Uptime, host.Uptime))
+	p.write("stat", fmt.Sprintf("cpu  0 0 0 0 0 0 0 0 0 0\nbtime %d\n", host.BootTime))
+	p.write("meminfo", fmt.Sprintf("MemTotal:       %d kB\nMemFree:        0 kB\n", host.MemTotal))
+	return p
+}
+
+// ProcFS returns a psinfo.ProcFS reading p.
+func (p *Proc) ProcFS() psinfo.ProcFS {
+	return psinfo.ProcFS{Root: p.Root}
+}
+
+// Add writes the stat, status, cmdline, io, cgroup, stack and fd files of
+// process, replacing those of any process with the same pid.
+func (p *Proc) Add(process Process) {
+	p.t.Helper()
+	if process.State == "" {
+		process.State = "S"
+	}
+	if process.Threads == 0 {
+		process.Threads = 1
+	}
+	if process.Priority == 0 {
+		process.Priority = 20
+	}
+
+	dir := strconv.Itoa(process.Pid)
+	if err := os.RemoveAll(filepath.Join(p.Root, dir)); err != nil {
+		p.t.Fatal(err)
+	}
+	p.write(filepath.Join(dir, "stat"), stat(process))
+	p.write(filepath.Join(dir, "status"), status(process))
+
+	var cmdline string
+	for _, arg := range process.Args {
+		cmdline += arg + "\x00"
+	}
+	p.write(filepath.Join(dir, "cmdline"), cmdline)
+	p.write(filepath.Join(dir, "comm"), process.Comm+"\n")
+
+	p.write(filepath.Join(dir, "io"), fmt.Sprintf(
+		"rchar: 0\nwchar: 0\nsyscr: %d\nsyscw: %d\nread_bytes: %d\nwrite_bytes: %d\ncancelled_write_bytes: 0\n",
+		process.IO.Syscr, process.IO.Syscw, process.IO.ReadBytes, process.IO.WriteBytes))
+
+	var cgroup string
+	if process.Cgroup != "" {
+		cgroup = "0::" + process.Cgroup + "\n"
+	}
+	p.write(filepath.Join(dir, "cgroup"), cgroup)
+
+	var stack string
+	for _, frame := range process.Stack {
+		stack += "[<0>] " + frame + "+0x0/0x0\n"
+	}
+	p.write(filepath.Join(dir, "stack"), stack)
+
+	fds := filepath.Join(p.Root, dir, "fd")
+	if err := os.MkdirAll(fds, 0755); err != nil {
+		p.t.Fatal(err)
+	}
+	for fd, target := range process.FDs {
+		if err := os.Symlink(target, filepath.Join(fds, strconv.Itoa(fd))); err != nil {
+			p.t.Fatal(err)
+		}
+	}
+}
+
+// Remove deletes the directory of process pid, as when it exits.
+func (p *Proc) Remove(pid int) {
+	p.t.Helper()
+	if err := os.RemoveAll(filepath.Join(p.Root, strconv.Itoa(pid))); err != nil {
+		p.t.Fatal(err)
+	}
+}
+
+// write writes content to the file name of p, creating its directory.
+func (p *Proc) write(name, content string) {
+	p.t.Helper()
+	path := filepath.Join(p.Root, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		p.t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		p.t.Fatal(err)
+	}
+}
+
+// stat returns the content of /proc/<pid>/stat for process.
+func stat(process Process) string {
+	// The fields after the command name, from the state on, as numbered by
+	// proc(5) minus 3.
+	fields := make([]string, 49)
+	for i := range fields {
+		fields[i] = "0"
+	}
+	fields[0] = process.State
+	fields[1] = strconv.Itoa(process.Ppid)
+	fields[2] = strconv.Itoa(process.Pgrp)
+	fields[3] = strconv.Itoa(process.Session)
+	fields[4] = strconv.Itoa(process.TtyNr)
+	fields[5] = "-1"
+	if process.Tpgid != 0 {
+		fields[5] = strconv.Itoa(process.Tpgid)
+	}
+	fields[7] = strconv.FormatUint(process.Minflt, 10)
+	fields[9] = strconv.FormatUint(process.Majflt, 10)
+	fields[11] = strconv.FormatUint(process.Utime, 10)
+	fields[12] = strconv.FormatUint(process.Stime, 10)
+	fields[15] = strconv.Itoa(process.Priority)
+	fields[16] = strconv.Itoa(process.Nice)
+	fields[17] = strconv.Itoa(process.Threads)
+	fields[19] = strconv.FormatUint(process.StartTime, 10)
+	fields[20] = strconv.FormatUint(process.Vsize, 10)
+	fields[21] = strconv.FormatInt(process.Rss, 10)
+	fields[36] = strconv.Itoa(process.Processor)
+	return fmt.Sprintf("%d (%s) %s\n", process.Pid, process.Comm, strings.Join(fields, " "))
+}
+
+// status returns the content of /proc/<pid>/status for process.
+func status(process Process) string {
+	return fmt.Sprintf("Name:\t%s\nState:\t%s\nPPid:\t%d\nUid:\t%d\t%d\t%d\t%d\nThreads:\t%d\nvoluntary_ctxt_switches:\t%d\nnonvoluntary_ctxt_switches:\t%d\n",
+		process.Comm, process.State, process.Ppid,
+		process.Ruid, process.Euid, process.Euid, process.Euid,
+		process.Threads, process.VoluntaryCtxtSwitches, process.NonvoluntaryCtxtSwitches)
+}
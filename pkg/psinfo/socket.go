@@ -0,0 +1,91 @@
+package psinfo
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// socketTables lists the tables of /proc/<pid>/net naming the sockets of a
+// network namespace, with the protocol of their sockets and the column
+// holding the inode of each socket.
+var socketTables = []struct {
+	name     string
+	protocol string
+	column   int
+}{
+	{"tcp", "tcp", 9},
+	{"tcp6", "tcp", 9},
+	{"udp", "udp", 9},
+	{"udp6", "udp", 9},
+	{"unix", "unix", 6},
+}
+
+// SocketInode returns the inode of the socket a file descriptor refers to,
+// given its target as returned by ReadFDTargets, or false if it does not
+// refer to a socket.
+func SocketInode(target string) (uint64, bool) {
+	if !strings.HasPrefix(target, "socket:[") || !strings.HasSuffix(target, "]") {
+		return 0, false
+	}
+	inode, err := strconv.ParseUint(target[len("socket:["):len(target)-1], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return inode, true
+}
+
+// ReadNetNamespace returns the network namespace of process pid, such as
+// "net:[4026531992]", which tells the processes seeing the same sockets.
+func (fs ProcFS) ReadNetNamespace(pid int) (string, error) {
+	ns, err := os.Readlink(filepath.Join(fs.Root, strconv.Itoa(pid), "ns", "net"))
+	if os.IsPermission(err) {
+		return "", fmt.Errorf("%w: %v", ErrPermission, err)
+	}
+	return ns, err
+}
+
+// ReadSockets returns the protocol, tcp, udp or unix, of the sockets of the
+// network namespace of process pid by inode. Tables missing from the
+// namespace, such as those of IPv6 when it is disabled, are skipped.
+func (fs ProcFS) ReadSockets(pid int) (map[uint64]string, error) {
+	sockets := make(map[uint64]string)
+	for _, table := range socketTables {
+		err := fs.readSocketTable(pid, table.name, table.column, table.protocol, sockets)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return sockets, nil
+}
+
+// readSocketTable adds to sockets the inodes found in column of the table
+// name of /proc/<pid>/net, with protocol.
+func (fs ProcFS) readSocketTable(pid int, name string, column int, protocol string, sockets map[uint64]string) error {
+	file, err := os.Open(filepath.Join(fs.Root, strconv.Itoa(pid), "net", name))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Scan() // header
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) <= column {
+			continue
+		}
+		inode, err := strconv.ParseUint(parts[column], 10, 64)
+		if err != nil || inode == 0 {
+			continue
+		}
+		sockets[inode] = protocol
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,547 @@
+package psinfo
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ClockTicks is the number of clock ticks per second the times of
+// /proc/<pid>/stat are counted in. It is 100 on every Linux architecture
+// in use, and cannot be queried without cgo.
+const ClockTicks = 100
+
+// Stat holds the attributes of a process read from /proc/<pid>/stat.
+type Stat struct {
+	Pid        int
+	Comm       string
+	State      string
+	Ppid       int
+	Pgrp       int
+	Session    int
+	TtyNr      int // device number of the controlling terminal, 0 if none
+	Tpgid      int
+	Minflt     uint64 // minor page faults
+	Majflt     uint64 // major page faults
+	Utime      uint64 // clock ticks
+	Stime      uint64 // clock ticks
+	Priority   int
+	Nice       int
+	NumThreads int
+	StartTime  uint64 // clock ticks since boot
+	Vsize      uint64 // bytes
+	Rss        int64  // pages
+	Processor  int
+	Policy     int // scheduling policy, SCHED_OTHER when not listed
+}
+
+// Status holds the attributes of a process read from /proc/<pid>/status.
+type Status struct {
+	Name    string
+	Ppid    int
+	Ruid    int
+	Euid    int
+	Threads int
+	VmSwap  int64 // KiB
+
+	VoluntaryCtxtSwitches    uint64
+	NonvoluntaryCtxtSwitches uint64
+
+	// CpusAllowedList is the affinity of the process, the cpus it may run
+	// on, as a list of ranges such as "0-3,8".
+	CpusAllowedList string
+
+	// CapEff is the effective capability set of the process, one bit per
+	// capability numbered as in linux/capability.h. HasCapEff tells whether
+	// the status listed it.
+	CapEff    uint64
+	HasCapEff bool
+}
+
+// IO holds the I/O counters of a process read from /proc/<pid>/io.
+type IO struct {
+	ReadBytes  uint64 // bytes fetched from the storage layer
+	WriteBytes uint64 // bytes sent to the storage layer
+	Syscr      uint64 // read system calls
+	Syscw      uint64 // write system calls
+}
+
+// Smaps holds the memory usage of a process summed over its mappings, read
+// from /proc/<pid>/smaps_rollup. All sizes are in KiB.
+type Smaps struct {
+	Rss          int64
+	Pss          int64
+	SharedClean  int64
+	SharedDirty  int64
+	PrivateClean int64
+	PrivateDirty int64
+	Swap         int64
+	SwapPss      int64
+}
+
+// Unlimited is the value of a resource limit that is not enforced.
+const Unlimited = -1
+
+// Limit is a soft and hard resource limit of a process, read from
+// /proc/<pid>/limits.
+type Limit struct {
+	Soft int64
+	Hard int64
+}
+
+// ReadStat returns the attributes of process pid listed in
+// /proc/<pid>/stat.
+func (fs ProcFS) ReadStat(pid int) (Stat, error) {
+	data, err := fs.readFile(pid, "stat")
+	if err != nil {
+		return Stat{}, err
+	}
+	return parseStat(pid, data)
+}
+
+// ReadThreads returns the attributes of the threads of process pid listed
+// in /proc/<pid>/task/<tid>/stat, the Pid of each holding its thread id.
+// Threads exiting while they are read are left out.
+func (fs ProcFS) ReadThreads(pid int) ([]Stat, error) {
+	entries, err := ioutil.ReadDir(filepath.Join(fs.Root, strconv.Itoa(pid), "task"))
+	if err != nil {
+		return nil, err
+	}
+
+	var threads []Stat
+	for _, entry := range entries {
+		tid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		data, err := fs.readFile(pid, filepath.Join("task", entry.Name(), "stat"))
+		if err != nil {
+			continue
+		}
+		thread, err := parseStat(tid, data)
+		if err != nil {
+			return nil, err
+		}
+		threads = append(threads, thread)
+	}
+	return threads, nil
+}
+
+// parseStat returns the attributes of process pid listed in data, the
+// content of its stat file.
+func parseStat(pid int, data []byte) (Stat, error) {
+	var err error
+	// The command name is enclosed in parentheses and may hold spaces and
+	// parentheses itself, so the fields are split after the last one.
+	open := bytes.IndexByte(data, '(')
+	closing := bytes.LastIndexByte(data, ')')
+	if open < 0 || closing < open {
+		return Stat{}, fmt.Errorf("%w: stat of process %d", ErrParse, pid)
+	}
+	fields := strings.Fields(string(data[closing+1:]))
+	// fields[0] is the third field of the file, the state.
+	if len(fields) < 37 {
+		return Stat{}, fmt.Errorf("%w: stat of process %d has %d fields", ErrParse, pid, len(fields)+2)
+	}
+
+	s := Stat{
+		Pid:   pid,
+		Comm:  Sanitize(string(data[open+1 : closing])),
+		State: fields[0],
+	}
+	ints := []struct {
+		dst   *int
+		index int
+	}{
+		{&s.Ppid, 1}, {&s.Pgrp, 2}, {&s.Session, 3}, {&s.TtyNr, 4}, {&s.Tpgid, 5},
+		{&s.Priority, 15}, {&s.Nice, 16}, {&s.NumThreads, 17}, {&s.Processor, 36},
+	}
+	for _, i := range ints {
+		if *i.dst, err = strconv.Atoi(fields[i.index]); err != nil {
+			return Stat{}, fmt.Errorf("%w: stat of process %d: %v", ErrParse, pid, err)
+		}
+	}
+	uints := []struct {
+		dst   *uint64
+		index int
+	}{
+		{&s.Minflt, 7}, {&s.Majflt, 9}, {&s.Utime, 11}, {&s.Stime, 12},
+		{&s.StartTime, 19}, {&s.Vsize, 20},
+	}
+	for _, u := range uints {
+		if *u.dst, err = strconv.ParseUint(fields[u.index], 10, 64); err != nil {
+			return Stat{}, fmt.Errorf("%w: stat of process %d: %v", ErrParse, pid, err)
+		}
+	}
+	if s.Rss, err = strconv.ParseInt(fields[21], 10, 64); err != nil {
+		return Stat{}, fmt.Errorf("%w: stat of process %d: %v", ErrParse, pid, err)
+	}
+	// The policy was added by Linux 2.5.19.
+	if len(fields) > 38 {
+		if s.Policy, err = strconv.Atoi(fields[38]); err != nil {
+			return Stat{}, fmt.Errorf("%w: stat of process %d: %v", ErrParse, pid, err)
+		}
+	}
+
+	return s, nil
+}
+
+// ReadStatus returns the attributes of process pid listed in
+// /proc/<pid>/status.
+func (fs ProcFS) ReadStatus(pid int) (Status, error) {
+	data, err := fs.readFile(pid, "status")
+	if err != nil {
+		return Status{}, err
+	}
+
+	var s Status
+	var uids bool
+	for _, line := range strings.Split(string(data), "\n") {
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		value := strings.TrimSpace(parts[1])
+		switch parts[0] {
+		case "Name":
+			s.Name = Sanitize(value)
+		case "Uid":
+			ids := strings.Fields(value)
+			if len(ids) < 2 {
+				return Status{}, fmt.Errorf("%w: status of process %d: Uid", ErrParse, pid)
+			}
+			if s.Ruid, err = strconv.Atoi(ids[0]); err != nil {
+				return Status{}, fmt.Errorf("%w: status of process %d: %v", ErrParse, pid, err)
+			}
+			if s.Euid, err = strconv.Atoi(ids[1]); err != nil {
+				return Status{}, fmt.Errorf("%w: status of process %d: %v", ErrParse, pid, err)
+			}
+			uids = true
+		case "PPid":
+			s.Ppid, _ = strconv.Atoi(value)
+		case "Threads":
+			s.Threads, _ = strconv.Atoi(value)
+		case "VmSwap":
+			s.VmSwap, _ = strconv.ParseInt(strings.TrimSuffix(value, " kB"), 10, 64)
+		case "voluntary_ctxt_switches":
+			s.VoluntaryCtxtSwitches, _ = strconv.ParseUint(value, 10, 64)
+		case "nonvoluntary_ctxt_switches":
+			s.NonvoluntaryCtxtSwitches, _ = strconv.ParseUint(value, 10, 64)
+		case "Cpus_allowed_list":
+			s.CpusAllowedList = value
+		case "CapEff":
+			if s.CapEff, err = strconv.ParseUint(value, 16, 64); err != nil {
+				return Status{}, fmt.Errorf("%w: status of process %d: %v", ErrParse, pid, err)
+			}
+			s.HasCapEff = true
+		}
+	}
+	if !uids {
+		return Status{}, fmt.Errorf("%w: no Uid line in status of process %d", ErrParse, pid)
+	}
+
+	return s, nil
+}
+
+// CountCPUs returns the number of cpus in list, a list of ranges of cpu
+// numbers such as "0-3,8" as found in /proc and /sys.
+func CountCPUs(list string) (int, error) {
+	var count int
+	for _, r := range strings.Split(list, ",") {
+		if r == "" {
+			continue
+		}
+		bounds := strings.SplitN(r, "-", 2)
+		first, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			return 0, fmt.Errorf("%w: cpu list %q", ErrParse, list)
+		}
+		last := first
+		if len(bounds) == 2 {
+			if last, err = strconv.Atoi(bounds[1]); err != nil || last < first {
+				return 0, fmt.Errorf("%w: cpu list %q", ErrParse, list)
+			}
+		}
+		count += last - first + 1
+	}
+	return count, nil
+}
+
+// ReadIO returns the I/O counters of process pid listed in /proc/<pid>/io.
+// Only the owner of a process, or a user with CAP_SYS_PTRACE, may read
+// them.
+func (fs ProcFS) ReadIO(pid int) (IO, error) {
+	data, err := fs.readFile(pid, "io")
+	if err != nil {
+		return IO{}, err
+	}
+
+	var counters IO
+	for _, line := range strings.Split(string(data), "\n") {
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		var counter *uint64
+		switch parts[0] {
+		case "read_bytes":
+			counter = &counters.ReadBytes
+		case "write_bytes":
+			counter = &counters.WriteBytes
+		case "syscr":
+			counter = &counters.Syscr
+		case "syscw":
+			counter = &counters.Syscw
+		default:
+			continue
+		}
+		if *counter, err = strconv.ParseUint(strings.TrimSpace(parts[1]), 10, 64); err != nil {
+			return IO{}, fmt.Errorf("%w: io of process %d: %v", ErrParse, pid, err)
+		}
+	}
+
+	return counters, nil
+}
+
+// ReadSmapsRollup returns the memory usage of process pid listed in
+// /proc/<pid>/smaps_rollup, which Linux provides since 4.14. Reading it
+// walks the page tables of the process, which takes time on large
+// processes.
+func (fs ProcFS) ReadSmapsRollup(pid int) (Smaps, error) {
+	data, err := fs.readFile(pid, "smaps_rollup")
+	if err != nil {
+		return Smaps{}, err
+	}
+
+	var s Smaps
+	sizes := map[string]*int64{
+		"Rss":           &s.Rss,
+		"Pss":           &s.Pss,
+		"Shared_Clean":  &s.SharedClean,
+		"Shared_Dirty":  &s.SharedDirty,
+		"Private_Clean": &s.PrivateClean,
+		"Private_Dirty": &s.PrivateDirty,
+		"Swap":          &s.Swap,
+		"SwapPss":       &s.SwapPss,
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		size, ok := sizes[parts[0]]
+		if !ok {
+			continue
+		}
+		value := strings.TrimSuffix(strings.TrimSpace(parts[1]), " kB")
+		if *size, err = strconv.ParseInt(value, 10, 64); err != nil {
+			return Smaps{}, fmt.Errorf("%w: smaps_rollup of process %d: %v", ErrParse, pid, err)
+		}
+	}
+
+	return s, nil
+}
+
+// ReadNumaMaps returns the memory of process pid resident on each NUMA
+// node, in KiB by node number, summed over the mappings listed in
+// /proc/<pid>/numa_maps. Like smaps, reading it walks the page tables of
+// the process. Nodes holding no page of the process are missing.
+func (fs ProcFS) ReadNumaMaps(pid int) (map[int]int64, error) {
+	data, err := fs.readFile(pid, "numa_maps")
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[int]int64)
+	for _, line := range strings.Split(string(data), "\n") {
+		// Each mapping counts its pages per node as N<node>=<pages>, in
+		// pages of kernelpagesize_kB, which huge pages enlarge.
+		pageSize := int64(4)
+		pages := make(map[int]int64)
+		for _, word := range strings.Fields(line) {
+			keyValue := strings.SplitN(word, "=", 2)
+			if len(keyValue) != 2 {
+				continue
+			}
+			key, value := keyValue[0], keyValue[1]
+			switch {
+			case key == "kernelpagesize_kB":
+				if pageSize, err = strconv.ParseInt(value, 10, 64); err != nil {
+					return nil, fmt.Errorf("%w: numa_maps of process %d: %v", ErrParse, pid, err)
+				}
+			case len(key) > 1 && key[0] == 'N':
+				node, err := strconv.Atoi(key[1:])
+				if err != nil {
+					continue
+				}
+				if pages[node], err = strconv.ParseInt(value, 10, 64); err != nil {
+					return nil, fmt.Errorf("%w: numa_maps of process %d: %v", ErrParse, pid, err)
+				}
+			}
+		}
+		for node, count := range pages {
+			nodes[node] += count * pageSize
+		}
+	}
+
+	return nodes, nil
+}
+
+// ReadLimits returns the resource limits of process pid by the name
+// /proc/<pid>/limits gives them, such as "Max open files".
+func (fs ProcFS) ReadLimits(pid int) (map[string]Limit, error) {
+	data, err := fs.readFile(pid, "limits")
+	if err != nil {
+		return nil, err
+	}
+
+	// The columns are aligned on the offsets of the header line, and the
+	// limit names contain spaces.
+	lines := strings.Split(string(data), "\n")
+	softAt := strings.Index(lines[0], "Soft Limit")
+	if softAt < 0 {
+		return nil, fmt.Errorf("%w: no header in limits of process %d", ErrParse, pid)
+	}
+
+	limits := make(map[string]Limit)
+	for _, line := range lines[1:] {
+		if len(line) <= softAt {
+			continue
+		}
+		values := strings.Fields(line[softAt:])
+		if len(values) < 2 {
+			return nil, fmt.Errorf("%w: limits of process %d: %q", ErrParse, pid, line)
+		}
+		var limit Limit
+		if limit.Soft, err = parseLimit(values[0]); err != nil {
+			return nil, fmt.Errorf("%w: limits of process %d: %v", ErrParse, pid, err)
+		}
+		if limit.Hard, err = parseLimit(values[1]); err != nil {
+			return nil, fmt.Errorf("%w: limits of process %d: %v", ErrParse, pid, err)
+		}
+		limits[strings.TrimSpace(line[:softAt])] = limit
+	}
+
+	return limits, nil
+}
+
+// parseLimit parses a value of /proc/<pid>/limits.
+func parseLimit(value string) (int64, error) {
+	if value == "unlimited" {
+		return Unlimited, nil
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// ReadUptime returns the number of seconds since the host booted.
+func (fs ProcFS) ReadUptime() (float64, error) {
+	data, err := ioutil.ReadFile(filepath.Join(fs.Root, "uptime"))
+	if err != nil {
+		return 0, err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("%w: uptime", ErrParse)
+	}
+	return strconv.ParseFloat(fields[0], 64)
+}
+
+// ReadBootTime returns the time the host booted, in seconds since the
+// epoch.
+func (fs ProcFS) ReadBootTime() (int64, error) {
+	data, err := ioutil.ReadFile(filepath.Join(fs.Root, "stat"))
+	if err != nil {
+		return 0, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "btime" {
+			return strconv.ParseInt(fields[1], 10, 64)
+		}
+	}
+	return 0, fmt.Errorf("%w: no btime in stat", ErrParse)
+}
+
+// ReadMemTotal returns the memory of the host in KiB.
+func (fs ProcFS) ReadMemTotal() (int64, error) {
+	data, err := ioutil.ReadFile(filepath.Join(fs.Root, "meminfo"))
+	if err != nil {
+		return 0, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "MemTotal:" {
+			return strconv.ParseInt(fields[1], 10, 64)
+		}
+	}
+	return 0, fmt.Errorf("%w: no MemTotal in meminfo", ErrParse)
+}
+
+// policyNames are the names of the scheduling policies of Linux by number.
+var policyNames = map[int]string{
+	0: "SCHED_OTHER",
+	1: "SCHED_FIFO",
+	2: "SCHED_RR",
+	3: "SCHED_BATCH",
+	5: "SCHED_IDLE",
+	6: "SCHED_DEADLINE",
+}
+
+// PolicyName returns the name of the scheduling policy numbered policy, or
+// the number itself if the policy is unknown.
+func PolicyName(policy int) string {
+	if name, ok := policyNames[policy]; ok {
+		return name
+	}
+	return strconv.Itoa(policy)
+}
+
+// TtyName returns the name of the terminal with device number nr, as in
+// the tty_nr field of /proc/<pid>/stat, the way ps shows it: pts/N for
+// pseudo-terminals, ttyN for virtual consoles and ttySN for serial ports.
+// Other terminals are named by their major and minor numbers, and nr 0,
+// meaning no terminal, by the empty string.
+func TtyName(nr int) string {
+	if nr == 0 {
+		return ""
+	}
+	major := (nr >> 8) & 0xfff
+	minor := (nr & 0xff) | ((nr >> 12) & 0xfff00)
+	switch {
+	case major >= 136 && major <= 143:
+		return "pts/" + strconv.Itoa((major-136)<<8|minor)
+	case major == 4 && minor < 64:
+		return "tty" + strconv.Itoa(minor)
+	case major == 4:
+		return "ttyS" + strconv.Itoa(minor-64)
+	}
+	return strconv.Itoa(major) + ":" + strconv.Itoa(minor)
+}
+
+// StatCode returns the process state code of s the way ps reports it in
+// its stat column: the state followed by the flags telling that the
+// process has a high (<) or low (N) priority, is a session leader (s), is
+// multi-threaded (l) or is in the foreground process group of its
+// terminal (+).
+func StatCode(s Stat) string {
+	code := s.State
+	switch {
+	case s.Nice < 0:
+		code += "<"
+	case s.Nice > 0:
+		code += "N"
+	}
+	if s.Session == s.Pid {
+		code += "s"
+	}
+	if s.NumThreads > 1 {
+		code += "l"
+	}
+	if s.Tpgid == s.Pgrp && s.Tpgid > 0 {
+		code += "+"
+	}
+	return code
+}
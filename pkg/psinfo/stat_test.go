@@ -0,0 +1,29 @@
+package psinfo
+
+import "testing"
+
+func FuzzParseStat(f *testing.F) {
+	f.Add("1 (systemd) S 0 1 1 0 -1 4194560 95352 2876402 106 1493 97 211 6093 3012 20 0 1 0 3 169352192 2816 18446744073709551615 1 1 0 0 0 0 671173123 4096 1260 0 0 0 17 3 0 0 0 0 0 0 0 0 0 0 0 0 0\n")
+	f.Add("2 (kthreadd) S 0 0 0 0 -1 2129984 0 0 0 0 0 3 0 0 20 0 1 0 3 0 0 18446744073709551615 0 0 0 0 0 0 0 2147483647 0 0 0 0 17 0 0 0 0 0 0 0 0 0 0 0 0 0 0\n")
+	f.Add("4242 (a (b) c) R 1 4242 4242 34819 4242 4194304 1 0 0 0 500 250 0 0 -51 0 12 0 123456 2147483648 131072 18446744073709551615 1 1 0 0 0 0 0 0 0 0 0 0 17 7 50 1 0 0 0\n")
+	f.Add("9 (bad\x1b\xffname\n) D 1 9 9 0 -1 0 0 0 0 0 0 0 0 0 20 0 1 0 1 1 1 1 1 1 0 0 0 0 0 0 0 0 0 0 0 0 17 0\n")
+	f.Add("3 (short) S 1 2 3")
+	f.Add("4 )( S")
+	f.Add("")
+
+	f.Fuzz(func(t *testing.T, data string) {
+		s, err := parseStat(1, []byte(data))
+		if err != nil {
+			return
+		}
+		if !sanitized(s.Comm) {
+			t.Errorf("comm %q is not sanitized", s.Comm)
+		}
+		if s.Pid != 1 {
+			t.Errorf("pid %d, expected 1", s.Pid)
+		}
+		StatCode(s)
+		TtyName(s.TtyNr)
+		PolicyName(s.Policy)
+	})
+}
@@ -0,0 +1,201 @@
+package psinfo
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Variant describes a flavour of the ps command: the flags listing every
+// process with a custom column format, and the name the flavour gives to
+// each column known to the parser. Columns absent from Specs are not
+// supported by the flavour and are left unset. FixedWidth tells whether the
+// flavour pads columns to the widths of FormatFixedColumns.
+type Variant struct {
+	Name       string
+	Flags      string
+	Specs      map[string]string
+	FixedWidth bool
+}
+
+// Variants holds the known ps flavours by name. Supporting a new flavour
+// only takes a new entry mapping the parser columns to its column names.
+var Variants = map[string]Variant{
+	"procps-ng": {
+		Name:       "procps-ng",
+		Flags:      "-axo",
+		FixedWidth: true,
+		Specs: map[string]string{
+			"pid":      "pid",
+			"ppid":     "ppid",
+			"nlwp":     "nlwp",
+			"rss":      "rss",
+			"vsz":      "vsz",
+			"%mem":     "%mem",
+			"%cpu":     "%cpu",
+			"psr":      "psr",
+			"ruser":    "ruser",
+			"ruid":     "ruid",
+			"euser":    "euser",
+			"euid":     "euid",
+			"etimes":   "etimes",
+			"ni":       "ni",
+			"priority": "priority",
+			"policy":   "policy",
+			"tty":      "tty",
+			"sid":      "sid",
+			"pgid":     "pgid",
+			"stat":     "stat",
+			"time":     "time",
+			"comm":     "comm",
+			"args":     "args",
+		},
+	},
+	"bsd": {
+		Name:  "bsd",
+		Flags: "-axo",
+		Specs: map[string]string{
+			"pid":   "pid",
+			"ppid":  "ppid",
+			"rss":   "rss",
+			"vsz":   "vsz",
+			"%mem":  "%mem",
+			"%cpu":  "%cpu",
+			"ruser": "ruser",
+			"ruid":  "ruid",
+			"euser": "user",
+			"euid":  "uid",
+			"ni":    "nice",
+			"tty":   "tty",
+			"pgid":  "pgid",
+			"stat":  "stat",
+			"time":  "time",
+			"comm":  "comm",
+			"args":  "args",
+		},
+	},
+	"darwin": {
+		Name:  "darwin",
+		Flags: "-axo",
+		Specs: map[string]string{
+			"pid":   "pid",
+			"ppid":  "ppid",
+			"rss":   "rss",
+			"vsz":   "vsz",
+			"%mem":  "%mem",
+			"%cpu":  "%cpu",
+			"ruser": "ruser",
+			"ruid":  "ruid",
+			"euser": "user",
+			"euid":  "uid",
+			"ni":    "nice",
+			"tty":   "tty",
+			"pgid":  "pgid",
+			"stat":  "stat",
+			"time":  "time",
+			// comm is the path of the executable on macOS.
+			"comm": "ucomm",
+			"args": "args",
+		},
+	},
+	"freebsd": {
+		Name:  "freebsd",
+		Flags: "-axo",
+		Specs: map[string]string{
+			"pid":    "pid",
+			"ppid":   "ppid",
+			"nlwp":   "nlwp",
+			"rss":    "rss",
+			"vsz":    "vsz",
+			"%mem":   "%mem",
+			"%cpu":   "%cpu",
+			"ruser":  "ruser",
+			"ruid":   "ruid",
+			"euser":  "user",
+			"euid":   "uid",
+			"etimes": "etimes",
+			"ni":     "nice",
+			"tty":    "tty",
+			"sid":    "sid",
+			"pgid":   "pgid",
+			"stat":   "stat",
+			"time":   "time",
+			"comm":   "comm",
+			"args":   "args",
+		},
+	},
+	"busybox": {
+		Name:  "busybox",
+		Flags: "-o",
+		Specs: map[string]string{
+			"pid":   "pid",
+			"ppid":  "ppid",
+			"rss":   "rss",
+			"vsz":   "vsz",
+			"ruser": "ruser",
+			"euser": "user",
+			"ni":    "nice",
+			"tty":   "tty",
+			"sid":   "sid",
+			"pgid":  "pgid",
+			"stat":  "stat",
+			"time":  "time",
+			"comm":  "comm",
+			"args":  "args",
+		},
+	},
+	"toybox": {
+		Name:  "toybox",
+		Flags: "-Ao",
+		Specs: map[string]string{
+			"pid":   "pid",
+			"ppid":  "ppid",
+			"nlwp":  "tcnt",
+			"rss":   "rss",
+			"vsz":   "vsz",
+			"%mem":  "%mem",
+			"%cpu":  "%cpu",
+			"psr":   "psr",
+			"ruser": "ruser",
+			"ruid":  "ruid",
+			"euser": "user",
+			"euid":  "uid",
+			"ni":    "ni",
+			"tty":   "tty",
+			"sid":   "sid",
+			"pgid":  "pgid",
+			"stat":  "stat",
+			"time":  "time",
+			"comm":  "comm",
+			"args":  "args",
+		},
+	},
+}
+
+// LookupVariant returns the ps flavour called name.
+func LookupVariant(name string) (Variant, error) {
+	variant, ok := Variants[name]
+	if !ok {
+		var names []string
+		for known := range Variants {
+			names = append(names, known)
+		}
+		sort.Strings(names)
+		return Variant{}, fmt.Errorf("unknown ps variant %q, expected one of %s", name, strings.Join(names, ", "))
+	}
+	return variant, nil
+}
+
+// Columns returns, in the order given, the columns supported by v renamed
+// to the column names of v.
+func (v Variant) Columns(columns []Column) []Column {
+	var supported []Column
+	for _, c := range columns {
+		spec, ok := v.Specs[c.Spec]
+		if !ok {
+			continue
+		}
+		supported = append(supported, Column{Spec: spec, Store: c.Store})
+	}
+	return supported
+}
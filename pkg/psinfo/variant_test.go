@@ -0,0 +1,180 @@
+package psinfo
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// fixtureColumns are the columns requested from every variant by the
+// captures of testdata, in the order of the ps input plugin. Each capture
+// holds the columns its variant supports.
+var fixtureColumns = []Column{
+	ColumnPid,
+	ColumnPpid,
+	ColumnNlwp,
+	ColumnRss,
+	ColumnVsz,
+	ColumnMem,
+	ColumnCPU,
+	ColumnPsr,
+	ColumnRuser,
+	ColumnRuid,
+	ColumnEuser,
+	ColumnEuid,
+	ColumnEtimes,
+	ColumnNice,
+	ColumnPri,
+	ColumnPolicy,
+	ColumnTty,
+	ColumnSid,
+	ColumnPgid,
+	ColumnTime,
+	ColumnStat,
+	ColumnComm,
+	ColumnArgs,
+}
+
+func TestVariantFixtures(t *testing.T) {
+	tests := []struct {
+		variant  string
+		expected []Process
+	}{
+		{
+			variant: "procps-ng",
+			expected: []Process{
+				{
+					Pid: 1, Ppid: 0, Comm: "systemd", Args: "/sbin/init splash",
+					Nlwp: 1, Rss: 11264, Vsz: 169352, Mem: 0.1, Psr: 3,
+					Ruser: "root", Stat: "Ss", Ruid: 0, Euser: "root", Euid: 0, Etimes: 86400,
+					CPUTime: 5, Priority: 20, Policy: "SCHED_OTHER", Sid: 1, Pgid: 1,
+				},
+				{
+					Pid: 2, Ppid: 0, Comm: "kthreadd", Args: "[kthreadd]",
+					Nlwp: 1, Psr: 0,
+					Ruser: "root", Stat: "S", Euser: "root", Etimes: 86400,
+					Priority: 20, Policy: "SCHED_OTHER",
+				},
+				{
+					Pid: 812, Ppid: 1, Comm: "dbus-daemon", Args: "/usr/bin/dbus-daemon --system --address=systemd: --nofork",
+					Nlwp: 4, Rss: 5120, Vsz: 22868, Psr: 1,
+					Ruser: "messagebus", Stat: "Ssl", Ruid: 102, Euser: "messagebus", Euid: 102, Etimes: 86390,
+					CPUTime: 12, Priority: 20, Policy: "SCHED_OTHER", Sid: 812, Pgid: 812,
+				},
+				{
+					Pid: 4242, Ppid: 4100, Comm: "sudo", Args: "sudo -i",
+					Nlwp: 1, Rss: 5632, Vsz: 10480, CPU: 0.1, Psr: 2,
+					Ruser: "alice", Stat: "S+", Ruid: 1000, Euser: "root", Euid: 0, Etimes: 120,
+					Priority: 20, Policy: "SCHED_OTHER", Tty: "pts/0", Sid: 4100, Pgid: 4242,
+				},
+				{
+					Pid: 4300, Ppid: 2, Comm: "irq/42-nvme", Args: "[irq/42-nvme]",
+					Nlwp: 1, CPU: 12.5, Psr: 5,
+					Ruser: "root", Stat: "D", Euser: "root", Etimes: 30,
+					CPUTime: 3, Priority: -51, Policy: "SCHED_FIFO",
+				},
+			},
+		},
+		{
+			variant: "bsd",
+			expected: []Process{
+				{
+					Pid: 1, Ppid: 0, Comm: "init", Args: "/sbin/init",
+					Rss: 1024, Vsz: 4540, Mem: 0.1,
+					Ruser: "root", Stat: "Is", Euser: "root",
+					CPUTime: 0.52, Pgid: 1,
+				},
+				{
+					Pid: 5813, Ppid: 1, Comm: "syslogd", Args: "/usr/sbin/syslogd",
+					Rss: 2364, Vsz: 9612, Mem: 0.2,
+					Ruser: "_syslogd", Stat: "S", Ruid: 73, Euser: "_syslogd", Euid: 73,
+					CPUTime: 1.07, Pgid: 5813,
+				},
+				{
+					Pid: 71244, Ppid: 50001, Comm: "vi", Args: "vi /etc/rc.conf",
+					Rss: 3020, Vsz: 8816, Mem: 0.3, CPU: 1.5,
+					Ruser: "alice", Stat: "SN+", Ruid: 1000, Euser: "root",
+					CPUTime: 0.03, Nice: 5, Tty: "ttyp0", Pgid: 71244,
+				},
+			},
+		},
+		{
+			variant: "busybox",
+			expected: []Process{
+				{
+					Pid: 1, Ppid: 0, Comm: "init", Args: "/sbin/init",
+					Rss: 412, Vsz: 1612, Ruser: "root", Stat: "S", Euser: "root",
+					Sid: 1, Pgid: 1,
+				},
+				{
+					Pid: 245, Ppid: 1, Comm: "syslogd", Args: "/sbin/syslogd -n",
+					Rss: 300, Vsz: 1600, Ruser: "root", Stat: "S", Euser: "root",
+					CPUTime: 1, Sid: 245, Pgid: 245,
+				},
+				{
+					Pid: 301, Ppid: 1, Comm: "sh", Args: "-sh",
+					Rss: 520, Vsz: 1740, Ruser: "root", Stat: "S", Euser: "root",
+					Tty: "ttyS0", Sid: 301, Pgid: 301,
+				},
+				{
+					Pid: 307, Ppid: 301, Comm: "top", Args: "top -b -n 1",
+					Rss: 284, Vsz: 1608, Ruser: "root", Stat: "R", Euser: "root",
+					CPUTime: 2, Nice: -10, Tty: "ttyS0", Sid: 301, Pgid: 307,
+				},
+			},
+		},
+		{
+			variant: "toybox",
+			expected: []Process{
+				{
+					Pid: 1, Ppid: 0, Comm: "init", Args: "/system/bin/init second_stage",
+					Nlwp: 1, Rss: 9636, Vsz: 10902360, Mem: 0.2, Psr: 2,
+					Ruser: "root", Stat: "S", Euser: "root",
+					CPUTime: 4,
+				},
+				{
+					Pid: 612, Ppid: 1, Comm: "system_server", Args: "system_server",
+					Nlwp: 67, Rss: 102456, Vsz: 14632500, Mem: 2.6, CPU: 1.2, Psr: 5,
+					Ruser: "system", Stat: "S", Ruid: 1000, Euser: "system", Euid: 1000,
+					CPUTime: 751, Nice: -2, Sid: 612, Pgid: 612,
+				},
+				{
+					Pid: 9123, Ppid: 9100, Comm: "ps", Args: "ps -A -o pid=",
+					Nlwp: 1, Rss: 3120, Vsz: 10833660, Psr: 1,
+					Ruser: "shell", Stat: "R", Ruid: 2000, Euser: "shell", Euid: 2000,
+					Tty: "pts/0", Sid: 9100, Pgid: 9123,
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.variant, func(t *testing.T) {
+			variant, err := LookupVariant(tt.variant)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := ioutil.ReadFile(filepath.Join("testdata", tt.variant+".txt"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			processes, dropped, err := ParseDropped(string(data), variant.Columns(fixtureColumns))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if dropped != 0 {
+				t.Errorf("%d lines dropped", dropped)
+			}
+			if len(processes) != len(tt.expected) {
+				t.Fatalf("%d processes, expected %d", len(processes), len(tt.expected))
+			}
+			for i, process := range processes {
+				if !reflect.DeepEqual(process, tt.expected[i]) {
+					t.Errorf("process %d:\n got %+v\nwant %+v", i, process, tt.expected[i])
+				}
+			}
+		})
+	}
+}
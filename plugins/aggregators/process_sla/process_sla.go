@@ -0,0 +1,222 @@
+package process_sla
+
+import (
+	"log"
+	"sort"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+const measurement = `process_sla`
+
+// ProcessSLA turns samples telling whether processes are up into their
+// availability over sliding windows.
+type ProcessSLA struct {
+	KeyTag   string
+	UpField  string
+	UpValues []string
+	Watch    []string
+	Windows  []string
+
+	now     func() time.Time
+	parsed  bool
+	windows []window
+	current map[string]bool
+	history map[string][]period
+}
+
+// window is a window over which the availability is computed.
+type window struct {
+	name     string
+	duration time.Duration
+}
+
+// period is the outcome of an aggregation period for a process.
+type period struct {
+	end time.Time
+	up  bool
+}
+
+// init initializes the package.
+func init() {
+	aggregators.Add("process_sla", func() telegraf.Aggregator {
+		return newProcessSLA()
+	})
+}
+
+// newProcessSLA returns a pointer to a new ProcessSLA object.
+func newProcessSLA() *ProcessSLA {
+	return &ProcessSLA{
+		KeyTag:   "comm",
+		UpValues: []string{"running", "active", "RUNNING", "run"},
+		Windows:  []string{"1h", "24h"},
+		now:      time.Now,
+		current:  make(map[string]bool),
+		history:  make(map[string][]period),
+	}
+}
+
+// Description returns a short description about the plugin.
+func (p *ProcessSLA) Description() string {
+	return "Compute the availability of processes over sliding windows."
+}
+
+// SampleConfig returns a sample configuration for the plugin.
+func (p *ProcessSLA) SampleConfig() string {
+	return `
+	## Period of the aggregation; the availability is the share of periods
+	## a process was up.
+	#period = "30s"
+	#drop_original = false
+
+	## Tag identifying the process in the samples.
+	#key_tag = "comm"
+
+	## Field telling whether the process is up: true, a non-zero number or
+	## one of up_values. Every sample is taken as up if empty, as when the
+	## samples are the per_process metrics of the ps input.
+	#up_field = ""
+	#up_values = ["running", "active", "RUNNING", "run"]
+
+	## Processes expected in every period; a period without any of their
+	## samples counts as down.
+	#watch = ["nginx", "postgres"]
+
+	## Windows over which the availability is computed.
+	#windows = ["1h", "24h"]
+	`
+}
+
+// Add records whether the process of the sample in was up. A period is up
+// when every sample of the process in the period was up.
+func (p *ProcessSLA) Add(in telegraf.Metric) {
+	key, ok := in.GetTag(p.KeyTag)
+	if !ok {
+		return
+	}
+
+	up := true
+	if p.UpField != "" {
+		value, ok := in.GetField(p.UpField)
+		if !ok {
+			return
+		}
+		up = p.isUp(value)
+	}
+
+	if previous, seen := p.current[key]; seen {
+		up = up && previous
+	}
+	p.current[key] = up
+}
+
+// Push closes the period, and stores in the accumulator acc the
+// availability of every process over each window.
+func (p *ProcessSLA) Push(acc telegraf.Accumulator) {
+	if !p.parsed {
+		for _, name := range p.Windows {
+			d, err := time.ParseDuration(name)
+			if err != nil {
+				log.Printf("E! [aggregators.process_sla] invalid window %q: %s", name, err)
+				continue
+			}
+			p.windows = append(p.windows, window{name: name, duration: d})
+		}
+		p.parsed = true
+	}
+
+	now := p.now().UTC()
+	for _, key := range p.Watch {
+		if _, seen := p.current[key]; !seen {
+			p.current[key] = false
+		}
+	}
+	for key, up := range p.current {
+		p.history[key] = append(p.history[key], period{end: now, up: up})
+	}
+
+	var longest time.Duration
+	for _, w := range p.windows {
+		if w.duration > longest {
+			longest = w.duration
+		}
+	}
+
+	keys := make([]string, 0, len(p.history))
+	for key := range p.history {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		periods := prune(p.history[key], now.Add(-longest))
+		if len(periods) == 0 {
+			delete(p.history, key)
+			continue
+		}
+		p.history[key] = periods
+
+		for _, w := range p.windows {
+			var total, down int
+			for _, period := range periods {
+				if period.end.Before(now.Add(-w.duration)) {
+					continue
+				}
+				total++
+				if !period.up {
+					down++
+				}
+			}
+			if total == 0 {
+				continue
+			}
+			fields := map[string]interface{}{
+				"availability": 100 * float64(total-down) / float64(total),
+				"periods":      total,
+				"down_periods": down,
+			}
+			tags := map[string]string{
+				p.KeyTag: key,
+				"window": w.name,
+			}
+			acc.AddFields(measurement, fields, tags, now)
+		}
+	}
+}
+
+// Reset starts a new period. The history of the periods is kept across
+// periods, for the windows.
+func (p *ProcessSLA) Reset() {
+	p.current = make(map[string]bool)
+}
+
+// isUp tells whether the value of the up field means up.
+func (p *ProcessSLA) isUp(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case int64:
+		return v != 0
+	case uint64:
+		return v != 0
+	case float64:
+		return v != 0
+	case string:
+		for _, up := range p.UpValues {
+			if v == up {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// prune drops the periods that ended before since.
+func prune(periods []period, since time.Time) []period {
+	i := 0
+	for i < len(periods) && periods[i].end.Before(since) {
+		i++
+	}
+	return periods[i:]
+}
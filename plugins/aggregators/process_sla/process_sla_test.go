@@ -0,0 +1,155 @@
+package process_sla
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+// sample returns a sample of the process comm in the state.
+func sample(comm, state string) telegraf.Metric {
+	return testutil.MustMetric("supervisord",
+		map[string]string{"comm": comm},
+		map[string]interface{}{"state": state},
+		time.Unix(0, 0))
+}
+
+// availability returns a metric of the availability of the process comm
+// over the window.
+func availability(comm, window string, periods, down int, tm time.Time) telegraf.Metric {
+	return testutil.MustMetric(measurement,
+		map[string]string{"comm": comm, "window": window},
+		map[string]interface{}{
+			"availability": 100 * float64(periods-down) / float64(periods),
+			"periods":      periods,
+			"down_periods": down,
+		},
+		tm)
+}
+
+func TestPush(t *testing.T) {
+	start := time.Unix(1600000000, 0).UTC()
+	at := func(d time.Duration) time.Time { return start.Add(d) }
+
+	// Periods last 40s, the 1m window holding the last two of them.
+	steps := []struct {
+		name     string
+		elapsed  time.Duration
+		in       []telegraf.Metric
+		expected []telegraf.Metric
+	}{
+		{
+			name:    "first period",
+			elapsed: 0,
+			in: []telegraf.Metric{
+				sample("nginx", "running"), sample("nginx", "running"),
+				sample("postgres", "RUNNING"), sample("cron", "run"),
+			},
+			expected: []telegraf.Metric{
+				availability("cron", "1m", 1, 0, at(0)), availability("cron", "1h", 1, 0, at(0)),
+				availability("nginx", "1m", 1, 0, at(0)), availability("nginx", "1h", 1, 0, at(0)),
+				availability("postgres", "1m", 1, 0, at(0)), availability("postgres", "1h", 1, 0, at(0)),
+			},
+		},
+		{
+			// A single sample down makes the period down; the watched
+			// postgres is missing, cron is not watched.
+			name:    "down and missing",
+			elapsed: 40 * time.Second,
+			in: []telegraf.Metric{
+				sample("nginx", "running"), sample("nginx", "stopped"),
+				testutil.MustMetric("supervisord", nil, map[string]interface{}{"state": "stopped"}, start),
+				testutil.MustMetric("supervisord", map[string]string{"comm": "nginx"}, map[string]interface{}{"pid": 42}, start),
+			},
+			expected: []telegraf.Metric{
+				availability("cron", "1m", 1, 0, at(40*time.Second)), availability("cron", "1h", 1, 0, at(40*time.Second)),
+				availability("nginx", "1m", 2, 1, at(40*time.Second)), availability("nginx", "1h", 2, 1, at(40*time.Second)),
+				availability("postgres", "1m", 2, 1, at(40*time.Second)), availability("postgres", "1h", 2, 1, at(40*time.Second)),
+			},
+		},
+		{
+			// The first period left the 1m window.
+			name:    "window slid",
+			elapsed: 80 * time.Second,
+			in:      []telegraf.Metric{sample("nginx", "running"), sample("postgres", "RUNNING")},
+			expected: []telegraf.Metric{
+				availability("cron", "1h", 1, 0, at(80*time.Second)),
+				availability("nginx", "1m", 2, 1, at(80*time.Second)), availability("nginx", "1h", 3, 1, at(80*time.Second)),
+				availability("postgres", "1m", 2, 1, at(80*time.Second)), availability("postgres", "1h", 3, 1, at(80*time.Second)),
+			},
+		},
+		{
+			// The periods older than the longest window are dropped, and cron
+			// with them.
+			name:    "history pruned",
+			elapsed: 2 * time.Hour,
+			in:      []telegraf.Metric{sample("nginx", "running")},
+			expected: []telegraf.Metric{
+				availability("nginx", "1m", 1, 0, at(2*time.Hour)), availability("nginx", "1h", 1, 0, at(2*time.Hour)),
+				availability("postgres", "1m", 1, 1, at(2*time.Hour)), availability("postgres", "1h", 1, 1, at(2*time.Hour)),
+			},
+		},
+	}
+
+	p := newProcessSLA()
+	p.UpField = "state"
+	p.Watch = []string{"postgres"}
+	p.Windows = []string{"1m", "1h", "invalid"}
+	for _, step := range steps {
+		p.now = func() time.Time { return start.Add(step.elapsed) }
+		for _, m := range step.in {
+			p.Add(m)
+		}
+		var acc testutil.Accumulator
+		p.Push(&acc)
+		p.Reset()
+
+		t.Run(step.name, func(t *testing.T) {
+			testutil.RequireMetricsEqual(t, step.expected, acc.GetTelegrafMetrics())
+		})
+	}
+}
+
+func TestPushEverySampleUp(t *testing.T) {
+	now := time.Unix(1600000000, 0).UTC()
+	p := newProcessSLA()
+	p.Windows = []string{"1h"}
+	p.now = func() time.Time { return now }
+
+	// The per_process metrics of the ps input, without an up field.
+	p.Add(testutil.MustMetric("ps",
+		map[string]string{"pid": "4242", "comm": "nginx"},
+		map[string]interface{}{"rss": 524288}, now))
+	var acc testutil.Accumulator
+	p.Push(&acc)
+
+	testutil.RequireMetricsEqual(t,
+		[]telegraf.Metric{availability("nginx", "1h", 1, 0, now)},
+		acc.GetTelegrafMetrics())
+}
+
+func TestIsUp(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		up    bool
+	}{
+		{true, true},
+		{false, false},
+		{int64(1), true},
+		{int64(0), false},
+		{uint64(3), true},
+		{float64(0), false},
+		{"active", true},
+		{"inactive", false},
+		{[]byte("running"), false},
+	}
+
+	p := newProcessSLA()
+	for _, tt := range tests {
+		if up := p.isUp(tt.value); up != tt.up {
+			t.Errorf("%#v: up %t, expected %t", tt.value, up, tt.up)
+		}
+	}
+}
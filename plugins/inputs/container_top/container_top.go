@@ -0,0 +1,235 @@
+package container_top
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const (
+	measurement      = `container_top`
+	statsMeasurement = `container_top_stats`
+)
+
+// columnTags maps the titles of the top columns reported as tags to the
+// names of the tags.
+var columnTags = map[string]string{
+	"PID":     "pid",
+	"USER":    "user",
+	"UID":     "user",
+	"COMMAND": "comm",
+	"CMD":     "comm",
+}
+
+// columnFields maps the titles of the top columns whose name is not a
+// valid field name to the names of the fields.
+var columnFields = map[string]string{
+	"%CPU": "cpu",
+	"%MEM": "mem",
+	"C":    "cpu",
+}
+
+// ContainerTop reports the processes and the resource usage of the
+// containers, as the container runtime sees them.
+type ContainerTop struct {
+	Endpoint   string
+	Containers []string
+	PsArgs     string `toml:"ps_args"`
+	Processes  bool
+	Stats      bool
+	Timeout    internal.Duration
+
+	client          *dockerClient
+	compiled        bool
+	containerFilter filter.Filter
+}
+
+// init initializes the package.
+func init() {
+	inputs.Add("container_top", func() telegraf.Input {
+		return newContainerTop()
+	})
+}
+
+// newContainerTop returns a pointer to a new ContainerTop object.
+func newContainerTop() *ContainerTop {
+	return &ContainerTop{
+		Endpoint:  "unix:///var/run/docker.sock",
+		PsArgs:    "-o pid,ppid,user,rss,vsz,%cpu,%mem,nlwp,comm",
+		Processes: true,
+		Stats:     true,
+		Timeout:   internal.Duration{Duration: time.Second * 5},
+	}
+}
+
+// Description returns a short description about the plugin.
+func (c *ContainerTop) Description() string {
+	return "Read the processes and the resource usage of the containers from the container runtime."
+}
+
+// SampleConfig returns a sample configuration for the plugin.
+func (c *ContainerTop) SampleConfig() string {
+	return `
+	## Docker Engine API endpoint.
+	#endpoint = "unix:///var/run/docker.sock"
+
+	## Names of the containers to report; glob patterns are supported.
+	## Every running container is reported if empty.
+	#containers = ["web-*"]
+
+	## Options of the ps command the container runtime runs to list the
+	## processes of a container; the output must have a PID column.
+	#ps_args = "-o pid,ppid,user,rss,vsz,%cpu,%mem,nlwp,comm"
+
+	## Report the processes of each container.
+	#processes = true
+
+	## Report the cpu, memory and pids usage of each container.
+	#stats = true
+
+	## Timeout for each request to the container runtime.
+	#timeout = "5s"
+	`
+}
+
+// Gather lists the running containers and stores their processes and
+// resource usage in the accumulator acc.
+func (c *ContainerTop) Gather(acc telegraf.Accumulator) error {
+	if !c.compiled {
+		var err error
+		c.containerFilter, err = filter.Compile(c.Containers)
+		if err != nil {
+			acc.AddError(err)
+			return fmt.Errorf("container_top: invalid containers: %s", err)
+		}
+		c.client, err = newDockerClient(c.Endpoint, c.Timeout.Duration)
+		if err != nil {
+			acc.AddError(err)
+			return fmt.Errorf("container_top: invalid endpoint: %s", err)
+		}
+		c.compiled = true
+	}
+
+	containers, err := c.client.containers()
+	if err != nil {
+		acc.AddError(err)
+		return fmt.Errorf("container_top: unable to gather metrics: %s", err)
+	}
+
+	for _, ctr := range containers {
+		name := ctr.ID
+		if len(ctr.Names) > 0 {
+			name = strings.TrimPrefix(ctr.Names[0], "/")
+		}
+		if c.containerFilter != nil && !c.containerFilter.Match(name) {
+			continue
+		}
+		tags := map[string]string{
+			"container_name":  name,
+			"container_id":    ctr.ID,
+			"container_image": ctr.Image,
+		}
+
+		if c.Processes {
+			if err := c.gatherProcesses(acc, ctr.ID, tags); err != nil {
+				acc.AddError(fmt.Errorf("container_top: container %s: %s", name, err))
+			}
+		}
+		if c.Stats {
+			if err := c.gatherStats(acc, ctr.ID, tags); err != nil {
+				acc.AddError(fmt.Errorf("container_top: container %s: %s", name, err))
+			}
+		}
+	}
+
+	return nil
+}
+
+// gatherProcesses stores one metric per process of container id, tagged
+// with the container tags.
+func (c *ContainerTop) gatherProcesses(acc telegraf.Accumulator, id string, containerTags map[string]string) error {
+	t, err := c.client.top(id, c.PsArgs)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	for _, process := range t.Processes {
+		tags := make(map[string]string, len(containerTags)+3)
+		for k, v := range containerTags {
+			tags[k] = v
+		}
+		fields := make(map[string]interface{})
+		for i, title := range t.Titles {
+			if i >= len(process) {
+				break
+			}
+			if tag, ok := columnTags[title]; ok {
+				tags[tag] = process[i]
+				continue
+			}
+			field, ok := columnFields[title]
+			if !ok {
+				field = strings.ToLower(title)
+			}
+			fields[field] = parseValue(process[i])
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		acc.AddFields(measurement, fields, tags, now)
+	}
+
+	return nil
+}
+
+// gatherStats stores the resource usage of container id, tagged with the
+// container tags.
+func (c *ContainerTop) gatherStats(acc telegraf.Accumulator, id string, tags map[string]string) error {
+	s, err := c.client.stats(id)
+	if err != nil {
+		return err
+	}
+
+	fields := map[string]interface{}{
+		"memory_usage": s.MemoryStats.Usage,
+		"memory_limit": s.MemoryStats.Limit,
+		"pids":         s.PidsStats.Current,
+		"cpu_usage":    s.CPUStats.CPUUsage.TotalUsage,
+	}
+	// The page cache is counted in the usage, so it is left out as docker
+	// stats does.
+	if cache, ok := s.MemoryStats.Stats["inactive_file"]; ok && cache < s.MemoryStats.Usage {
+		fields["memory_usage"] = s.MemoryStats.Usage - cache
+	}
+	cpuDelta := float64(s.CPUStats.CPUUsage.TotalUsage) - float64(s.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(s.CPUStats.SystemUsage) - float64(s.PreCPUStats.SystemUsage)
+	if cpuDelta >= 0 && systemDelta > 0 {
+		cpus := float64(s.CPUStats.OnlineCPUs)
+		if cpus == 0 {
+			cpus = 1
+		}
+		fields["cpu_percent"] = cpuDelta / systemDelta * cpus * 100
+	}
+
+	acc.AddFields(statsMeasurement, fields, tags, time.Now().UTC())
+	return nil
+}
+
+// parseValue returns the column value s as an integer or a float if it is
+// one, and as a string otherwise.
+func parseValue(s string) interface{} {
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f
+	}
+	return s
+}
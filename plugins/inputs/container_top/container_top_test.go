@@ -0,0 +1,265 @@
+package container_top
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+)
+
+// newDocker returns a Docker Engine API server listing the containers of
+// testdata/containers.json, and answering the top and stats requests of a
+// container with testdata/top-<name>.json and testdata/stats-<name>.json.
+// Without such a file, the container is restarting and the request fails
+// as it does with dockerd.
+func newDocker(t *testing.T) *httptest.Server {
+	s := httptest.NewServer(dockerHandler(t))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// dockerHandler returns the handler of newDocker.
+func dockerHandler(t *testing.T) http.Handler {
+	data, err := ioutil.ReadFile("testdata/containers.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var containers []container
+	if err := json.Unmarshal(data, &containers); err != nil {
+		t.Fatal(err)
+	}
+	names := make(map[string]string)
+	for _, ctr := range containers {
+		names[ctr.ID] = strings.TrimPrefix(ctr.Names[0], "/")
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/containers/json" {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(data)
+			return
+		}
+
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/containers/"), "/")
+		name, ok := names[parts[0]]
+		if len(parts) != 2 || !ok {
+			http.Error(w, `{"message":"page not found"}`, http.StatusNotFound)
+			return
+		}
+		if parts[1] == "top" && r.URL.Query().Get("ps_args") == "" {
+			t.Errorf("top of %s without ps_args", name)
+		}
+		body, err := ioutil.ReadFile(filepath.Join("testdata", parts[1]+"-"+name+".json"))
+		if err != nil {
+			msg := fmt.Sprintf(`{"message":"Container %s is restarting, wait until the container is running"}`, parts[0])
+			http.Error(w, msg, http.StatusConflict)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	})
+}
+
+// metric is a metric of the accumulator, without its time.
+type metric struct {
+	Tags   map[string]string
+	Fields map[string]interface{}
+}
+
+// metricsByKey returns the metrics of acc by measurement, container name
+// and pid.
+func metricsByKey(acc *testutil.Accumulator) map[string]metric {
+	metrics := make(map[string]metric)
+	for _, m := range acc.Metrics {
+		key := m.Measurement + " " + m.Tags["container_name"] + " " + m.Tags["pid"]
+		metrics[strings.TrimSpace(key)] = metric{m.Tags, m.Fields}
+	}
+	return metrics
+}
+
+const (
+	web1ID = "3f1e9c2a7b6d5e4f3a2b1c0d9e8f7a6b5c4d3e2f1a0b9c8d7e6f5a4b3c2d1e0f"
+	dbID   = "9a8b7c6d5e4f3a2b1c0d9e8f7a6b5c4d3e2f1a0b9c8d7e6f5a4b3c2d1e0f1a2b"
+)
+
+// containerTags returns the tags of container name, with id and image,
+// and the process tags given as pairs of key and value.
+func containerTags(name, id, image string, process ...string) map[string]string {
+	tags := map[string]string{"container_name": name, "container_id": id, "container_image": image}
+	for i := 0; i+1 < len(process); i += 2 {
+		tags[process[i]] = process[i+1]
+	}
+	return tags
+}
+
+func TestGather(t *testing.T) {
+	web1Processes := map[string]metric{
+		"container_top web-1 12345": {
+			containerTags("web-1", web1ID, "nginx:1.25", "pid", "12345", "user", "root", "comm", "nginx"),
+			map[string]interface{}{
+				"ppid": int64(12320), "rss": int64(5120), "vsz": int64(11240),
+				"cpu": 0.0, "mem": 0.1, "nlwp": int64(1),
+			},
+		},
+		"container_top web-1 12401": {
+			// The user of a uid without a name in the container.
+			containerTags("web-1", web1ID, "nginx:1.25", "pid", "12401", "user", "101", "comm", "nginx"),
+			map[string]interface{}{
+				"ppid": int64(12345), "rss": int64(2816), "vsz": int64(11712),
+				"cpu": 1.5, "mem": 0.0, "nlwp": int64(1),
+			},
+		},
+	}
+	web1Stats := metric{
+		containerTags("web-1", web1ID, "nginx:1.25"),
+		map[string]interface{}{
+			// The usage less the inactive page cache.
+			"memory_usage": uint64(10485760), "memory_limit": uint64(8323432448),
+			"pids": uint64(2), "cpu_usage": uint64(120000000000), "cpu_percent": 100.0,
+		},
+	}
+
+	tests := []struct {
+		name       string
+		containers []string
+		processes  bool
+		stats      bool
+		expected   map[string]metric
+		errors     int
+	}{
+		{
+			name:      "every container",
+			processes: true,
+			stats:     true,
+			expected: map[string]metric{
+				"container_top web-1 12345": web1Processes["container_top web-1 12345"],
+				"container_top web-1 12401": web1Processes["container_top web-1 12401"],
+				"container_top db 2001": {
+					containerTags("db", dbID, "postgres:16", "pid", "2001", "user", "999", "comm", "postgres"),
+					map[string]interface{}{
+						"ppid": int64(1980), "rss": int64(28672), "vsz": int64(218944),
+						"cpu": 0.3, "mem": 0.3, "nlwp": int64(1),
+					},
+				},
+				"container_top_stats web-1": web1Stats,
+				"container_top_stats db": {
+					containerTags("db", dbID, "postgres:16"),
+					map[string]interface{}{
+						"memory_usage": uint64(104857600), "memory_limit": uint64(536870912),
+						"pids": uint64(7), "cpu_usage": uint64(56000000000), "cpu_percent": 50.0,
+					},
+				},
+			},
+			// Both requests of the restarting web-2 fail.
+			errors: 2,
+		},
+		{
+			name:       "containers",
+			containers: []string{"web-*"},
+			processes:  true,
+			expected:   web1Processes,
+			errors:     1,
+		},
+		{
+			name:       "stats only",
+			containers: []string{"web-1"},
+			stats:      true,
+			expected:   map[string]metric{"container_top_stats web-1": web1Stats},
+		},
+	}
+
+	s := newDocker(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newContainerTop()
+			c.Endpoint = "tcp://" + s.Listener.Addr().String()
+			c.Containers = tt.containers
+			c.Processes = tt.processes
+			c.Stats = tt.stats
+
+			var acc testutil.Accumulator
+			if err := c.Gather(&acc); err != nil {
+				t.Fatal(err)
+			}
+			if len(acc.Errors) != tt.errors {
+				t.Errorf("errors %v, expected %d", acc.Errors, tt.errors)
+			}
+			if metrics := metricsByKey(&acc); !reflect.DeepEqual(metrics, tt.expected) {
+				t.Errorf("\n got %v\nwant %v", metrics, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGatherUnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "docker.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	s := &http.Server{Handler: dockerHandler(t)}
+	go s.Serve(l)
+	t.Cleanup(func() { s.Close() })
+
+	c := newContainerTop()
+	c.Endpoint = "unix://" + socket
+	c.Containers = []string{"db"}
+	var acc testutil.Accumulator
+	if err := c.Gather(&acc); err != nil {
+		t.Fatal(err)
+	}
+	if len(acc.Errors) != 0 || len(acc.Metrics) != 2 {
+		t.Errorf("metrics %v, errors %v", acc.Metrics, acc.Errors)
+	}
+}
+
+func TestGatherErrors(t *testing.T) {
+	// A server answering every request with garbage.
+	garbage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html>"))
+	}))
+	defer garbage.Close()
+
+	tests := []struct {
+		name     string
+		endpoint string
+	}{
+		{"unsupported scheme", "ftp://localhost"},
+		{"unreachable", "unix://" + filepath.Join(t.TempDir(), "missing.sock")},
+		{"invalid response", "http://" + garbage.Listener.Addr().String()},
+	}
+
+	for _, tt := range tests {
+		c := newContainerTop()
+		c.Endpoint = tt.endpoint
+		var acc testutil.Accumulator
+		if err := c.Gather(&acc); err == nil || len(acc.Errors) != 1 {
+			t.Errorf("%s: error %v, errors %v", tt.name, err, acc.Errors)
+		}
+	}
+}
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected interface{}
+	}{
+		{"4242", int64(4242)},
+		{"0.3", 0.3},
+		{"Ss", "Ss"},
+		{"00:01:02", "00:01:02"},
+	}
+	for _, tt := range tests {
+		if got := parseValue(tt.in); got != tt.expected {
+			t.Errorf("%q: %#v, expected %#v", tt.in, got, tt.expected)
+		}
+	}
+}
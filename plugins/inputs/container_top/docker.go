@@ -0,0 +1,122 @@
+package container_top
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// dockerClient calls the Docker Engine API, over its unix socket or TCP.
+type dockerClient struct {
+	base string
+	http *http.Client
+}
+
+// container is a container as listed by /containers/json.
+type container struct {
+	ID    string   `json:"Id"`
+	Names []string `json:"Names"`
+	Image string   `json:"Image"`
+}
+
+// top is the process list of a container, as returned by
+// /containers/{id}/top.
+type top struct {
+	Titles    []string   `json:"Titles"`
+	Processes [][]string `json:"Processes"`
+}
+
+// stats is the resource usage of a container, as returned by
+// /containers/{id}/stats.
+type stats struct {
+	CPUStats    cpuStats `json:"cpu_stats"`
+	PreCPUStats cpuStats `json:"precpu_stats"`
+	MemoryStats struct {
+		Usage uint64            `json:"usage"`
+		Limit uint64            `json:"limit"`
+		Stats map[string]uint64 `json:"stats"`
+	} `json:"memory_stats"`
+	PidsStats struct {
+		Current uint64 `json:"current"`
+		Limit   uint64 `json:"limit"`
+	} `json:"pids_stats"`
+}
+
+// cpuStats is the cpu usage of a container at a point in time.
+type cpuStats struct {
+	CPUUsage struct {
+		TotalUsage uint64 `json:"total_usage"`
+	} `json:"cpu_usage"`
+	SystemUsage uint64 `json:"system_cpu_usage"`
+	OnlineCPUs  uint64 `json:"online_cpus"`
+}
+
+// newDockerClient returns a client for the Docker Engine API at endpoint,
+// either a unix:// socket path or a tcp:// or http:// address.
+func newDockerClient(endpoint string, timeout time.Duration) (*dockerClient, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &dockerClient{http: &http.Client{Timeout: timeout}}
+	switch u.Scheme {
+	case "unix":
+		socket := u.Path
+		c.base = "http://docker"
+		c.http.Transport = &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", socket)
+			},
+		}
+	case "tcp", "http":
+		c.base = "http://" + u.Host
+	default:
+		return nil, fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+
+	return c, nil
+}
+
+// get decodes into v the json response to a GET of path.
+func (c *dockerClient) get(path string, v interface{}) error {
+	resp, err := c.http.Get(c.base + path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", path, resp.Status)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("%s: unable to parse response: %s", path, err)
+	}
+	return nil
+}
+
+// containers lists the running containers.
+func (c *dockerClient) containers() ([]container, error) {
+	var containers []container
+	err := c.get("/containers/json", &containers)
+	return containers, err
+}
+
+// top lists the processes of container id, running ps with psArgs.
+func (c *dockerClient) top(id, psArgs string) (top, error) {
+	var t top
+	err := c.get("/containers/"+url.PathEscape(id)+"/top?ps_args="+url.QueryEscape(psArgs), &t)
+	return t, err
+}
+
+// stats returns the resource usage of container id.
+func (c *dockerClient) stats(id string) (stats, error) {
+	var s stats
+	err := c.get("/containers/"+url.PathEscape(id)+"/stats?stream=false", &s)
+	return s, err
+}
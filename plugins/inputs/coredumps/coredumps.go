@@ -0,0 +1,285 @@
+package coredumps
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const measurement = `coredumps`
+
+// Sources of the core dumps.
+const (
+	sourceCoredumpctl = `coredumpctl`
+	sourceDirectory   = `directory`
+)
+
+// signalNames maps the numbers of the signals dumping core to their names.
+var signalNames = map[int]string{
+	3:  "SIGQUIT",
+	4:  "SIGILL",
+	5:  "SIGTRAP",
+	6:  "SIGABRT",
+	7:  "SIGBUS",
+	8:  "SIGFPE",
+	11: "SIGSEGV",
+	24: "SIGXCPU",
+	25: "SIGXFSZ",
+	31: "SIGSYS",
+}
+
+// Coredumps reports the core dumps written since the previous gather.
+type Coredumps struct {
+	Source          string
+	CoredumpctlPath string `toml:"coredumpctl_path"`
+	Directory       string
+	FilenamePattern string
+	Timeout         internal.Duration
+
+	compiled bool
+	filename *regexp.Regexp
+	since    time.Time
+}
+
+// coredump describes a core dump.
+type coredump struct {
+	time   time.Time
+	comm   string
+	exe    string
+	pid    int64
+	uid    int64
+	signal int
+	size   int64
+}
+
+// entry is a core dump as listed by coredumpctl --json=short.
+type entry struct {
+	Time int64  `json:"time"`
+	Pid  int64  `json:"pid"`
+	UID  int64  `json:"uid"`
+	Sig  int    `json:"sig"`
+	Exe  string `json:"exe"`
+	Size *int64 `json:"size"`
+}
+
+// init initializes the package.
+func init() {
+	inputs.Add("coredumps", func() telegraf.Input {
+		return newCoredumps()
+	})
+}
+
+// newCoredumps returns a pointer to a new Coredumps object.
+func newCoredumps() *Coredumps {
+	return &Coredumps{
+		Source:          sourceCoredumpctl,
+		CoredumpctlPath: "/usr/bin/coredumpctl",
+		Directory:       "/var/lib/systemd/coredump",
+		FilenamePattern: `^core\.(?P<comm>[^.]+)\.(?P<uid>\d+)\.[0-9a-f]+\.(?P<pid>\d+)\.`,
+		Timeout:         internal.Duration{Duration: time.Second * 5},
+	}
+}
+
+// Description returns a short description about the plugin.
+func (c *Coredumps) Description() string {
+	return "Report the core dumps written since the previous gather."
+}
+
+// SampleConfig returns a sample configuration for the plugin.
+func (c *Coredumps) SampleConfig() string {
+	return `
+	## Where to look for core dumps: "coredumpctl" to list those caught by
+	## systemd-coredump, or "directory" to watch the directory the
+	## kernel.core_pattern sysctl writes them to.
+	#source = "coredumpctl"
+
+	## Path of the coredumpctl binary.
+	#coredumpctl_path = "/usr/bin/coredumpctl"
+
+	## Directory the core dumps are written to, with source = "directory".
+	#directory = "/var/lib/systemd/coredump"
+
+	## Regular expression matching the names of the core dump files, with
+	## source = "directory". The named groups comm, pid, uid and signal, when
+	## present, are reported; the default matches the files of
+	## systemd-coredump.
+	#filename_pattern = '^core\.(?P<comm>[^.]+)\.(?P<uid>\d+)\.[0-9a-f]+\.(?P<pid>\d+)\.'
+
+	## Timeout for coredumpctl to complete.
+	#timeout = "5s"
+	`
+}
+
+// Gather lists the core dumps written since the previous gather and stores
+// one metric per core dump in the accumulator acc. The first gather only
+// records the current time, so that older core dumps are not reported.
+func (c *Coredumps) Gather(acc telegraf.Accumulator) error {
+	if !c.compiled {
+		var err error
+		c.filename, err = regexp.Compile(c.FilenamePattern)
+		if err != nil {
+			acc.AddError(err)
+			return fmt.Errorf("coredumps: invalid filename_pattern: %s", err)
+		}
+		c.compiled = true
+	}
+
+	now := time.Now().UTC()
+	if c.since.IsZero() {
+		c.since = now
+		return nil
+	}
+
+	var dumps []coredump
+	var err error
+	switch c.Source {
+	case sourceCoredumpctl:
+		dumps, err = c.listCoredumpctl()
+	case sourceDirectory:
+		dumps, err = c.listDirectory()
+	default:
+		err = fmt.Errorf("unknown source %q", c.Source)
+	}
+	if err != nil {
+		acc.AddError(err)
+		return fmt.Errorf("coredumps: unable to gather metrics: %s", err)
+	}
+
+	for _, dump := range dumps {
+		// Core dumps written while listing are left to the next gather.
+		if !dump.time.After(c.since) || dump.time.After(now) {
+			continue
+		}
+		tags := map[string]string{
+			"source": c.Source,
+		}
+		if dump.comm != "" {
+			tags["comm"] = dump.comm
+		}
+		if dump.exe != "" {
+			tags["exe"] = dump.exe
+		}
+		fields := map[string]interface{}{
+			"size_bytes": dump.size,
+		}
+		if dump.pid > 0 {
+			fields["pid"] = dump.pid
+		}
+		if dump.uid >= 0 {
+			fields["uid"] = dump.uid
+		}
+		if dump.signal > 0 {
+			fields["signal_number"] = dump.signal
+			tags["signal"] = signalName(dump.signal)
+		}
+		acc.AddFields(measurement, fields, tags, dump.time)
+	}
+	c.since = now
+
+	return nil
+}
+
+// listCoredumpctl lists the core dumps caught by systemd-coredump since the
+// previous gather.
+func (c *Coredumps) listCoredumpctl() ([]coredump, error) {
+	var out bytes.Buffer
+	cmd := exec.Command(c.CoredumpctlPath, "list", "--json=short", "--no-pager",
+		fmt.Sprintf("--since=@%d", c.since.Unix()))
+	cmd.Stdout = &out
+	if err := internal.RunTimeout(cmd, c.Timeout.Duration); err != nil {
+		// coredumpctl exits with an error when no core dump matches.
+		if _, ok := err.(*exec.ExitError); ok && out.Len() == 0 {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return parseCoredumpctl(out.Bytes())
+}
+
+// parseCoredumpctl parses the output of coredumpctl list --json=short.
+func parseCoredumpctl(out []byte) ([]coredump, error) {
+	var entries []entry
+	if err := json.Unmarshal(out, &entries); err != nil {
+		return nil, fmt.Errorf("unable to parse coredumpctl output: %s", err)
+	}
+
+	dumps := make([]coredump, 0, len(entries))
+	for _, entry := range entries {
+		dump := coredump{
+			time:   time.Unix(0, entry.Time*int64(time.Microsecond)).UTC(),
+			comm:   filepath.Base(entry.Exe),
+			exe:    entry.Exe,
+			pid:    entry.Pid,
+			uid:    entry.UID,
+			signal: entry.Sig,
+		}
+		if entry.Size != nil {
+			dump.size = *entry.Size
+		}
+		dumps = append(dumps, dump)
+	}
+
+	return dumps, nil
+}
+
+// listDirectory lists the core dump files of the directory, telling the
+// process that dumped core from their name.
+func (c *Coredumps) listDirectory() ([]coredump, error) {
+	files, err := ioutil.ReadDir(c.Directory)
+	if err != nil {
+		return nil, err
+	}
+
+	var dumps []coredump
+	for _, file := range files {
+		if !file.Mode().IsRegular() {
+			continue
+		}
+		match := c.filename.FindStringSubmatch(file.Name())
+		if match == nil {
+			continue
+		}
+
+		dump := coredump{
+			time: file.ModTime().UTC(),
+			uid:  -1,
+			size: file.Size(),
+		}
+		for i, name := range c.filename.SubexpNames() {
+			switch name {
+			case "comm":
+				dump.comm = match[i]
+			case "pid":
+				dump.pid, _ = strconv.ParseInt(match[i], 10, 64)
+			case "uid":
+				if uid, err := strconv.ParseInt(match[i], 10, 64); err == nil {
+					dump.uid = uid
+				}
+			case "signal":
+				dump.signal, _ = strconv.Atoi(match[i])
+			}
+		}
+		dumps = append(dumps, dump)
+	}
+
+	return dumps, nil
+}
+
+// signalName returns the name of the signal number.
+func signalName(number int) string {
+	if name, ok := signalNames[number]; ok {
+		return name
+	}
+	return strconv.Itoa(number)
+}
@@ -0,0 +1,161 @@
+package coredumps
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestParseCoredumpctl(t *testing.T) {
+	// testdata/coredumpctl.json is the output of coredumpctl list
+	// --json=short of systemd 250, the size of the core dumps not stored
+	// being omitted.
+	out, err := ioutil.ReadFile("testdata/coredumpctl.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dumps, err := parseCoredumpctl(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []coredump{
+		{
+			time: time.Unix(1634284923, 123456000).UTC(), comm: "python3.10", exe: "/usr/bin/python3.10",
+			pid: 4242, uid: 1000, signal: 11, size: 1245184,
+		},
+		{
+			time: time.Unix(1634285100, 1000).UTC(), comm: "nginx", exe: "/usr/sbin/nginx",
+			pid: 777, uid: 0, signal: 6,
+		},
+		{
+			time: time.Unix(1634285220, 500000000).UTC(), comm: "worker", exe: "/opt/app/bin/worker",
+			pid: 31337, uid: 33, signal: 64, size: 2147483648,
+		},
+	}
+	if !reflect.DeepEqual(dumps, expected) {
+		t.Errorf("\n got %+v\nwant %+v", dumps, expected)
+	}
+
+	if _, err := parseCoredumpctl([]byte("No coredumps found.\n")); err == nil {
+		t.Error("text output accepted")
+	}
+}
+
+// writeCore writes a core dump file name of size bytes to dir, modified at
+// tm.
+func writeCore(t *testing.T, dir, name string, size int, tm time.Time) {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, make([]byte, size), 0640); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, tm, tm); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGatherDirectory(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	dir := t.TempDir()
+
+	// Files written by systemd-coredump, and by a kernel.core_pattern of
+	// core.%e.%p.%s.
+	writeCore(t, dir, "core.python3.1000.5f3b2c1d0e9f4a7b8c6d5e4f3a2b1c0d.4242.1634284923000000.zst", 512, now.Add(-time.Minute))
+	writeCore(t, dir, "core.nginx.0.5f3b2c1d0e9f4a7b8c6d5e4f3a2b1c0d.777.1634285100000000.zst", 256, now.Add(-2*time.Hour))
+	writeCore(t, dir, "core.nginx.777.11", 1024, now.Add(-time.Minute))
+	writeCore(t, dir, "notes.txt", 1, now.Add(-time.Minute))
+	if err := os.Mkdir(filepath.Join(dir, "core.dir.0.0.1.0"), 0750); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		pattern  string
+		expected []telegraf.Metric
+	}{
+		{
+			// The core dump of nginx was written before the previous
+			// gather.
+			name: "systemd-coredump",
+			expected: []telegraf.Metric{
+				testutil.MustMetric(measurement,
+					map[string]string{"source": "directory", "comm": "python3"},
+					map[string]interface{}{"pid": int64(4242), "uid": int64(1000), "size_bytes": int64(512)},
+					now.Add(-time.Minute)),
+			},
+		},
+		{
+			name:    "core_pattern",
+			pattern: `^core\.(?P<comm>[^.]+)\.(?P<pid>\d+)\.(?P<signal>\d+)$`,
+			expected: []telegraf.Metric{
+				testutil.MustMetric(measurement,
+					map[string]string{"source": "directory", "comm": "nginx", "signal": "SIGSEGV"},
+					map[string]interface{}{"pid": int64(777), "signal_number": 11, "size_bytes": int64(1024)},
+					now.Add(-time.Minute)),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newCoredumps()
+			c.Source = sourceDirectory
+			c.Directory = dir
+			if tt.pattern != "" {
+				c.FilenamePattern = tt.pattern
+			}
+
+			// The first gather reports none of the core dumps already
+			// written.
+			var acc testutil.Accumulator
+			if err := c.Gather(&acc); err != nil {
+				t.Fatal(err)
+			}
+			if len(acc.Metrics) != 0 {
+				t.Errorf("first gather reported %v", acc.Metrics)
+			}
+
+			c.since = now.Add(-time.Hour)
+			if err := c.Gather(&acc); err != nil {
+				t.Fatal(err)
+			}
+			testutil.RequireMetricsEqual(t, tt.expected, acc.GetTelegrafMetrics())
+		})
+	}
+}
+
+func TestGatherErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config func(c *Coredumps)
+	}{
+		{"invalid pattern", func(c *Coredumps) { c.FilenamePattern = `(?P<comm>` }},
+		{"unknown source", func(c *Coredumps) { c.Source = "abrt" }},
+		{"missing directory", func(c *Coredumps) { c.Source = sourceDirectory; c.Directory = "testdata/missing" }},
+	}
+
+	for _, tt := range tests {
+		c := newCoredumps()
+		tt.config(c)
+		c.since = time.Now().Add(-time.Hour)
+
+		var acc testutil.Accumulator
+		if err := c.Gather(&acc); err == nil || len(acc.Errors) != 1 {
+			t.Errorf("%s: error %v, errors %v", tt.name, err, acc.Errors)
+		}
+	}
+}
+
+func TestSignalName(t *testing.T) {
+	for number, name := range map[int]string{11: "SIGSEGV", 6: "SIGABRT", 64: "64"} {
+		if got := signalName(number); got != name {
+			t.Errorf("signal %d: %q, expected %q", number, got, name)
+		}
+	}
+}
@@ -0,0 +1,312 @@
+package cronjobs
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gpapag/telegraf-plugins/pkg/psinfo"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const (
+	measurement      = `cronjobs`
+	maxCommandLength = 256
+)
+
+// Cronjobs reports whether the jobs scheduled in the crontabs of the host
+// were started by cron when they were due.
+type Cronjobs struct {
+	SystemCrontabs []string
+	UserCrontabs   []string
+	Window         internal.Duration
+	Source         string
+	LogFiles       []string
+	JournalctlPath string `toml:"journalctl_path"`
+	Timeout        internal.Duration
+
+	procFS   psinfo.ProcFS
+	now      func() time.Time
+	started  time.Time
+	logFiles map[string]logFile
+	cursor   string
+	states   map[string]*jobState
+}
+
+// jobState tracks the runs of a job across gathers.
+type jobState struct {
+	next       time.Time
+	pending    []time.Time
+	runs       []time.Time
+	seenRuns   int
+	missedRuns int
+	lastMissed bool
+}
+
+// jobProcess is a process started by cron for a job.
+type jobProcess struct {
+	cmdline string
+	started time.Time
+}
+
+// init initializes the package.
+func init() {
+	inputs.Add("cronjobs", func() telegraf.Input {
+		return newCronjobs()
+	})
+}
+
+// newCronjobs returns a pointer to a new Cronjobs object.
+func newCronjobs() *Cronjobs {
+	return &Cronjobs{
+		SystemCrontabs: []string{"/etc/crontab", "/etc/cron.d/*"},
+		UserCrontabs:   []string{"/var/spool/cron/crontabs/*", "/var/spool/cron/*"},
+		Window:         internal.Duration{Duration: time.Minute * 5},
+		Source:         sourceLog,
+		LogFiles:       []string{"/var/log/syslog", "/var/log/cron"},
+		JournalctlPath: "/usr/bin/journalctl",
+		Timeout:        internal.Duration{Duration: time.Second * 5},
+		procFS:         psinfo.DefaultProcFS,
+		now:            time.Now,
+		logFiles:       make(map[string]logFile),
+		states:         make(map[string]*jobState),
+	}
+}
+
+// Description returns a short description about the plugin.
+func (c *Cronjobs) Description() string {
+	return "Report whether scheduled cron jobs ran when they were due."
+}
+
+// SampleConfig returns a sample configuration for the plugin.
+func (c *Cronjobs) SampleConfig() string {
+	return `
+	## Crontabs naming the user of each job in a column of their own; glob
+	## patterns are supported.
+	#system_crontabs = ["/etc/crontab", "/etc/cron.d/*"]
+
+	## Crontabs of a single user, named after the user.
+	#user_crontabs = ["/var/spool/cron/crontabs/*", "/var/spool/cron/*"]
+
+	## Time after its scheduled time within which a job must be started by
+	## cron, or the run is reported as missed.
+	#window = "5m"
+
+	## Where the runs of the jobs are read from, as logged by cron when it
+	## starts them: "log" to read the log_files, or "journal" to query the
+	## entries of cron in the systemd journal.
+	#source = "log"
+
+	## Log files cron logs to, with source = "log"; missing files are
+	## skipped.
+	#log_files = ["/var/log/syslog", "/var/log/cron"]
+
+	## Path of the journalctl binary, with source = "journal", and the
+	## time it has to complete.
+	#journalctl_path = "/usr/bin/journalctl"
+	#timeout = "5s"
+	`
+}
+
+// Gather reads the crontabs and the runs cron logged since the previous
+// gather, looks for the jobs among the running processes and stores the
+// state of each job in the accumulator acc.
+func (c *Cronjobs) Gather(acc telegraf.Accumulator) error {
+	systemJobs, err := readCrontabs(c.SystemCrontabs, true)
+	if err != nil {
+		acc.AddError(err)
+		return fmt.Errorf("cronjobs: unable to read crontabs: %s", err)
+	}
+	userJobs, err := readCrontabs(c.UserCrontabs, false)
+	if err != nil {
+		acc.AddError(err)
+		return fmt.Errorf("cronjobs: unable to read crontabs: %s", err)
+	}
+
+	now := c.now()
+	if c.started.IsZero() {
+		c.started = now
+	}
+	runs, err := c.runs(now)
+	if err != nil {
+		acc.AddError(fmt.Errorf("cronjobs: unable to read the runs: %s", err))
+	}
+	processes, err := c.jobProcesses()
+	if err != nil {
+		acc.AddError(err)
+		return fmt.Errorf("cronjobs: unable to gather metrics: %s", err)
+	}
+
+	states := make(map[string]*jobState)
+	for _, j := range append(systemJobs, userJobs...) {
+		state, ok := c.states[j.key()]
+		if !ok {
+			state = &jobState{next: j.schedule.next(now)}
+		}
+		states[j.key()] = state
+
+		// A job still running when seen tells when it started, which the
+		// log may have missed, such as when rotated in between.
+		var started []time.Time
+		for _, r := range runs {
+			if r.matches(j) {
+				started = append(started, r.time)
+			}
+		}
+		running := false
+		for _, process := range processes {
+			if j.runBy(process.cmdline) {
+				running = true
+				started = append(started, process.started)
+			}
+		}
+		c.update(state, j, started, now)
+
+		command := j.command
+		if len(command) > maxCommandLength {
+			command = command[:maxCommandLength]
+		}
+		tags := map[string]string{
+			"user":     j.user,
+			"file":     j.file,
+			"schedule": j.spec,
+			"command":  command,
+		}
+		fields := map[string]interface{}{
+			"running":         running,
+			"seen_runs":       state.seenRuns,
+			"missed_runs":     state.missedRuns,
+			"last_run_missed": state.lastMissed,
+		}
+		if !state.next.IsZero() {
+			fields["next_run"] = state.next.Unix()
+		}
+		acc.AddFields(measurement, fields, tags, now.UTC())
+	}
+	c.states = states
+
+	return nil
+}
+
+// runs returns the runs logged by cron since the previous gather.
+func (c *Cronjobs) runs(now time.Time) ([]run, error) {
+	switch c.Source {
+	case sourceJournal:
+		return c.readJournal(now)
+	case sourceLog:
+		var runs []run
+		for _, path := range c.LogFiles {
+			fileRuns, err := c.readLog(path, now)
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return runs, err
+			}
+			runs = append(runs, fileRuns...)
+		}
+		return runs, nil
+	default:
+		return nil, fmt.Errorf("unknown source %q", c.Source)
+	}
+}
+
+// update records the runs of j that became due by now, and settles those
+// started within the window or whose window has passed. started holds the
+// times j was started at since the previous gather.
+func (c *Cronjobs) update(state *jobState, j *job, started []time.Time, now time.Time) {
+	state.runs = append(state.runs, started...)
+	for !state.next.IsZero() && !state.next.After(now) {
+		state.pending = append(state.pending, state.next)
+		state.next = j.schedule.next(state.next)
+	}
+
+	var pending []time.Time
+	for _, due := range state.pending {
+		deadline := due.Add(c.Window.Duration)
+		switch {
+		case startedWithin(state.runs, due, deadline):
+			state.seenRuns++
+			state.lastMissed = false
+		case now.After(deadline):
+			state.missedRuns++
+			state.lastMissed = true
+		default:
+			pending = append(pending, due)
+		}
+	}
+	state.pending = pending
+
+	// Only the runs that may still settle a pending run are kept.
+	var runs []time.Time
+	for _, t := range state.runs {
+		if len(pending) > 0 && !t.Before(pending[0]) {
+			runs = append(runs, t)
+		}
+	}
+	state.runs = runs
+}
+
+// startedWithin reports whether one of runs is between due and deadline.
+// Cron logs the time in seconds, and the start times of processes are
+// rounded to the clock tick, so runs are compared to the second.
+func startedWithin(runs []time.Time, due, deadline time.Time) bool {
+	for _, t := range runs {
+		t = t.Truncate(time.Second)
+		if !t.Before(due) && !t.After(deadline) {
+			return true
+		}
+	}
+	return false
+}
+
+// cronNames are the commands of the cron daemons, and of the processes they
+// fork to run each job.
+var cronNames = map[string]bool{"cron": true, "crond": true, "CRON": true, "CROND": true}
+
+// jobProcesses returns the processes started by cron for the jobs: the
+// children of cron, and of the processes it forks for each job.
+func (c *Cronjobs) jobProcesses() ([]jobProcess, error) {
+	pids, err := c.procFS.Pids()
+	if err != nil {
+		return nil, err
+	}
+	bootTime, err := c.procFS.ReadBootTime()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make(map[int]psinfo.Stat, len(pids))
+	for _, pid := range pids {
+		if stat, err := c.procFS.ReadStat(pid); err == nil {
+			stats[pid] = stat
+		}
+	}
+
+	var processes []jobProcess
+	for pid, stat := range stats {
+		parent, ok := stats[stat.Ppid]
+		if !ok || !cronNames[parent.Comm] || cronNames[stat.Comm] {
+			continue
+		}
+		cmdline, err := c.procFS.ReadCmdline(pid)
+		if err != nil || cmdline == "" {
+			continue
+		}
+		started := time.Unix(bootTime, 0).Add(time.Duration(stat.StartTime) * time.Second / psinfo.ClockTicks)
+		processes = append(processes, jobProcess{cmdline: cmdline, started: started})
+	}
+	return processes, nil
+}
+
+// runBy reports whether cmdline, of a process started by cron, runs j.
+// Cron runs jobs with "sh -c <command>", so the shell carries the whole
+// command; shells replacing themselves with a single program leave the
+// command itself.
+func (j *job) runBy(cmdline string) bool {
+	return cmdline == j.command || strings.HasSuffix(cmdline, " -c "+j.command)
+}
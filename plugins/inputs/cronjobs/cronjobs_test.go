@@ -0,0 +1,194 @@
+package cronjobs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/gpapag/telegraf-plugins/pkg/psinfo/psinfotest"
+)
+
+// testHost is the host of the synthetic proc filesystems of the tests,
+// booted at midnight on the day of testNow.
+var testHost = psinfotest.Host{Uptime: 32400, BootTime: testNow.Add(-9 * time.Hour).Unix(), MemTotal: 8 << 20}
+
+// newTestProc returns a synthetic proc filesystem holding cron, running
+// the backup job started at 08:45, and processes whose command line holds
+// that of the job without being run by cron.
+func newTestProc(t *testing.T) *psinfotest.Proc {
+	proc := psinfotest.NewProc(t, testHost)
+	proc.Add(psinfotest.Process{Pid: 1, Comm: "systemd", Args: []string{"/sbin/init"}})
+	proc.Add(psinfotest.Process{Pid: 600, Ppid: 1, Comm: "cron", Args: []string{"/usr/sbin/cron", "-f"}})
+	proc.Add(psinfotest.Process{Pid: 7000, Ppid: 600, Comm: "cron", Args: []string{"/usr/sbin/CRON", "-f"}})
+	proc.Add(psinfotest.Process{
+		Pid: 7001, Ppid: 7000, Comm: "sh",
+		Args:      []string{"/bin/sh", "-c", "/usr/local/bin/backup --quick"},
+		StartTime: uint64((8*time.Hour + 45*time.Minute) / time.Second * 100),
+	})
+	proc.Add(psinfotest.Process{
+		Pid: 7002, Ppid: 1, Comm: "vim",
+		Args: []string{"vim", "/usr/local/bin/backup --quick"},
+	})
+	proc.Add(psinfotest.Process{
+		Pid: 7003, Ppid: 600, Comm: "sh",
+		Args: []string{"/bin/sh", "-c", "/usr/local/bin/backup --quick --full"},
+	})
+	return proc
+}
+
+func TestJobProcesses(t *testing.T) {
+	c := newCronjobs()
+	c.procFS = newTestProc(t).ProcFS()
+
+	processes, err := c.jobProcesses()
+	if err != nil {
+		t.Fatal(err)
+	}
+	j := &job{user: "backup", command: "/usr/local/bin/backup --quick"}
+	var running []jobProcess
+	for _, process := range processes {
+		if j.runBy(process.cmdline) {
+			running = append(running, process)
+		}
+	}
+	if len(running) != 1 {
+		t.Fatalf("processes running the job %v, expected the shell of cron", running)
+	}
+	if started := testNow.Add(-15 * time.Minute); !running[0].started.Equal(started) {
+		t.Errorf("started at %v, expected %v", running[0].started, started)
+	}
+}
+
+// gather runs a gather of c at now and returns the fields of the metric of
+// the job of command.
+func gather(t *testing.T, c *Cronjobs, now time.Time, command string) map[string]interface{} {
+	t.Helper()
+	c.now = func() time.Time { return now }
+	var acc testutil.Accumulator
+	if err := c.Gather(&acc); err != nil {
+		t.Fatal(err)
+	}
+	if len(acc.Errors) > 0 {
+		t.Fatalf("errors: %v", acc.Errors)
+	}
+	for _, m := range acc.Metrics {
+		if m.Tags["command"] == command {
+			return m.Fields
+		}
+	}
+	t.Fatalf("no metric of %q", command)
+	return nil
+}
+
+func TestGather(t *testing.T) {
+	dir := t.TempDir()
+	crontab := filepath.Join(dir, "crontab")
+	if err := ioutil.WriteFile(crontab, []byte("*/5 * * * * root /usr/local/bin/rotate\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	syslog := filepath.Join(dir, "syslog")
+	if err := ioutil.WriteFile(syslog, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	logRun := func(stamp, command string) {
+		t.Helper()
+		f, err := os.OpenFile(syslog, os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if _, err := f.WriteString(stamp + " web1 CRON[1]: (root) CMD (" + command + ")\n"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := newCronjobs()
+	c.SystemCrontabs = []string{crontab}
+	c.UserCrontabs = nil
+	c.LogFiles = []string{syslog, filepath.Join(dir, "missing")}
+	c.procFS = psinfotest.NewProc(t, testHost).ProcFS()
+	const command = "/usr/local/bin/rotate"
+
+	steps := []struct {
+		name     string
+		at       time.Time
+		logged   []string
+		seen     int
+		missed   int
+		lastMiss bool
+	}{
+		{name: "first gather", at: time.Date(2026, 10, 15, 10, 2, 0, 0, time.UTC)},
+		{
+			// The run lasted a second, long before the gather, and is
+			// still seen.
+			name:   "short run logged",
+			at:     time.Date(2026, 10, 15, 10, 6, 0, 0, time.UTC),
+			logged: []string{"Oct 15 10:05:01"},
+			seen:   1,
+		},
+		{
+			// The run of 10:10 is still within its window.
+			name: "run pending",
+			at:   time.Date(2026, 10, 15, 10, 12, 0, 0, time.UTC),
+			seen: 1,
+		},
+		{
+			// Its window passed with no run, and the run of 10:15 came in
+			// the window of its own.
+			name:     "run missed",
+			at:       time.Date(2026, 10, 15, 10, 16, 0, 0, time.UTC),
+			seen:     1,
+			missed:   1,
+			lastMiss: true,
+		},
+		{
+			name:   "run late within the window",
+			at:     time.Date(2026, 10, 15, 10, 19, 0, 0, time.UTC),
+			logged: []string{"Oct 15 10:18:30"},
+			seen:   2,
+			missed: 1,
+		},
+	}
+	for _, step := range steps {
+		for _, stamp := range step.logged {
+			logRun(stamp, command)
+		}
+		// A run logged for another job, or another user, counts for none.
+		logRun("Oct 15 10:05:01", command+" --force")
+
+		fields := gather(t, c, step.at, command)
+		if fields["seen_runs"] != step.seen || fields["missed_runs"] != step.missed || fields["last_run_missed"] != step.lastMiss {
+			t.Errorf("%s: fields %v, expected %d seen and %d missed runs", step.name, fields, step.seen, step.missed)
+		}
+		if fields["running"] != false {
+			t.Errorf("%s: running %v", step.name, fields["running"])
+		}
+	}
+}
+
+func TestGatherRunning(t *testing.T) {
+	dir := t.TempDir()
+	crontab := filepath.Join(dir, "crontab")
+	if err := ioutil.WriteFile(crontab, []byte("45 8 * * * backup /usr/local/bin/backup --quick\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := newCronjobs()
+	c.SystemCrontabs = []string{crontab}
+	c.UserCrontabs = nil
+	c.LogFiles = nil
+	c.procFS = newTestProc(t).ProcFS()
+	const command = "/usr/local/bin/backup --quick"
+
+	// The job seen running counts as run at its start, absent from the
+	// log.
+	gather(t, c, testNow.Add(-20*time.Minute), command)
+	fields := gather(t, c, testNow, command)
+	if fields["running"] != true || fields["seen_runs"] != 1 || fields["missed_runs"] != 0 {
+		t.Errorf("fields %v", fields)
+	}
+}
@@ -0,0 +1,147 @@
+package cronjobs
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// job is a single entry of a crontab.
+type job struct {
+	file     string
+	user     string
+	spec     string
+	command  string
+	schedule *schedule
+}
+
+// key identifies j across reloads of its crontab.
+func (j *job) key() string {
+	return j.file + "\x00" + j.user + "\x00" + j.spec + "\x00" + j.command
+}
+
+// readCrontabs returns the jobs of the crontabs matching the glob patterns.
+// System crontabs name the user of each job in a column of their own; user
+// crontabs are named after their user.
+func readCrontabs(patterns []string, system bool) ([]*job, error) {
+	var jobs []*job
+	for _, pattern := range patterns {
+		files, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			info, err := os.Stat(file)
+			if err != nil || info.IsDir() {
+				continue
+			}
+			fileJobs, err := readCrontab(file, system)
+			if err != nil {
+				return nil, err
+			}
+			jobs = append(jobs, fileJobs...)
+		}
+	}
+	return jobs, nil
+}
+
+// readCrontab returns the jobs of a single crontab. Entries that cannot be
+// parsed, @reboot entries, and entries whose command is empty, such as
+// those starting with the % introducing the input of the command, are
+// skipped.
+func readCrontab(file string, system bool) ([]*job, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var jobs []*job
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || isAssignment(line) {
+			continue
+		}
+
+		// The command is the rest of the line as written, so that it
+		// matches the command line of the shell running it.
+		var spec []string
+		first, rest := cutField(line)
+		if macro, ok := macros[first]; ok {
+			spec = strings.Fields(macro)
+		} else {
+			spec = []string{first}
+			for len(spec) < 5 && rest != "" {
+				var field string
+				field, rest = cutField(rest)
+				spec = append(spec, field)
+			}
+			if len(spec) < 5 {
+				continue
+			}
+		}
+
+		user := filepath.Base(file)
+		if system {
+			if user, rest = cutField(rest); user == "" {
+				continue
+			}
+		}
+		cmd := command(rest)
+		if cmd == "" {
+			continue
+		}
+
+		sched, err := parseSchedule(spec)
+		if err != nil {
+			continue
+		}
+		jobs = append(jobs, &job{
+			file:     file,
+			user:     user,
+			spec:     strings.Join(spec, " "),
+			command:  cmd,
+			schedule: sched,
+		})
+	}
+
+	return jobs, scanner.Err()
+}
+
+// cutField returns the first whitespace delimited field of s, and the rest
+// of s after it as written.
+func cutField(s string) (field, rest string) {
+	s = strings.TrimLeft(s, " \t")
+	i := strings.IndexAny(s, " \t")
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], s[i:]
+}
+
+// isAssignment reports whether a crontab line sets an environment variable.
+func isAssignment(line string) bool {
+	i := strings.IndexAny(line, "= \t")
+	return i > 0 && line[i] == '=' && !strings.ContainsAny(line[:1], "*@0123456789")
+}
+
+// command returns the command run by cron for the command field of an
+// entry: the text up to the first unescaped %, which starts the input of
+// the command, with escaped percent signs restored.
+func command(field string) string {
+	var b strings.Builder
+	for i := 0; i < len(field); i++ {
+		switch {
+		case field[i] == '\\' && i+1 < len(field) && field[i+1] == '%':
+			b.WriteByte('%')
+			i++
+		case field[i] == '%':
+			return strings.TrimSpace(b.String())
+		default:
+			b.WriteByte(field[i])
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
@@ -0,0 +1,62 @@
+package cronjobs
+
+import (
+	"testing"
+)
+
+func TestReadCrontabs(t *testing.T) {
+	system, err := readCrontabs([]string{"testdata/crontab", "testdata/cron.d/*"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := readCrontabs([]string{"testdata/crontabs/*", "testdata/missing/*"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []job{
+		{file: "testdata/crontab", user: "root", spec: "17 * * * *", command: "cd / && run-parts --report /etc/cron.hourly"},
+		{file: "testdata/crontab", user: "root", spec: "25 6 * * *", command: "test -x /usr/sbin/anacron || ( cd / && run-parts --report /etc/cron.daily )"},
+		{file: "testdata/crontab", user: "root", spec: "47 6 * * 7", command: "test -x /usr/sbin/anacron || ( cd / && run-parts --report /etc/cron.weekly )"},
+		{file: "testdata/crontab", user: "root", spec: "52 6 1 * *", command: "test -x /usr/sbin/anacron || ( cd / && run-parts --report /etc/cron.monthly )"},
+		{file: "testdata/cron.d/backup", user: "backup", spec: "*/15 * * * *", command: "/usr/local/bin/backup --quick"},
+		{file: "testdata/cron.d/backup", user: "backup", spec: "0 0 * * *", command: "/usr/local/bin/backup --full"},
+		{file: "testdata/cron.d/backup", user: "postgres", spec: "0 3 * * mon-fri", command: "pg_dump shop > /var/backups/shop-$(date +%F).sql"},
+		{file: "testdata/crontabs/alice", user: "alice", spec: "0 9 * * 1-5", command: "/home/alice/bin/standup"},
+		{file: "testdata/crontabs/alice", user: "alice", spec: "30 18 * * fri", command: "mail -s report alice"},
+	}
+	jobs := append(system, user...)
+	if len(jobs) != len(expected) {
+		for _, j := range jobs {
+			t.Logf("%+v", *j)
+		}
+		t.Fatalf("%d jobs, expected %d", len(jobs), len(expected))
+	}
+	for i, j := range jobs {
+		e := expected[i]
+		if j.file != e.file || j.user != e.user || j.spec != e.spec || j.command != e.command {
+			t.Errorf("job %d:\n got %q %q %q %q\nwant %q %q %q %q", i,
+				j.file, j.user, j.spec, j.command, e.file, e.user, e.spec, e.command)
+		}
+		if j.schedule == nil {
+			t.Errorf("job %d: no schedule", i)
+		}
+	}
+}
+
+func TestCommand(t *testing.T) {
+	tests := []struct {
+		field    string
+		expected string
+	}{
+		{" /bin/true ", "/bin/true"},
+		{"date +\\%s", "date +%s"},
+		{"mail -s hi bob%Hello%Bye", "mail -s hi bob"},
+		{"%input only", ""},
+	}
+	for _, tt := range tests {
+		if cmd := command(tt.field); cmd != tt.expected {
+			t.Errorf("command(%q) = %q, expected %q", tt.field, cmd, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package cronjobs
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+)
+
+// Sources of the runs of the jobs.
+const (
+	sourceJournal = `journal`
+	sourceLog     = `log`
+)
+
+// run is a job started by cron, as logged when it started it.
+type run struct {
+	time    time.Time
+	user    string
+	command string
+}
+
+// cmdRe matches the line logged by cron, cronie and their derivatives for
+// every job they start, such as
+// "Oct 15 07:49:01 host CRON[1234]: (root) CMD (command)", with the time
+// written the syslog way or as RFC 3339 by rsyslog and journalctl.
+var cmdRe = regexp.MustCompile(`^(\d{4}-\d\d-\d\dT\S+|[A-Z][a-z]{2} +\d+ \d\d:\d\d:\d\d) \S+ (?i:cron|crond)\[\d+\]: \((\S+)\) CMD \((.*)\)$`)
+
+// cursorPrefix starts the line journalctl --show-cursor ends its output
+// with.
+const cursorPrefix = `-- cursor: `
+
+// parseRun returns the run logged by line, if it logs one. Times without a
+// year, as syslog writes them, are taken in the location of now and in the
+// latest year not putting them after now.
+func parseRun(line string, now time.Time) (run, bool) {
+	m := cmdRe.FindStringSubmatch(line)
+	if m == nil {
+		return run{}, false
+	}
+
+	var t time.Time
+	var err error
+	if strings.Contains(m[1], "T") {
+		if t, err = time.Parse(time.RFC3339, m[1]); err != nil {
+			t, err = time.Parse("2006-01-02T15:04:05Z0700", m[1])
+		}
+	} else {
+		stamp := fmt.Sprintf("%d %s", now.Year(), strings.Join(strings.Fields(m[1]), " "))
+		t, err = time.ParseInLocation("2006 Jan 2 15:04:05", stamp, now.Location())
+		if err == nil && t.After(now.Add(24*time.Hour)) {
+			t = t.AddDate(-1, 0, 0)
+		}
+	}
+	if err != nil {
+		return run{}, false
+	}
+	return run{time: t, user: m[2], command: m[3]}, true
+}
+
+// parseRuns returns the runs logged in the lines of r, and the cursor of
+// the last entry when journalctl wrote one.
+func parseRuns(r io.Reader, now time.Time) ([]run, string, error) {
+	var runs []run
+	var cursor string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, cursorPrefix) {
+			cursor = strings.TrimPrefix(line, cursorPrefix)
+			continue
+		}
+		if run, ok := parseRun(line, now); ok {
+			runs = append(runs, run)
+		}
+	}
+	return runs, cursor, scanner.Err()
+}
+
+// matches reports whether r is a run of j. Cron logs the command field of
+// the entry as written, including the input following an unescaped %.
+func (r run) matches(j *job) bool {
+	return r.user == j.user && (r.command == j.command || command(r.command) == j.command)
+}
+
+// logFile is the position reached in a cron log file.
+type logFile struct {
+	info   os.FileInfo
+	offset int64
+}
+
+// readLog returns the runs logged in path since the previous gather. The
+// first gather only records the end of the file. A file replaced, as when
+// rotated, or truncated is read from its start.
+func (c *Cronjobs) readLog(path string, now time.Time) ([]run, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	previous, ok := c.logFiles[path]
+	if !ok {
+		c.logFiles[path] = logFile{info: info, offset: info.Size()}
+		return nil, nil
+	}
+	offset := previous.offset
+	if !os.SameFile(previous.info, info) || info.Size() < offset {
+		offset = 0
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	// Only whole lines are read, the rest being left for the next gather.
+	data, err := ioutil.ReadAll(io.LimitReader(f, info.Size()-offset))
+	if err != nil {
+		return nil, err
+	}
+	data = data[:bytes.LastIndexByte(data, '\n')+1]
+	c.logFiles[path] = logFile{info: info, offset: offset + int64(len(data))}
+
+	runs, _, err := parseRuns(bytes.NewReader(data), now)
+	return runs, err
+}
+
+// readJournal returns the runs logged in the journal by cron since the
+// previous gather, or since the start of Telegraf for the first one.
+func (c *Cronjobs) readJournal(now time.Time) ([]run, error) {
+	args := []string{"--output=short-iso", "--no-pager", "--quiet", "--show-cursor"}
+	if c.cursor != "" {
+		args = append(args, "--after-cursor="+c.cursor)
+	} else {
+		args = append(args, fmt.Sprintf("--since=@%d", c.started.Unix()))
+	}
+	for _, identifier := range []string{"CRON", "cron", "CROND", "crond"} {
+		args = append(args, "SYSLOG_IDENTIFIER="+identifier)
+	}
+
+	var out bytes.Buffer
+	cmd := exec.Command(c.JournalctlPath, args...)
+	cmd.Stdout = &out
+	if err := internal.RunTimeout(cmd, c.Timeout.Duration); err != nil {
+		return nil, err
+	}
+
+	runs, cursor, err := parseRuns(&out, now)
+	if cursor != "" {
+		c.cursor = cursor
+	}
+	return runs, err
+}
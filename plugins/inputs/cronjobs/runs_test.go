@@ -0,0 +1,174 @@
+package cronjobs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testNow is the time of the gathers of the tests reading the captures of
+// testdata.
+var testNow = time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC)
+
+func TestParseRuns(t *testing.T) {
+	tests := []struct {
+		file     string
+		expected []run
+		cursor   string
+	}{
+		{
+			// Debian and Ubuntu, through rsyslog to /var/log/syslog.
+			file: "testdata/syslog",
+			expected: []run{
+				{time.Date(2026, 10, 15, 7, 45, 1, 0, time.UTC), "root", "cd / && run-parts --report /etc/cron.hourly"},
+				{time.Date(2026, 10, 15, 7, 45, 1, 0, time.UTC), "backup", "/usr/local/bin/backup --quick"},
+				{time.Date(2026, 10, 5, 18, 30, 1, 0, time.UTC), "alice", "mail -s report alice%Weekly report%Regards"},
+			},
+		},
+		{
+			// cronie on RHEL and Fedora, to /var/log/cron.
+			file: "testdata/cron",
+			expected: []run{
+				{time.Date(2026, 10, 15, 8, 0, 1, 0, time.UTC), "root", "run-parts /etc/cron.hourly"},
+				{time.Date(2026, 10, 15, 8, 0, 1, 0, time.UTC), "postgres", "pg_dump shop > /var/backups/shop-$(date +\\%F).sql"},
+			},
+		},
+		{
+			// journalctl --output=short-iso --show-cursor.
+			file: "testdata/journal",
+			expected: []run{
+				{time.Date(2026, 10, 15, 8, 15, 1, 0, time.UTC), "backup", "/usr/local/bin/backup --quick"},
+				{time.Date(2026, 10, 15, 8, 17, 1, 123000, time.UTC), "root", "cd / && run-parts --report /etc/cron.hourly"},
+			},
+			cursor: "s=6f2e1b0c9d8e4f3a;i=1a2b3;b=5c4d3e2f;m=4f3e2d1c;t=5e4d3c2b1a098;x=1234abcd",
+		},
+	}
+
+	for _, tt := range tests {
+		f, err := os.Open(tt.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		runs, cursor, err := parseRuns(f, testNow)
+		f.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.file, err)
+		}
+		if len(runs) != len(tt.expected) {
+			t.Errorf("%s: runs %v, expected %v", tt.file, runs, tt.expected)
+			continue
+		}
+		for i, r := range runs {
+			e := tt.expected[i]
+			if !r.time.Equal(e.time) || r.user != e.user || r.command != e.command {
+				t.Errorf("%s: run %d %v, expected %v", tt.file, i, r, e)
+			}
+		}
+		if cursor != tt.cursor {
+			t.Errorf("%s: cursor %q, expected %q", tt.file, cursor, tt.cursor)
+		}
+	}
+}
+
+func TestParseRunYear(t *testing.T) {
+	// Syslog times have no year: a run logged just before the new year
+	// belongs to the previous one.
+	now := time.Date(2027, 1, 1, 0, 0, 30, 0, time.UTC)
+	r, ok := parseRun("Dec 31 23:59:01 web1 CRON[1]: (root) CMD (/bin/true)", now)
+	if !ok || !r.time.Equal(time.Date(2026, 12, 31, 23, 59, 1, 0, time.UTC)) {
+		t.Errorf("run %v %v", r, ok)
+	}
+}
+
+func TestRunMatches(t *testing.T) {
+	j := &job{user: "postgres", command: "pg_dump shop > /var/backups/shop-$(date +%F).sql"}
+	tests := []struct {
+		r        run
+		expected bool
+	}{
+		{run{user: "postgres", command: "pg_dump shop > /var/backups/shop-$(date +%F).sql"}, true},
+		// Cron logs the command field as written in the crontab.
+		{run{user: "postgres", command: "pg_dump shop > /var/backups/shop-$(date +\\%F).sql"}, true},
+		{run{user: "root", command: "pg_dump shop > /var/backups/shop-$(date +%F).sql"}, false},
+		{run{user: "postgres", command: "pg_dump shop"}, false},
+	}
+	for _, tt := range tests {
+		if matched := tt.r.matches(j); matched != tt.expected {
+			t.Errorf("%v matches %v, expected %v", tt.r, matched, tt.expected)
+		}
+	}
+}
+
+func TestReadLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "syslog")
+	write := func(content string, flag int) {
+		t.Helper()
+		f, err := os.OpenFile(path, flag|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if _, err := f.WriteString(content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := newCronjobs()
+	commands := func() []string {
+		t.Helper()
+		runs, err := c.readLog(path, testNow)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var commands []string
+		for _, r := range runs {
+			commands = append(commands, r.command)
+		}
+		return commands
+	}
+	line := func(command string) string {
+		return "Oct 15 08:00:01 web1 CRON[1]: (root) CMD (" + command + ")\n"
+	}
+
+	// The lines logged before the first gather are skipped.
+	write(line("before"), os.O_TRUNC)
+	if runs := commands(); runs != nil {
+		t.Errorf("runs %v on the first gather", runs)
+	}
+
+	// A line being written is left for the next gather.
+	write(line("first")+line("second")[:20], os.O_APPEND)
+	if runs := commands(); !reflect.DeepEqual(runs, []string{"first"}) {
+		t.Errorf("runs %v, expected [first]", runs)
+	}
+	write(line("second")[20:], os.O_APPEND)
+	if runs := commands(); !reflect.DeepEqual(runs, []string{"second"}) {
+		t.Errorf("runs %v, expected [second]", runs)
+	}
+	if runs := commands(); runs != nil {
+		t.Errorf("runs %v with nothing logged", runs)
+	}
+
+	// A rotated file is read from its start.
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	write(line("rotated"), os.O_TRUNC)
+	if runs := commands(); !reflect.DeepEqual(runs, []string{"rotated"}) {
+		t.Errorf("runs %v, expected [rotated]", runs)
+	}
+
+	// So is a truncated one, as by the copytruncate of logrotate.
+	if err := ioutil.WriteFile(path, []byte(line("truncated")[:10]), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if runs := commands(); runs != nil {
+		t.Errorf("runs %v of a truncated file", runs)
+	}
+	write(line("truncated")[10:], os.O_APPEND)
+	if runs := commands(); !reflect.DeepEqual(runs, []string{"truncated"}) {
+		t.Errorf("runs %v, expected [truncated]", runs)
+	}
+}
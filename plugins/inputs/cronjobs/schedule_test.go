@@ -0,0 +1,98 @@
+package cronjobs
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// date returns the time of the tests at the given date and time, in UTC.
+func date(year int, month time.Month, day, hour, min int) time.Time {
+	return time.Date(year, month, day, hour, min, 0, 0, time.UTC)
+}
+
+func TestNext(t *testing.T) {
+	// Thursday, 4 March 2021.
+	from := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		spec     string
+		from     time.Time
+		expected time.Time
+	}{
+		{"every minute", "* * * * *", from, date(2021, 3, 4, 5, 7)},
+		{"strictly after", "7 5 * * *", date(2021, 3, 4, 5, 7), date(2021, 3, 5, 5, 7)},
+		{"daily", "30 2 * * *", from, date(2021, 3, 5, 2, 30)},
+		{"range", "0 9-17 * * *", date(2021, 3, 4, 17, 30), date(2021, 3, 5, 9, 0)},
+		{"step", "*/15 * * * *", from, date(2021, 3, 4, 5, 15)},
+		{"step of range", "10-40/10 * * * *", date(2021, 3, 4, 5, 41), date(2021, 3, 4, 6, 10)},
+		{"step from value", "5/20 * * * *", date(2021, 3, 4, 5, 26), date(2021, 3, 4, 5, 45)},
+		{"list", "0 6,18 * * *", date(2021, 3, 4, 7, 0), date(2021, 3, 4, 18, 0)},
+		{"list of ranges", "0 1-2,22-23 * * *", date(2021, 3, 4, 3, 0), date(2021, 3, 4, 22, 0)},
+		{"month names", "0 0 1 jan,jul *", from, date(2021, 7, 1, 0, 0)},
+		{"day names", "0 8 * * mon-fri", date(2021, 3, 5, 9, 0), date(2021, 3, 8, 8, 0)},
+		{"upper case names", "0 8 * MAR FRI", from, date(2021, 3, 5, 8, 0)},
+		{"sunday as 7", "0 0 * * 7", from, date(2021, 3, 7, 0, 0)},
+		{"sunday as 0", "0 0 * * 0", from, date(2021, 3, 7, 0, 0)},
+		{"across years", "0 0 1 1 *", from, date(2022, 1, 1, 0, 0)},
+		{"leap day", "0 0 29 2 *", from, date(2024, 2, 29, 0, 0)},
+		{"never", "0 0 31 2 *", from, time.Time{}},
+
+		// A day matching either the day of month or the day of week is
+		// allowed when both are restricted, and one matching both when
+		// either is unrestricted, even with a step.
+		{"dom or dow, dow first", "0 0 13 * 5", from, date(2021, 3, 5, 0, 0)},
+		{"dom or dow, dom first", "0 0 13 * 5", date(2021, 3, 12, 0, 0), date(2021, 3, 13, 0, 0)},
+		{"dom only", "0 0 13 * *", from, date(2021, 3, 13, 0, 0)},
+		{"dow only", "0 0 * * 5", date(2021, 3, 5, 0, 0), date(2021, 3, 12, 0, 0)},
+		{"dom step and dow", "0 0 */10 * 1", from, date(2021, 5, 31, 0, 0)},
+	}
+	for name, spec := range macros {
+		expected := map[string]time.Time{
+			"@yearly":   date(2022, 1, 1, 0, 0),
+			"@annually": date(2022, 1, 1, 0, 0),
+			"@monthly":  date(2021, 4, 1, 0, 0),
+			"@weekly":   date(2021, 3, 7, 0, 0),
+			"@daily":    date(2021, 3, 5, 0, 0),
+			"@midnight": date(2021, 3, 5, 0, 0),
+			"@hourly":   date(2021, 3, 4, 6, 0),
+		}[name]
+		tests = append(tests, struct {
+			name     string
+			spec     string
+			from     time.Time
+			expected time.Time
+		}{name, spec, from, expected})
+	}
+
+	for _, tt := range tests {
+		s, err := parseSchedule(strings.Fields(tt.spec))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if next := s.next(tt.from); !next.Equal(tt.expected) {
+			t.Errorf("%s: next(%v) of %q = %v, expected %v", tt.name, tt.from, tt.spec, next, tt.expected)
+		}
+	}
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	for _, spec := range []string{
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"foo * * * *",
+		"* * * * mon-",
+	} {
+		if _, err := parseSchedule(strings.Fields(spec)); err == nil {
+			t.Errorf("%q accepted", spec)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package entropy
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const measurement = `entropy`
+
+// Entropy reports the entropy available to the kernel random number
+// generator.
+type Entropy struct {
+	procRandom string
+}
+
+// init initializes the package.
+func init() {
+	inputs.Add("entropy", func() telegraf.Input {
+		return newEntropy("/proc/sys/kernel/random")
+	})
+}
+
+// newEntropy returns a pointer to a new Entropy object reading the files in
+// procRandom.
+func newEntropy(procRandom string) *Entropy {
+	return &Entropy{
+		procRandom: procRandom,
+	}
+}
+
+// Description returns a short description about the plugin.
+func (e *Entropy) Description() string {
+	return "Read the entropy available to the kernel random number generator."
+}
+
+// SampleConfig returns a sample configuration for the plugin.
+func (e *Entropy) SampleConfig() string {
+	return `
+	## This plugin has no options.
+	`
+}
+
+// Gather reads the available entropy and stores it in the accumulator acc.
+func (e *Entropy) Gather(acc telegraf.Accumulator) error {
+	now := time.Now().UTC()
+
+	available, err := e.readValue("entropy_avail")
+	if err != nil {
+		acc.AddError(err)
+		return fmt.Errorf("entropy: unable to gather metrics: %s", err)
+	}
+	fields := map[string]interface{}{
+		"available": available,
+	}
+
+	poolSize, err := e.readValue("poolsize")
+	if err != nil && !os.IsNotExist(err) {
+		acc.AddError(err)
+		return fmt.Errorf("entropy: unable to gather metrics: %s", err)
+	}
+	if err == nil {
+		fields["pool_size"] = poolSize
+	}
+
+	acc.AddFields(measurement, fields, map[string]string{}, now)
+
+	return nil
+}
+
+// readValue reads the integer held by the file name.
+func (e *Entropy) readValue(name string) (int64, error) {
+	content, err := ioutil.ReadFile(e.procRandom + "/" + name)
+	if err != nil {
+		return 0, err
+	}
+
+	value, err := strconv.ParseInt(strings.TrimSpace(string(content)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %s", name, err)
+	}
+
+	return value, nil
+}
@@ -0,0 +1,42 @@
+package entropy
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestGather(t *testing.T) {
+	// The directories of testdata are captures of /proc/sys/kernel/random,
+	// the pool being of 256 bits since 5.18.
+	tests := []struct {
+		dir      string
+		expected map[string]interface{}
+		err      bool
+	}{
+		{"testdata/4.19", map[string]interface{}{"available": int64(3804), "pool_size": int64(4096)}, false},
+		{"testdata/5.18", map[string]interface{}{"available": int64(256), "pool_size": int64(256)}, false},
+		{"testdata/no-poolsize", map[string]interface{}{"available": int64(1873)}, false},
+		{"testdata/invalid", nil, true},
+		{"testdata/missing", nil, true},
+	}
+
+	for _, tt := range tests {
+		var acc testutil.Accumulator
+		err := newEntropy(tt.dir).Gather(&acc)
+		if tt.err {
+			if err == nil || len(acc.Errors) != 1 || len(acc.Metrics) != 0 {
+				t.Errorf("%s: error %v, errors %v, metrics %v", tt.dir, err, acc.Errors, acc.Metrics)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.dir, err)
+			continue
+		}
+		if len(acc.Metrics) != 1 || !reflect.DeepEqual(acc.Metrics[0].Fields, tt.expected) {
+			t.Errorf("%s: metrics %v, expected fields %v", tt.dir, acc.Metrics, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package file_nr
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const measurement = `file_nr`
+
+// FileNr reports the file handle and inode usage of the host.
+type FileNr struct {
+	procFS string
+}
+
+// init initializes the package.
+func init() {
+	inputs.Add("file_nr", func() telegraf.Input {
+		return newFileNr("/proc/sys/fs")
+	})
+}
+
+// newFileNr returns a pointer to a new FileNr object reading the files in
+// procFS.
+func newFileNr(procFS string) *FileNr {
+	return &FileNr{
+		procFS: procFS,
+	}
+}
+
+// Description returns a short description about the plugin.
+func (f *FileNr) Description() string {
+	return "Read the file handle and inode usage of the host."
+}
+
+// SampleConfig returns a sample configuration for the plugin.
+func (f *FileNr) SampleConfig() string {
+	return `
+	## This plugin has no options.
+	`
+}
+
+// Gather reads the file handle and inode counters and stores them in the
+// accumulator acc.
+func (f *FileNr) Gather(acc telegraf.Accumulator) error {
+	now := time.Now().UTC()
+
+	// file-nr holds the allocated, free and maximum file handles.
+	handles, err := f.readValues("file-nr", 3)
+	if err != nil {
+		acc.AddError(err)
+		return fmt.Errorf("file_nr: unable to gather metrics: %s", err)
+	}
+	fields := map[string]interface{}{
+		"allocated": handles[0],
+		"unused":    handles[1],
+		"max":       handles[2],
+	}
+	if handles[2] > 0 {
+		fields["used_percent"] = 100 * float64(handles[0]-handles[1]) / float64(handles[2])
+	}
+
+	// inode-nr holds the allocated and free inodes.
+	inodes, err := f.readValues("inode-nr", 2)
+	if err != nil && !os.IsNotExist(err) {
+		acc.AddError(err)
+		return fmt.Errorf("file_nr: unable to gather metrics: %s", err)
+	}
+	if err == nil {
+		fields["inodes"] = inodes[0]
+		fields["free_inodes"] = inodes[1]
+	}
+
+	acc.AddFields(measurement, fields, map[string]string{}, now)
+
+	return nil
+}
+
+// readValues reads the first count integers of the file name.
+func (f *FileNr) readValues(name string, count int) ([]int64, error) {
+	content, err := ioutil.ReadFile(f.procFS + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := strings.Fields(string(content))
+	if len(parts) < count {
+		return nil, fmt.Errorf("%s: expected %d values, got %d", name, count, len(parts))
+	}
+	values := make([]int64, count)
+	for i := range values {
+		values[i], err = strconv.ParseInt(parts[i], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", name, err)
+		}
+	}
+
+	return values, nil
+}
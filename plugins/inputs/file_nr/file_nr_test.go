@@ -0,0 +1,53 @@
+package file_nr
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestGather(t *testing.T) {
+	// The directories of testdata are captures of /proc/sys/fs, fs.file-max
+	// defaulting to LONG_MAX since 5.0.
+	tests := []struct {
+		dir      string
+		expected map[string]interface{}
+		err      bool
+	}{
+		{"testdata/3.10", map[string]interface{}{
+			"allocated": int64(2144), "unused": int64(0), "max": int64(1601184),
+			"used_percent": 100 * 2144 / float64(1601184),
+			"inodes":       int64(45233), "free_inodes": int64(7180),
+		}, false},
+		{"testdata/6.1", map[string]interface{}{
+			"allocated": int64(5664), "unused": int64(0), "max": int64(9223372036854775807),
+			"used_percent": 100 * 5664 / float64(9223372036854775807),
+			"inodes":       int64(183245), "free_inodes": int64(62011),
+		}, false},
+		{"testdata/no-inodes", map[string]interface{}{
+			"allocated": int64(1024), "unused": int64(0), "max": int64(65536),
+			"used_percent": 100 * 1024 / float64(65536),
+		}, false},
+		{"testdata/truncated", nil, true},
+		{"testdata/missing", nil, true},
+	}
+
+	for _, tt := range tests {
+		var acc testutil.Accumulator
+		err := newFileNr(tt.dir).Gather(&acc)
+		if tt.err {
+			if err == nil || len(acc.Errors) != 1 || len(acc.Metrics) != 0 {
+				t.Errorf("%s: error %v, errors %v, metrics %v", tt.dir, err, acc.Errors, acc.Metrics)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.dir, err)
+			continue
+		}
+		if len(acc.Metrics) != 1 || !reflect.DeepEqual(acc.Metrics[0].Fields, tt.expected) {
+			t.Errorf("%s: metrics %v, expected fields %v", tt.dir, acc.Metrics, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package interrupts
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const (
+	measurement     = `interrupts`
+	softMeasurement = `soft_interrupts`
+)
+
+// Interrupts reports the hardware and software interrupts serviced by each
+// CPU of the host.
+type Interrupts struct {
+	procRoot string
+	CPUAsTag bool `toml:"cpu_as_tag"`
+
+	previous map[string]int64
+}
+
+// interrupt is one row of /proc/interrupts or /proc/softirqs.
+type interrupt struct {
+	name   string
+	device string
+	kind   string
+	counts []int64
+}
+
+// init initializes the package.
+func init() {
+	inputs.Add("interrupts", func() telegraf.Input {
+		return newInterrupts("/proc")
+	})
+}
+
+// newInterrupts returns a pointer to a new Interrupts object reading the
+// files in procRoot.
+func newInterrupts(procRoot string) *Interrupts {
+	return &Interrupts{
+		procRoot: procRoot,
+		previous: make(map[string]int64),
+	}
+}
+
+// Description returns a short description about the plugin.
+func (i *Interrupts) Description() string {
+	return "Read the interrupts and soft interrupts serviced by each CPU."
+}
+
+// SampleConfig returns a sample configuration for the plugin.
+func (i *Interrupts) SampleConfig() string {
+	return `
+	## Report one metric per interrupt and CPU, with a cpu tag, instead of
+	## one metric per interrupt with a field per CPU.
+	#cpu_as_tag = false
+	`
+}
+
+// Gather reads the interrupt counters and stores them, along with their
+// change since the previous gather, in the accumulator acc.
+func (i *Interrupts) Gather(acc telegraf.Accumulator) error {
+	now := time.Now().UTC()
+	current := make(map[string]int64)
+
+	for file, name := range map[string]string{
+		"interrupts": measurement,
+		"softirqs":   softMeasurement,
+	} {
+		irqs, err := i.readInterrupts(file)
+		if err != nil {
+			acc.AddError(err)
+			return fmt.Errorf("interrupts: unable to gather metrics: %s", err)
+		}
+		for _, irq := range irqs {
+			i.addInterrupt(acc, name, irq, current, now)
+		}
+	}
+	i.previous = current
+
+	return nil
+}
+
+// addInterrupt adds the counters of irq to the accumulator acc and records
+// them in current, so that the next gather can compute their deltas.
+func (i *Interrupts) addInterrupt(acc telegraf.Accumulator, name string, irq interrupt, current map[string]int64, now time.Time) {
+	tags := map[string]string{"irq": irq.name}
+	if irq.kind != "" {
+		tags["type"] = irq.kind
+	}
+	if irq.device != "" {
+		tags["device"] = irq.device
+	}
+
+	var total int64
+	fields := make(map[string]interface{})
+	for cpu, count := range irq.counts {
+		total += count
+		key := fmt.Sprintf("%s/%s/%d", name, irq.name, cpu)
+		current[key] = count
+		previous, seen := i.previous[key]
+
+		if !i.CPUAsTag {
+			fields[fmt.Sprintf("CPU%d", cpu)] = count
+			if seen && count >= previous {
+				fields[fmt.Sprintf("CPU%d_delta", cpu)] = count - previous
+			}
+			continue
+		}
+
+		cpuTags := make(map[string]string, len(tags)+1)
+		for k, v := range tags {
+			cpuTags[k] = v
+		}
+		cpuTags["cpu"] = fmt.Sprintf("cpu%d", cpu)
+		cpuFields := map[string]interface{}{"count": count}
+		if seen && count >= previous {
+			cpuFields["delta"] = count - previous
+		}
+		acc.AddFields(name, cpuFields, cpuTags, now)
+	}
+
+	if i.CPUAsTag {
+		return
+	}
+	fields["total"] = total
+	key := fmt.Sprintf("%s/%s/total", name, irq.name)
+	current[key] = total
+	if previous, seen := i.previous[key]; seen && total >= previous {
+		fields["total_delta"] = total - previous
+	}
+	acc.AddFields(name, fields, tags, now)
+}
+
+// readInterrupts parses an interrupts file, whose header names the CPUs and
+// whose rows look like "  9:  0  4  IO-APIC  9-fasteoi  acpi", the trailing
+// type and device being absent from softirqs.
+func (i *Interrupts) readInterrupts(file string) ([]interrupt, error) {
+	f, err := os.Open(i.procRoot + "/" + file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	if !scanner.Scan() {
+		return nil, fmt.Errorf("%s: missing header", file)
+	}
+	cpus := len(strings.Fields(scanner.Text()))
+
+	var irqs []interrupt
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) < 2 || !strings.HasSuffix(parts[0], ":") {
+			continue
+		}
+		irq := interrupt{name: strings.TrimSuffix(parts[0], ":")}
+
+		// Rows such as ERR and MIS carry a single count, not one per CPU.
+		for _, part := range parts[1:] {
+			if len(irq.counts) == cpus {
+				break
+			}
+			count, err := strconv.ParseInt(part, 10, 64)
+			if err != nil {
+				break
+			}
+			irq.counts = append(irq.counts, count)
+		}
+		if len(irq.counts) == 0 {
+			continue
+		}
+
+		// Rows of named interrupts, such as "NMI: 0 0 Non-maskable
+		// interrupts", end with a description instead of the controller.
+		rest := parts[1+len(irq.counts):]
+		if _, err := strconv.Atoi(irq.name); err != nil {
+			irq.device = strings.Join(rest, " ")
+			irqs = append(irqs, irq)
+			continue
+		}
+		if len(rest) > 0 {
+			irq.kind = rest[0]
+		}
+		if len(rest) > 1 {
+			irq.device = strings.Join(rest[1:], " ")
+		}
+		irqs = append(irqs, irq)
+	}
+
+	return irqs, scanner.Err()
+}
@@ -0,0 +1,156 @@
+package interrupts
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestReadInterrupts(t *testing.T) {
+	// The files of testdata/first are captures of a two CPU x86 host.
+	tests := []struct {
+		file     string
+		expected []interrupt
+	}{
+		{"interrupts", []interrupt{
+			{name: "0", kind: "IO-APIC", device: "2-edge timer", counts: []int64{44, 0}},
+			{name: "1", kind: "IO-APIC", device: "1-edge i8042", counts: []int64{0, 9}},
+			{name: "8", kind: "IO-APIC", device: "8-edge rtc0", counts: []int64{0, 0}},
+			{name: "9", kind: "IO-APIC", device: "9-fasteoi acpi", counts: []int64{0, 4}},
+			{name: "24", kind: "PCI-MSI", device: "327680-edge xhci_hcd", counts: []int64{123456, 98765}},
+			{name: "25", kind: "PCI-MSI", device: "512000-edge ahci[0000:00:1f.2]", counts: []int64{0, 512034}},
+			{name: "26", kind: "PCI-MSI", device: "1572864-edge enp3s0-rx-0", counts: []int64{2345, 0}},
+			{name: "NMI", device: "Non-maskable interrupts", counts: []int64{12, 10}},
+			{name: "LOC", device: "Local timer interrupts", counts: []int64{8145623, 7934512}},
+			{name: "SPU", device: "Spurious interrupts", counts: []int64{0, 0}},
+			{name: "RES", device: "Rescheduling interrupts", counts: []int64{34567, 45678}},
+			{name: "TLB", device: "TLB shootdowns", counts: []int64{789, 654}},
+			{name: "ERR", counts: []int64{0}},
+			{name: "MIS", counts: []int64{0}},
+		}},
+		{"softirqs", []interrupt{
+			{name: "HI", counts: []int64{1, 0}},
+			{name: "TIMER", counts: []int64{150388, 140211}},
+			{name: "NET_TX", counts: []int64{2, 5}},
+			{name: "NET_RX", counts: []int64{31245, 29876}},
+			{name: "BLOCK", counts: []int64{4567, 3456}},
+			{name: "IRQ_POLL", counts: []int64{0, 0}},
+			{name: "TASKLET", counts: []int64{34, 12}},
+			{name: "SCHED", counts: []int64{101234, 99876}},
+			{name: "HRTIMER", counts: []int64{0, 0}},
+			{name: "RCU", counts: []int64{87654, 86543}},
+		}},
+	}
+
+	i := newInterrupts("testdata/first")
+	for _, tt := range tests {
+		irqs, err := i.readInterrupts(tt.file)
+		if err != nil {
+			t.Errorf("%s: %v", tt.file, err)
+			continue
+		}
+		if !reflect.DeepEqual(irqs, tt.expected) {
+			t.Errorf("%s:\n got %+v\nwant %+v", tt.file, irqs, tt.expected)
+		}
+	}
+
+	if _, err := newInterrupts("testdata/missing").readInterrupts("interrupts"); err == nil {
+		t.Error("missing file read")
+	}
+}
+
+func TestGather(t *testing.T) {
+	i := newInterrupts("testdata/first")
+	var acc testutil.Accumulator
+	if err := i.Gather(&acc); err != nil {
+		t.Fatal(err)
+	}
+
+	// The second capture is of a later gather, the counters of irq 26
+	// having been reset and irq 27 being new.
+	i.procRoot = "testdata/second"
+	acc.ClearMetrics()
+	if err := i.Gather(&acc); err != nil {
+		t.Fatal(err)
+	}
+
+	metrics := make(map[string]map[string]interface{})
+	for _, m := range acc.Metrics {
+		metrics[m.Measurement+"/"+m.Tags["irq"]] = m.Fields
+	}
+	expected := map[string]map[string]interface{}{
+		"interrupts/1": {
+			"CPU0": int64(0), "CPU1": int64(12), "total": int64(12),
+			"CPU0_delta": int64(0), "CPU1_delta": int64(3), "total_delta": int64(3),
+		},
+		"interrupts/26": {
+			"CPU0": int64(10), "CPU1": int64(0), "total": int64(10),
+			"CPU1_delta": int64(0),
+		},
+		"interrupts/27": {
+			"CPU0": int64(7), "CPU1": int64(3), "total": int64(10),
+		},
+		"interrupts/ERR": {
+			"CPU0": int64(0), "total": int64(0),
+			"CPU0_delta": int64(0), "total_delta": int64(0),
+		},
+		"soft_interrupts/NET_RX": {
+			"CPU0": int64(31500), "CPU1": int64(30000), "total": int64(61500),
+			"CPU0_delta": int64(255), "CPU1_delta": int64(124), "total_delta": int64(379),
+		},
+	}
+	for key, fields := range expected {
+		if !reflect.DeepEqual(metrics[key], fields) {
+			t.Errorf("%s:\n got %v\nwant %v", key, metrics[key], fields)
+		}
+	}
+	if len(metrics) != 25 {
+		t.Errorf("%d metrics, expected 25", len(metrics))
+	}
+}
+
+func TestGatherCPUAsTag(t *testing.T) {
+	i := newInterrupts("testdata/first")
+	i.CPUAsTag = true
+	var acc testutil.Accumulator
+	if err := i.Gather(&acc); err != nil {
+		t.Fatal(err)
+	}
+	i.procRoot = "testdata/second"
+	acc.ClearMetrics()
+	if err := i.Gather(&acc); err != nil {
+		t.Fatal(err)
+	}
+
+	metrics := make(map[string]map[string]interface{})
+	for _, m := range acc.Metrics {
+		metrics[m.Measurement+"/"+m.Tags["irq"]+"/"+m.Tags["cpu"]] = m.Fields
+		if m.Tags["irq"] == "24" && (m.Tags["type"] != "PCI-MSI" || m.Tags["device"] != "327680-edge xhci_hcd") {
+			t.Errorf("tags of irq 24 %v", m.Tags)
+		}
+	}
+	expected := map[string]map[string]interface{}{
+		"interrupts/24/cpu1":       {"count": int64(99000), "delta": int64(235)},
+		"interrupts/26/cpu0":       {"count": int64(10)},
+		"interrupts/LOC/cpu0":      {"count": int64(8146623), "delta": int64(1000)},
+		"interrupts/ERR/cpu0":      {"count": int64(0), "delta": int64(0)},
+		"soft_interrupts/RCU/cpu1": {"count": int64(86643), "delta": int64(100)},
+	}
+	for key, fields := range expected {
+		if !reflect.DeepEqual(metrics[key], fields) {
+			t.Errorf("%s:\n got %v\nwant %v", key, metrics[key], fields)
+		}
+	}
+	// One metric per CPU, but for ERR and MIS.
+	if len(metrics) != 48 {
+		t.Errorf("%d metrics, expected 48", len(metrics))
+	}
+}
+
+func TestGatherMissing(t *testing.T) {
+	var acc testutil.Accumulator
+	if err := newInterrupts("testdata/missing").Gather(&acc); err == nil {
+		t.Error("gather of a missing proc root succeeded")
+	}
+}
@@ -0,0 +1,164 @@
+//go:build darwin
+// +build darwin
+
+package launchd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const measurement = `launchd`
+
+// Launchd reports the state of the jobs loaded in launchd.
+type Launchd struct {
+	LaunchctlPath string `toml:"launchctl_path"`
+	Labels        []string
+	Timeout       internal.Duration
+
+	compiled    bool
+	labelFilter filter.Filter
+}
+
+// job is a job listed by launchctl list.
+type job struct {
+	label      string
+	pid        int64
+	running    bool
+	lastExit   int64
+	signal     int64
+	exitStatus bool
+}
+
+// init initializes the package.
+func init() {
+	inputs.Add("launchd", func() telegraf.Input {
+		return newLaunchd()
+	})
+}
+
+// newLaunchd returns a pointer to a new Launchd object.
+func newLaunchd() *Launchd {
+	return &Launchd{
+		LaunchctlPath: "/bin/launchctl",
+		Timeout:       internal.Duration{Duration: time.Second * 5},
+	}
+}
+
+// Description returns a short description about the plugin.
+func (l *Launchd) Description() string {
+	return "Read the state of the jobs loaded in launchd."
+}
+
+// SampleConfig returns a sample configuration for the plugin.
+func (l *Launchd) SampleConfig() string {
+	return `
+	## Path of the launchctl binary.
+	#launchctl_path = "/bin/launchctl"
+
+	## Labels of the jobs to report; glob patterns are supported. Every job
+	## is reported if empty.
+	#labels = ["com.example.*"]
+
+	## Timeout for launchctl to complete.
+	#timeout = "5s"
+	`
+}
+
+// Gather lists the jobs of launchd and stores their state in the
+// accumulator acc.
+func (l *Launchd) Gather(acc telegraf.Accumulator) error {
+	if !l.compiled {
+		var err error
+		l.labelFilter, err = filter.Compile(l.Labels)
+		if err != nil {
+			acc.AddError(err)
+			return fmt.Errorf("launchd: invalid labels: %s", err)
+		}
+		l.compiled = true
+	}
+
+	now := time.Now().UTC()
+	var out bytes.Buffer
+	cmd := exec.Command(l.LaunchctlPath, "list")
+	cmd.Stdout = &out
+	if err := internal.RunTimeout(cmd, l.Timeout.Duration); err != nil {
+		acc.AddError(err)
+		return fmt.Errorf("launchd: unable to gather metrics: %s", err)
+	}
+
+	jobs, err := parseList(out.Bytes())
+	if err != nil {
+		acc.AddError(err)
+		return fmt.Errorf("launchd: unable to gather metrics: %s", err)
+	}
+
+	for _, job := range jobs {
+		if l.labelFilter != nil && !l.labelFilter.Match(job.label) {
+			continue
+		}
+		fields := map[string]interface{}{
+			"running": job.running,
+			"pid":     job.pid,
+		}
+		if job.exitStatus {
+			fields["last_exit_status"] = job.lastExit
+			fields["last_exit_signal"] = job.signal
+		}
+		tags := map[string]string{"label": job.label}
+		acc.AddFields(measurement, fields, tags, now)
+	}
+
+	return nil
+}
+
+// parseList parses the output of launchctl list, whose lines look like
+// "PID	Status	Label", the pid being "-" for jobs not running and the
+// status being the exit status of the last run, negated when the job was
+// killed by a signal.
+func parseList(out []byte) ([]job, error) {
+	var jobs []job
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Scan() // header
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), "\t", 3)
+		if len(parts) != 3 {
+			continue
+		}
+
+		j := job{label: parts[2]}
+		if parts[0] != "-" {
+			pid, err := strconv.ParseInt(parts[0], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("job %s: %s", j.label, err)
+			}
+			j.pid = pid
+			j.running = true
+		}
+		if parts[1] != "-" {
+			status, err := strconv.ParseInt(parts[1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("job %s: %s", j.label, err)
+			}
+			j.exitStatus = true
+			if status < 0 {
+				j.signal = -status
+			} else {
+				j.lastExit = status
+			}
+		}
+		jobs = append(jobs, j)
+	}
+
+	return jobs, scanner.Err()
+}
@@ -0,0 +1,4 @@
+//go:build !darwin
+// +build !darwin
+
+package launchd
@@ -0,0 +1,43 @@
+//go:build darwin
+// +build darwin
+
+package launchd
+
+import (
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func TestParseList(t *testing.T) {
+	// testdata/launchctl-list is the output of launchctl list of macOS 12,
+	// the negative statuses being of jobs killed by a signal.
+	out, err := ioutil.ReadFile("testdata/launchctl-list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobs, err := parseList(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []job{
+		{label: "com.apple.SafariHistoryServiceAgent", exitStatus: true},
+		{label: "com.apple.Finder", pid: 412, running: true, exitStatus: true},
+		{label: "com.apple.bird", signal: 9, exitStatus: true},
+		{label: "com.example.worker", lastExit: 78, exitStatus: true},
+		{label: "com.example.web", pid: 1234, running: true, signal: 15, exitStatus: true},
+		{label: "com.example.reports"},
+	}
+	if !reflect.DeepEqual(jobs, expected) {
+		t.Errorf("\n got %+v\nwant %+v", jobs, expected)
+	}
+
+	out, err = ioutil.ReadFile("testdata/launchctl-list-invalid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parseList(out); err == nil {
+		t.Error("invalid pid accepted")
+	}
+}
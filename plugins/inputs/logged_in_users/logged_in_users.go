@@ -0,0 +1,187 @@
+//go:build linux
+// +build linux
+
+package logged_in_users
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const (
+	measurement        = `logged_in_users`
+	sessionMeasurement = `logged_in_users_session`
+
+	// userProcess is the utmp record type of a login session.
+	userProcess = 7
+)
+
+// utmpRecord is the layout of a utmp record written by glibc on Linux.
+type utmpRecord struct {
+	Type    int16
+	_       [2]byte
+	Pid     int32
+	Line    [32]byte
+	ID      [4]byte
+	User    [32]byte
+	Host    [256]byte
+	Exit    [2]int16
+	Session int32
+	Sec     int32
+	Usec    int32
+	AddrV6  [4]int32
+	_       [20]byte
+}
+
+// LoggedInUsers reports the login sessions recorded in utmp.
+type LoggedInUsers struct {
+	UtmpFile   string
+	PerSession bool
+}
+
+// init initializes the package.
+func init() {
+	inputs.Add("logged_in_users", func() telegraf.Input {
+		return newLoggedInUsers()
+	})
+}
+
+// newLoggedInUsers returns a pointer to a new LoggedInUsers object.
+func newLoggedInUsers() *LoggedInUsers {
+	return &LoggedInUsers{
+		UtmpFile:   "/var/run/utmp",
+		PerSession: true,
+	}
+}
+
+// Description returns a short description about the plugin.
+func (l *LoggedInUsers) Description() string {
+	return "Read the users logged in to the host from utmp."
+}
+
+// SampleConfig returns a sample configuration for the plugin.
+func (l *LoggedInUsers) SampleConfig() string {
+	return `
+	## utmp file listing the current login sessions.
+	#utmp_file = "/var/run/utmp"
+
+	## Report every session in addition to the totals.
+	#per_session = true
+	`
+}
+
+// Gather reads the login sessions and stores them in the accumulator acc.
+func (l *LoggedInUsers) Gather(acc telegraf.Accumulator) error {
+	sessions, err := readSessions(l.UtmpFile)
+	if err != nil {
+		acc.AddError(err)
+		return fmt.Errorf("logged_in_users: unable to gather metrics: %s", err)
+	}
+
+	now := time.Now()
+	users := make(map[string]bool)
+	var remote int
+	for _, session := range sessions {
+		user := cString(session.User[:])
+		host := cString(session.Host[:])
+		line := cString(session.Line[:])
+		users[user] = true
+		if host != "" {
+			remote++
+		}
+
+		if !l.PerSession {
+			continue
+		}
+		tags := map[string]string{
+			"user": user,
+			"line": line,
+			"host": host,
+		}
+		fields := map[string]interface{}{
+			"pid":        int(session.Pid),
+			"login_time": int64(session.Sec),
+		}
+		if idle, ok := idleTime(line, now); ok {
+			fields["idle_seconds"] = int64(idle.Seconds())
+		}
+		acc.AddFields(sessionMeasurement, fields, tags, now.UTC())
+	}
+
+	fields := map[string]interface{}{
+		"sessions":        len(sessions),
+		"users":           len(users),
+		"remote_sessions": remote,
+	}
+	acc.AddFields(measurement, fields, map[string]string{}, now.UTC())
+	return nil
+}
+
+// readSessions returns the login session records of the utmp file whose
+// process is still alive.
+func readSessions(file string) ([]utmpRecord, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var sessions []utmpRecord
+	for {
+		var record utmpRecord
+		err := binary.Read(f, binary.LittleEndian, &record)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if record.Type != userProcess || !alive(int(record.Pid)) {
+			continue
+		}
+		sessions = append(sessions, record)
+	}
+	return sessions, nil
+}
+
+// alive reports whether process pid exists, as stale records are left
+// behind by sessions that ended abnormally.
+func alive(pid int) bool {
+	_, err := os.Stat("/proc/" + strconv.Itoa(pid))
+	return err == nil
+}
+
+// idleTime returns the time since the terminal line was last used, as
+// reported by w.
+func idleTime(line string, now time.Time) (time.Duration, bool) {
+	info, err := os.Stat("/dev/" + line)
+	if err != nil {
+		return 0, false
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	atime := time.Unix(int64(stat.Atim.Sec), int64(stat.Atim.Nsec))
+	if atime.After(now) {
+		return 0, true
+	}
+	return now.Sub(atime), true
+}
+
+// cString returns the NUL terminated string held in b.
+func cString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
@@ -0,0 +1,114 @@
+//go:build linux
+// +build linux
+
+package logged_in_users
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+)
+
+// testdata/utmp holds the records of a host booted with a getty on tty1,
+// alice logged in on tty2 and over ssh, bob over ssh, a stale record of
+// carol whose pid cannot exist and a dead process. The sessions alive are
+// those of pid 1, which always exists.
+
+func TestReadSessions(t *testing.T) {
+	tests := []struct {
+		file     string
+		expected []string
+	}{
+		{"testdata/utmp", []string{"alice tty2", "alice pts/0", "bob pts/1"}},
+		// A record being written is left to the next gather.
+		{"testdata/utmp-truncated", []string{"alice tty2"}},
+	}
+
+	for _, tt := range tests {
+		sessions, err := readSessions(tt.file)
+		if err != nil {
+			t.Errorf("%s: %v", tt.file, err)
+			continue
+		}
+		got := []string{}
+		for _, session := range sessions {
+			got = append(got, cString(session.User[:])+" "+cString(session.Line[:]))
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("%s: sessions %v, expected %v", tt.file, got, tt.expected)
+		}
+	}
+
+	if _, err := readSessions("testdata/missing"); err == nil {
+		t.Error("missing file read")
+	}
+}
+
+func TestGather(t *testing.T) {
+	l := newLoggedInUsers()
+	l.UtmpFile = "testdata/utmp"
+	var acc testutil.Accumulator
+	if err := l.Gather(&acc); err != nil {
+		t.Fatal(err)
+	}
+
+	type metric struct {
+		Measurement string
+		Tags        map[string]string
+		Fields      map[string]interface{}
+	}
+	expected := []metric{
+		{
+			sessionMeasurement,
+			map[string]string{"user": "alice", "line": "tty2", "host": ""},
+			map[string]interface{}{"pid": 1, "login_time": int64(1634281500)},
+		},
+		{
+			sessionMeasurement,
+			map[string]string{"user": "alice", "line": "pts/0", "host": "192.0.2.10"},
+			map[string]interface{}{"pid": 1, "login_time": int64(1634284000)},
+		},
+		{
+			sessionMeasurement,
+			map[string]string{"user": "bob", "line": "pts/1", "host": "bastion.example.com"},
+			map[string]interface{}{"pid": 1, "login_time": int64(1634284500)},
+		},
+		{
+			measurement,
+			map[string]string{},
+			map[string]interface{}{"sessions": 3, "users": 2, "remote_sessions": 2},
+		},
+	}
+
+	// The idle time depends on the terminals of the host running the test.
+	var got []metric
+	for _, m := range acc.Metrics {
+		delete(m.Fields, "idle_seconds")
+		got = append(got, metric{m.Measurement, m.Tags, m.Fields})
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("\n got %+v\nwant %+v", got, expected)
+	}
+
+	l.PerSession = false
+	acc.ClearMetrics()
+	if err := l.Gather(&acc); err != nil {
+		t.Fatal(err)
+	}
+	if len(acc.Metrics) != 1 || acc.Metrics[0].Measurement != measurement {
+		t.Errorf("metrics %v without per_session", acc.Metrics)
+	}
+}
+
+func TestCString(t *testing.T) {
+	for in, expected := range map[string]string{
+		"pts/0\x00\x00\x00": "pts/0",
+		"full":              "full",
+		"\x00junk":          "",
+	} {
+		if got := cString([]byte(in)); got != expected {
+			t.Errorf("%q: %q, expected %q", in, got, expected)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package lsof
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gpapag/telegraf-plugins/pkg/psinfo"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const (
+	measurement        = `lsof`
+	processMeasurement = `lsof_process`
+)
+
+// File types counted by the plugin.
+const (
+	typeRegular = `regular`
+	typeSocket  = `socket`
+	typePipe    = `pipe`
+	typeDevice  = `device`
+	typeOther   = `other`
+)
+
+// Lsof counts the files opened by the processes running on the host, by
+// type of file.
+type Lsof struct {
+	procFS     psinfo.ProcFS
+	PerProcess bool
+}
+
+// init initializes the package.
+func init() {
+	inputs.Add("lsof", func() telegraf.Input {
+		return newLsof()
+	})
+}
+
+// newLsof returns a pointer to a new Lsof object.
+func newLsof() *Lsof {
+	return &Lsof{
+		procFS:     psinfo.DefaultProcFS,
+		PerProcess: true,
+	}
+}
+
+// Description returns a short description about the plugin.
+func (l *Lsof) Description() string {
+	return "Count the files opened by the processes running on the host."
+}
+
+// SampleConfig returns a sample configuration for the plugin.
+func (l *Lsof) SampleConfig() string {
+	return `
+	## Report the counts of every process in addition to the host totals.
+	#per_process = true
+	`
+}
+
+// Gather counts the open files of every process and stores the counts in
+// the accumulator acc.
+func (l *Lsof) Gather(acc telegraf.Accumulator) error {
+	pids, err := l.procFS.Pids()
+	if err != nil {
+		acc.AddError(err)
+		return fmt.Errorf("lsof: unable to gather metrics: %s", err)
+	}
+
+	now := time.Now().UTC()
+	totals := newCounts()
+	var unreadable int
+	for _, pid := range pids {
+		targets, err := l.procFS.ReadFDTargets(pid)
+		if err != nil {
+			unreadable++
+			continue
+		}
+
+		counts := newCounts()
+		for _, target := range targets {
+			counts[classify(target)]++
+		}
+		for fileType, count := range counts {
+			totals[fileType] += count
+		}
+
+		if l.PerProcess {
+			comm, _ := l.procFS.ReadComm(pid)
+			tags := map[string]string{
+				"pid":  strconv.Itoa(pid),
+				"comm": comm,
+			}
+			acc.AddFields(processMeasurement, counts.fields(), tags, now)
+		}
+	}
+
+	fields := totals.fields()
+	fields["processes"] = len(pids)
+	fields["unreadable_processes"] = unreadable
+	acc.AddFields(measurement, fields, map[string]string{}, now)
+	return nil
+}
+
+// counts holds the number of open files by type.
+type counts map[string]int
+
+// newCounts returns counts with every file type set to zero.
+func newCounts() counts {
+	return counts{
+		typeRegular: 0,
+		typeSocket:  0,
+		typePipe:    0,
+		typeDevice:  0,
+		typeOther:   0,
+	}
+}
+
+// fields returns c as metric fields, along with their total.
+func (c counts) fields() map[string]interface{} {
+	fields := make(map[string]interface{}, len(c)+1)
+	var total int
+	for fileType, count := range c {
+		fields[fileType] = count
+		total += count
+	}
+	fields["total"] = total
+	return fields
+}
+
+// classify returns the type of the file a descriptor link target refers to.
+func classify(target string) string {
+	switch {
+	case strings.HasPrefix(target, "socket:"):
+		return typeSocket
+	case strings.HasPrefix(target, "pipe:"):
+		return typePipe
+	case strings.HasPrefix(target, "/dev/"):
+		return typeDevice
+	case strings.HasPrefix(target, "/"):
+		return typeRegular
+	default:
+		return typeOther
+	}
+}
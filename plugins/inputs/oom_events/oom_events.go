@@ -0,0 +1,194 @@
+//go:build linux
+// +build linux
+
+package oom_events
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const measurement = `oom_events`
+
+// Sources of the kernel messages.
+const (
+	sourceKmsg    = `kmsg`
+	sourceJournal = `journal`
+)
+
+// maxRecordSize is the size of the buffer each record of /dev/kmsg is read
+// into; reads fail with EINVAL when a record does not fit.
+const maxRecordSize = 8192
+
+// OOMEvents reports the processes killed by the OOM killer and the tasks
+// reported hung by the kernel, as it logs them.
+type OOMEvents struct {
+	Source         string
+	KmsgPath       string `toml:"kmsg_path"`
+	JournalctlPath string `toml:"journalctl_path"`
+	HungTasks      bool
+
+	acc    telegraf.Accumulator
+	parser parser
+	reader io.ReadCloser
+	cmd    *exec.Cmd
+	wg     sync.WaitGroup
+}
+
+// init initializes the package.
+func init() {
+	inputs.Add("oom_events", func() telegraf.Input {
+		return newOOMEvents()
+	})
+}
+
+// newOOMEvents returns a pointer to a new OOMEvents object.
+func newOOMEvents() *OOMEvents {
+	return &OOMEvents{
+		Source:         sourceKmsg,
+		KmsgPath:       "/dev/kmsg",
+		JournalctlPath: "/usr/bin/journalctl",
+		HungTasks:      true,
+	}
+}
+
+// Description returns a short description about the plugin.
+func (o *OOMEvents) Description() string {
+	return "Report the OOM kills and hung tasks logged by the kernel."
+}
+
+// SampleConfig returns a sample configuration for the plugin.
+func (o *OOMEvents) SampleConfig() string {
+	return `
+	## Where to read the kernel messages from: "kmsg" to read the kernel log
+	## buffer, or "journal" to follow the kernel messages of the journal.
+	#source = "kmsg"
+
+	## Path of the kernel log buffer, with source = "kmsg".
+	#kmsg_path = "/dev/kmsg"
+
+	## Path of the journalctl binary, with source = "journal".
+	#journalctl_path = "/usr/bin/journalctl"
+
+	## Report the tasks the kernel found blocked in uninterruptible sleep.
+	#hung_tasks = true
+	`
+}
+
+// Gather does nothing, the events being stored in the accumulator as they
+// are logged.
+func (o *OOMEvents) Gather(acc telegraf.Accumulator) error {
+	return nil
+}
+
+// Start starts reading the kernel messages logged from now on, and stores
+// an event in the accumulator acc for each OOM kill or hung task.
+func (o *OOMEvents) Start(acc telegraf.Accumulator) error {
+	o.acc = acc
+
+	switch o.Source {
+	case sourceKmsg:
+		f, err := os.Open(o.KmsgPath)
+		if err != nil {
+			return fmt.Errorf("oom_events: unable to open %s: %s", o.KmsgPath, err)
+		}
+		// Skip the messages logged before Telegraf started.
+		if _, err := f.Seek(0, io.SeekEnd); err != nil {
+			f.Close()
+			return fmt.Errorf("oom_events: unable to seek %s: %s", o.KmsgPath, err)
+		}
+		o.reader = f
+		o.wg.Add(1)
+		go o.readKmsg(f)
+	case sourceJournal:
+		o.cmd = exec.Command(o.JournalctlPath, "--dmesg", "--follow", "--lines=0", "--output=cat")
+		stdout, err := o.cmd.StdoutPipe()
+		if err != nil {
+			return fmt.Errorf("oom_events: unable to run journalctl: %s", err)
+		}
+		if err := o.cmd.Start(); err != nil {
+			return fmt.Errorf("oom_events: unable to run journalctl: %s", err)
+		}
+		o.reader = stdout
+		o.wg.Add(1)
+		go o.readLines(stdout)
+	default:
+		return fmt.Errorf("oom_events: unknown source %q", o.Source)
+	}
+
+	return nil
+}
+
+// Stop stops reading the kernel messages.
+func (o *OOMEvents) Stop() {
+	if o.cmd != nil && o.cmd.Process != nil {
+		o.cmd.Process.Kill()
+	}
+	if o.reader != nil {
+		o.reader.Close()
+	}
+	o.wg.Wait()
+	if o.cmd != nil {
+		o.cmd.Wait()
+	}
+}
+
+// readKmsg reads the records of /dev/kmsg, one per read, which look like
+// "6,1234,5678901,-;message" followed by continuation lines.
+func (o *OOMEvents) readKmsg(f *os.File) {
+	defer o.wg.Done()
+
+	buf := make([]byte, maxRecordSize)
+	for {
+		n, err := f.Read(buf)
+		if err != nil {
+			// EPIPE tells that records were overwritten before being read.
+			if errors.Is(err, syscall.EPIPE) {
+				continue
+			}
+			return
+		}
+
+		record := string(buf[:n])
+		if i := strings.IndexByte(record, ';'); i >= 0 {
+			record = record[i+1:]
+		}
+		if i := strings.IndexByte(record, '\n'); i >= 0 {
+			record = record[:i]
+		}
+		o.handle(record)
+	}
+}
+
+// readLines reads the kernel messages written by journalctl, one per line.
+func (o *OOMEvents) readLines(r io.Reader) {
+	defer o.wg.Done()
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		o.handle(scanner.Text())
+	}
+}
+
+// handle stores the event described by message, if any, in the
+// accumulator.
+func (o *OOMEvents) handle(message string) {
+	e, ok := o.parser.parse(message)
+	if !ok || (e.name == eventHungTask && !o.HungTasks) {
+		return
+	}
+
+	e.tags["event"] = e.name
+	o.acc.AddFields(measurement, e.fields, e.tags, time.Now().UTC())
+}
@@ -0,0 +1,114 @@
+package oom_events
+
+import (
+	"regexp"
+	"strconv"
+)
+
+// Events reported by the plugin.
+const (
+	eventOOMKill  = `oom_kill`
+	eventHungTask = `hung_task`
+)
+
+var (
+	// killedRe matches the message of the OOM killer, such as "Out of
+	// memory: Killed process 1234 (java) total-vm:123kB, anon-rss:45kB,
+	// file-rss:6kB, shmem-rss:0kB, UID:1000 pgtables:8kB oom_score_adj:0",
+	// prefixed with "Memory cgroup out of memory: " when the limit of a
+	// cgroup was hit.
+	killedRe = regexp.MustCompile(`(?:(Memory cgroup out of memory|Out of memory).*: )?Killed process (\d+) \((.*?)\) total-vm:(\d+)kB, anon-rss:(\d+)kB, file-rss:(\d+)kB(?:, shmem-rss:(\d+)kB)?(?:,? UID:(\d+))?(?:.*oom_score_adj:(-?\d+))?`)
+
+	// killRe matches the message announcing the victim of the OOM killer
+	// before Linux 5.1, such as "Memory cgroup out of memory: Kill process
+	// 1234 (java) score 1000 or sacrifice child", which the message of the
+	// kill then follows without telling the scope.
+	killRe = regexp.MustCompile(`(Memory cgroup out of memory|Out of memory): Kill process (\d+) `)
+
+	// hungRe matches the message of the hung task detector, such as "INFO:
+	// task nfsd:1234 blocked for more than 120 seconds.".
+	hungRe = regexp.MustCompile(`task (.+):(\d+) blocked for more than (\d+) seconds`)
+)
+
+// event is an event parsed from a kernel message.
+type event struct {
+	name   string
+	tags   map[string]string
+	fields map[string]interface{}
+}
+
+// parser parses the kernel messages, remembering the scope announced for
+// the next kill.
+type parser struct {
+	killPid   string
+	killScope string
+}
+
+// scopes maps the prefixes of the messages of the OOM killer to the scope
+// of the kill.
+var scopes = map[string]string{
+	"Out of memory":               "system",
+	"Memory cgroup out of memory": "cgroup",
+}
+
+// parse returns the event described by the kernel message, if any.
+func (p *parser) parse(message string) (event, bool) {
+	if m := killRe.FindStringSubmatch(message); m != nil {
+		p.killPid, p.killScope = m[2], scopes[m[1]]
+		return event{}, false
+	}
+
+	if m := killedRe.FindStringSubmatch(message); m != nil {
+		scope := scopes[m[1]]
+		if scope == "" {
+			scope = "system"
+			if m[2] == p.killPid {
+				scope = p.killScope
+			}
+		}
+		p.killPid, p.killScope = "", ""
+
+		e := event{
+			name: eventOOMKill,
+			tags: map[string]string{
+				"comm":  m[3],
+				"scope": scope,
+			},
+			fields: map[string]interface{}{
+				"pid":          atoi(m[2]),
+				"total_vm_kb":  atoi(m[4]),
+				"anon_rss_kb":  atoi(m[5]),
+				"file_rss_kb":  atoi(m[6]),
+				"shmem_rss_kb": atoi(m[7]),
+			},
+		}
+		if m[8] != "" {
+			e.fields["uid"] = atoi(m[8])
+		}
+		if m[9] != "" {
+			e.fields["oom_score_adj"] = atoi(m[9])
+		}
+		return e, true
+	}
+
+	if m := hungRe.FindStringSubmatch(message); m != nil {
+		return event{
+			name: eventHungTask,
+			tags: map[string]string{
+				"comm": m[1],
+			},
+			fields: map[string]interface{}{
+				"pid":             atoi(m[2]),
+				"blocked_seconds": atoi(m[3]),
+			},
+		}, true
+	}
+
+	return event{}, false
+}
+
+// atoi returns s as an integer, 0 if empty.
+func atoi(s string) int64 {
+	n, _ := strconv.ParseInt(s, 10, 64)
+	return n
+}
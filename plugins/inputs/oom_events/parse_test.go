@@ -0,0 +1,178 @@
+package oom_events
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testMessages are kernel messages as logged by several kernel versions,
+// in the order they log them, and the events they describe.
+var testMessages = []struct {
+	name     string
+	messages []string
+	expected []event
+}{
+	{
+		// Before shmem-rss was added, the victim being announced first.
+		name: "3.10",
+		messages: []string{
+			"[1234567.890123] Out of memory: Kill process 2592 (java) score 914 or sacrifice child",
+			"[1234567.891234] Killed process 2592 (java) total-vm:4505716kB, anon-rss:3574388kB, file-rss:0kB",
+		},
+		expected: []event{{
+			name: eventOOMKill,
+			tags: map[string]string{"comm": "java", "scope": "system"},
+			fields: map[string]interface{}{
+				"pid": int64(2592), "total_vm_kb": int64(4505716), "anon_rss_kb": int64(3574388),
+				"file_rss_kb": int64(0), "shmem_rss_kb": int64(0),
+			},
+		}},
+	},
+	{
+		// The scope only told by the announcement, followed by the report
+		// of the oom reaper.
+		name: "4.14 cgroup",
+		messages: []string{
+			"Memory cgroup out of memory: Kill process 31337 (node) score 1974 or sacrifice child",
+			"Killed process 31337 (node) total-vm:1386292kB, anon-rss:519564kB, file-rss:26696kB, shmem-rss:0kB",
+			"oom_reaper: reaped process 31337 (node), now anon-rss:0kB, file-rss:0kB, shmem-rss:0kB",
+		},
+		expected: []event{{
+			name: eventOOMKill,
+			tags: map[string]string{"comm": "node", "scope": "cgroup"},
+			fields: map[string]interface{}{
+				"pid": int64(31337), "total_vm_kb": int64(1386292), "anon_rss_kb": int64(519564),
+				"file_rss_kb": int64(26696), "shmem_rss_kb": int64(0),
+			},
+		}},
+	},
+	{
+		// An announcement of another process does not scope the kill.
+		name: "4.14 stale announcement",
+		messages: []string{
+			"Memory cgroup out of memory: Kill process 100 (a) score 1000 or sacrifice child",
+			"Killed process 200 (b) total-vm:10kB, anon-rss:5kB, file-rss:1kB, shmem-rss:2kB",
+		},
+		expected: []event{{
+			name: eventOOMKill,
+			tags: map[string]string{"comm": "b", "scope": "system"},
+			fields: map[string]interface{}{
+				"pid": int64(200), "total_vm_kb": int64(10), "anon_rss_kb": int64(5),
+				"file_rss_kb": int64(1), "shmem_rss_kb": int64(2),
+			},
+		}},
+	},
+	{
+		name: "5.4",
+		messages: []string{
+			"oom-kill:constraint=CONSTRAINT_NONE,nodemask=(null),cpuset=/,mems_allowed=0,global_oom,task_memcg=/user.slice/user-1000.slice/session-3.scope,task=stress,pid=1822,uid=1000",
+			"Out of memory: Killed process 1822 (stress) total-vm:2106744kB, anon-rss:1993836kB, file-rss:4kB, shmem-rss:0kB, UID:1000 pgtables:3956kB oom_score_adj:0",
+		},
+		expected: []event{{
+			name: eventOOMKill,
+			tags: map[string]string{"comm": "stress", "scope": "system"},
+			fields: map[string]interface{}{
+				"pid": int64(1822), "total_vm_kb": int64(2106744), "anon_rss_kb": int64(1993836),
+				"file_rss_kb": int64(4), "shmem_rss_kb": int64(0), "uid": int64(1000), "oom_score_adj": int64(0),
+			},
+		}},
+	},
+	{
+		name: "5.15 cgroup",
+		messages: []string{
+			"Memory cgroup out of memory: Killed process 40112 (python3) total-vm:1593036kB, anon-rss:1045892kB, file-rss:5312kB, shmem-rss:0kB, UID:0 pgtables:2420kB oom_score_adj:-998",
+		},
+		expected: []event{{
+			name: eventOOMKill,
+			tags: map[string]string{"comm": "python3", "scope": "cgroup"},
+			fields: map[string]interface{}{
+				"pid": int64(40112), "total_vm_kb": int64(1593036), "anon_rss_kb": int64(1045892),
+				"file_rss_kb": int64(5312), "shmem_rss_kb": int64(0), "uid": int64(0), "oom_score_adj": int64(-998),
+			},
+		}},
+	},
+	{
+		// With the vm.oom_kill_allocating_task sysctl, and a command name
+		// holding spaces and parentheses.
+		name: "6.1 allocating task",
+		messages: []string{
+			"Out of memory (oom_kill_allocating_task): Killed process 777 ((sd-pam) x) total-vm:104kB, anon-rss:8kB, file-rss:0kB, shmem-rss:0kB, UID:0 pgtables:44kB oom_score_adj:100",
+		},
+		expected: []event{{
+			name: eventOOMKill,
+			tags: map[string]string{"comm": "(sd-pam) x", "scope": "system"},
+			fields: map[string]interface{}{
+				"pid": int64(777), "total_vm_kb": int64(104), "anon_rss_kb": int64(8),
+				"file_rss_kb": int64(0), "shmem_rss_kb": int64(0), "uid": int64(0), "oom_score_adj": int64(100),
+			},
+		}},
+	},
+	{
+		name: "hung task",
+		messages: []string{
+			"INFO: task nfsd:1234 blocked for more than 120 seconds.",
+			"      Not tainted 5.15.0-91-generic #101-Ubuntu",
+			`"echo 0 > /proc/sys/kernel/hung_task_timeout_secs" disables this message.`,
+			"INFO: task kworker/u16:3:8123 blocked for more than 122 seconds.",
+		},
+		expected: []event{
+			{
+				name:   eventHungTask,
+				tags:   map[string]string{"comm": "nfsd"},
+				fields: map[string]interface{}{"pid": int64(1234), "blocked_seconds": int64(120)},
+			},
+			{
+				name:   eventHungTask,
+				tags:   map[string]string{"comm": "kworker/u16:3"},
+				fields: map[string]interface{}{"pid": int64(8123), "blocked_seconds": int64(122)},
+			},
+		},
+	},
+}
+
+func TestParse(t *testing.T) {
+	for _, tt := range testMessages {
+		var p parser
+		var events []event
+		for _, message := range tt.messages {
+			if e, ok := p.parse(message); ok {
+				events = append(events, e)
+			}
+		}
+		if !reflect.DeepEqual(events, tt.expected) {
+			t.Errorf("%s:\n got %+v\nwant %+v", tt.name, events, tt.expected)
+		}
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	for _, tt := range testMessages {
+		f.Add(strings.Join(tt.messages, "\n"))
+		for _, message := range tt.messages {
+			f.Add(message)
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, in string) {
+		var p parser
+		for _, message := range strings.Split(in, "\n") {
+			e, ok := p.parse(message)
+			if !ok {
+				continue
+			}
+			if e.name != eventOOMKill && e.name != eventHungTask {
+				t.Errorf("event %q of %q", e.name, message)
+			}
+			if !strings.Contains(message, e.tags["comm"]) {
+				t.Errorf("comm %q not in %q", e.tags["comm"], message)
+			}
+			if e.name == eventOOMKill && e.tags["scope"] != "system" && e.tags["scope"] != "cgroup" {
+				t.Errorf("scope %q of %q", e.tags["scope"], message)
+			}
+			if _, ok := e.fields["pid"].(int64); !ok {
+				t.Errorf("pid %#v of %q", e.fields["pid"], message)
+			}
+		}
+	})
+}
@@ -0,0 +1,113 @@
+package pressure
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const measurement = `pressure`
+
+// Pressure reports the pressure stall information of the host.
+type Pressure struct {
+	procPressure string
+	Resources    []string
+}
+
+// init initializes the package.
+func init() {
+	inputs.Add("pressure", func() telegraf.Input {
+		return newPressure("/proc/pressure")
+	})
+}
+
+// newPressure returns a pointer to a new Pressure object reading the files
+// in procPressure.
+func newPressure(procPressure string) *Pressure {
+	return &Pressure{
+		procPressure: procPressure,
+		Resources:    []string{"cpu", "memory", "io"},
+	}
+}
+
+// Description returns a short description about the plugin.
+func (p *Pressure) Description() string {
+	return "Read the pressure stall information of the cpu, memory and io."
+}
+
+// SampleConfig returns a sample configuration for the plugin.
+func (p *Pressure) SampleConfig() string {
+	return `
+	## Resources to report, among the files of /proc/pressure.
+	#resources = ["cpu", "memory", "io"]
+	`
+}
+
+// Gather reads the stall information of every resource and stores it in
+// the accumulator acc.
+func (p *Pressure) Gather(acc telegraf.Accumulator) error {
+	now := time.Now().UTC()
+	for _, resource := range p.Resources {
+		if err := p.gatherResource(acc, resource, now); err != nil {
+			if os.IsNotExist(err) {
+				err = fmt.Errorf("%s, the kernel must be 4.20 or later with CONFIG_PSI", err)
+			}
+			acc.AddError(fmt.Errorf("pressure: unable to gather metrics: %s", err))
+		}
+	}
+	return nil
+}
+
+// gatherResource parses a pressure file, whose lines look like
+// "some avg10=0.12 avg60=0.05 avg300=0.01 total=123456".
+func (p *Pressure) gatherResource(acc telegraf.Accumulator, resource string, now time.Time) error {
+	file, err := os.Open(filepath.Join(p.procPressure, resource))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) < 2 {
+			continue
+		}
+
+		fields := make(map[string]interface{})
+		for _, part := range parts[1:] {
+			keyValue := strings.SplitN(part, "=", 2)
+			if len(keyValue) != 2 {
+				continue
+			}
+			if keyValue[0] == "total" {
+				total, err := strconv.ParseInt(keyValue[1], 10, 64)
+				if err != nil {
+					return fmt.Errorf("%s: %s", resource, err)
+				}
+				fields["total"] = total
+				continue
+			}
+			avg, err := strconv.ParseFloat(keyValue[1], 64)
+			if err != nil {
+				return fmt.Errorf("%s: %s", resource, err)
+			}
+			fields[keyValue[0]] = avg
+		}
+
+		tags := map[string]string{
+			"resource": resource,
+			"type":     parts[0],
+		}
+		acc.AddFields(measurement, fields, tags, now)
+	}
+
+	return scanner.Err()
+}
@@ -0,0 +1,94 @@
+package pressure
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+// stall returns a metric of the stall information of the resource.
+func stall(resource, kind string, avg10, avg60, avg300 float64, total int64) telegraf.Metric {
+	return testutil.MustMetric(measurement,
+		map[string]string{"resource": resource, "type": kind},
+		map[string]interface{}{"avg10": avg10, "avg60": avg60, "avg300": avg300, "total": total},
+		time.Unix(0, 0))
+}
+
+func TestGather(t *testing.T) {
+	// The directories of testdata are captures of /proc/pressure, the
+	// kernels before 5.13 lacking the full line of cpu.
+	tests := []struct {
+		name      string
+		dir       string
+		resources []string
+		expected  []telegraf.Metric
+		errors    []string
+	}{
+		{
+			name: "5.4",
+			dir:  "testdata/5.4",
+			expected: []telegraf.Metric{
+				stall("cpu", "some", 2.39, 1.88, 1.32, 112560975),
+				stall("memory", "some", 0, 0.01, 0, 5579847),
+				stall("memory", "full", 0, 0.01, 0, 4548901),
+				stall("io", "some", 0.52, 0.31, 0.12, 38112347),
+				stall("io", "full", 0.41, 0.25, 0.10, 30117289),
+			},
+		},
+		{
+			name:      "6.1 with irq",
+			dir:       "testdata/6.1",
+			resources: []string{"cpu", "irq"},
+			expected: []telegraf.Metric{
+				stall("cpu", "some", 12.5, 8.73, 3.04, 987654321),
+				stall("cpu", "full", 0, 0, 0, 0),
+				stall("irq", "full", 0.03, 0.02, 0.01, 1234567),
+			},
+		},
+		{
+			// A missing resource does not keep the others from being
+			// reported.
+			name:      "missing resource",
+			dir:       "testdata/5.4",
+			resources: []string{"irq", "cpu"},
+			expected: []telegraf.Metric{
+				stall("cpu", "some", 2.39, 1.88, 1.32, 112560975),
+			},
+			errors: []string{"the kernel must be 4.20 or later with CONFIG_PSI"},
+		},
+		{
+			name:      "invalid",
+			dir:       "testdata/invalid",
+			resources: []string{"cpu", "memory"},
+			expected:  []telegraf.Metric{},
+			errors:    []string{`cpu: strconv.ParseFloat: parsing "abc"`, `memory: strconv.ParseInt: parsing "-"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newPressure(tt.dir)
+			if tt.resources != nil {
+				p.Resources = tt.resources
+			}
+
+			var acc testutil.Accumulator
+			if err := p.Gather(&acc); err != nil {
+				t.Fatal(err)
+			}
+			testutil.RequireMetricsEqual(t, tt.expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+
+			if len(acc.Errors) != len(tt.errors) {
+				t.Fatalf("errors %v, expected %d", acc.Errors, len(tt.errors))
+			}
+			for i, err := range acc.Errors {
+				if !strings.Contains(err.Error(), tt.errors[i]) {
+					t.Errorf("error %q, expected %q", err, tt.errors[i])
+				}
+			}
+		})
+	}
+}
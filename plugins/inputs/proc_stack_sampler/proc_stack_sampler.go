@@ -0,0 +1,234 @@
+package proc_stack_sampler
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gpapag/telegraf-plugins/pkg/psinfo"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const measurement = `proc_stack`
+
+// ProcStackSampler samples the kernel stacks of selected processes and
+// reports the most frequent ones.
+type ProcStackSampler struct {
+	Comms          []string
+	States         []string
+	Samples        int
+	SampleInterval internal.Duration
+	Top            int
+	MaxDepth       int
+
+	procFS     psinfo.ProcFS
+	sleep      func(time.Duration)
+	compiled   bool
+	commFilter filter.Filter
+}
+
+// stackCount is the number of samples a stack was seen in.
+type stackCount struct {
+	frames []string
+	count  int
+}
+
+// init initializes the package.
+func init() {
+	inputs.Add("proc_stack_sampler", func() telegraf.Input {
+		return newProcStackSampler()
+	})
+}
+
+// newProcStackSampler returns a pointer to a new ProcStackSampler object.
+func newProcStackSampler() *ProcStackSampler {
+	return &ProcStackSampler{
+		States:         []string{"D"},
+		Samples:        10,
+		SampleInterval: internal.Duration{Duration: time.Millisecond * 50},
+		Top:            3,
+		MaxDepth:       16,
+		procFS:         psinfo.DefaultProcFS,
+		sleep:          time.Sleep,
+	}
+}
+
+// Description returns a short description about the plugin.
+func (s *ProcStackSampler) Description() string {
+	return "Sample the kernel stacks of selected processes and report the most frequent ones."
+}
+
+// SampleConfig returns a sample configuration for the plugin.
+func (s *ProcStackSampler) SampleConfig() string {
+	return `
+	## Command names of the processes to sample; glob patterns are
+	## supported. Every process is considered if empty.
+	#comms = ["nginx", "postgres*"]
+
+	## States of the processes to sample, as the ps plugin reports them,
+	## checked before the first sample; every state if empty. The default
+	## samples the processes stuck in uninterruptible sleep.
+	#states = ["D"]
+
+	## Number of samples taken each gather, and time between them.
+	#samples = 10
+	#sample_interval = "50ms"
+
+	## Number of the most frequent stacks reported per process.
+	#top = 3
+
+	## Number of frames, from the innermost, kept in the reported stacks.
+	#max_depth = 16
+	`
+}
+
+// Gather samples the kernel stacks of the selected processes and stores
+// the most frequent ones in the accumulator acc.
+func (s *ProcStackSampler) Gather(acc telegraf.Accumulator) error {
+	if !s.compiled {
+		var err error
+		s.commFilter, err = filter.Compile(s.Comms)
+		if err != nil {
+			acc.AddError(err)
+			return fmt.Errorf("proc_stack_sampler: invalid comms: %s", err)
+		}
+		s.compiled = true
+	}
+
+	comms, err := s.selectProcesses()
+	if err != nil {
+		acc.AddError(err)
+		return fmt.Errorf("proc_stack_sampler: unable to gather metrics: %s", err)
+	}
+
+	stacks := make(map[int]map[string]*stackCount, len(comms))
+	taken := make(map[int]int, len(comms))
+	var denied int
+	for i := 0; i < s.Samples; i++ {
+		if i > 0 {
+			s.sleep(s.SampleInterval.Duration)
+		}
+		for pid := range comms {
+			frames, err := s.procFS.ReadStack(pid)
+			if err != nil {
+				if i == 0 && errors.Is(err, psinfo.ErrPermission) {
+					denied++
+				}
+				continue
+			}
+			if s.MaxDepth > 0 && len(frames) > s.MaxDepth {
+				frames = frames[:s.MaxDepth]
+			}
+			if len(frames) == 0 {
+				frames = []string{"[running]"}
+			}
+
+			if stacks[pid] == nil {
+				stacks[pid] = make(map[string]*stackCount)
+			}
+			folded := strings.Join(frames, ";")
+			if stacks[pid][folded] == nil {
+				stacks[pid][folded] = &stackCount{frames: frames}
+			}
+			stacks[pid][folded].count++
+			taken[pid]++
+		}
+	}
+	if denied > 0 && len(stacks) == 0 {
+		err := fmt.Errorf("%w: reading kernel stacks requires CAP_SYS_ADMIN", psinfo.ErrPermission)
+		acc.AddError(err)
+		return fmt.Errorf("proc_stack_sampler: unable to gather metrics: %s", err)
+	}
+
+	now := time.Now().UTC()
+	for pid, counts := range stacks {
+		s.addStacks(acc, pid, comms[pid], counts, taken[pid], now)
+	}
+
+	return nil
+}
+
+// selectProcesses returns the command names of the processes to sample,
+// by pid.
+func (s *ProcStackSampler) selectProcesses() (map[int]string, error) {
+	pids, err := s.procFS.Pids()
+	if err != nil {
+		return nil, err
+	}
+
+	selected := make(map[int]string)
+	for _, pid := range pids {
+		comm, err := s.procFS.ReadComm(pid)
+		if err != nil {
+			continue
+		}
+		if s.commFilter != nil && !s.commFilter.Match(comm) {
+			continue
+		}
+		if len(s.States) > 0 {
+			state, err := s.procFS.ReadState(pid)
+			if err != nil || !matchState(state, s.States) {
+				continue
+			}
+		}
+		selected[pid] = comm
+	}
+	return selected, nil
+}
+
+// addStacks adds the most frequent stacks of process pid to the
+// accumulator acc.
+func (s *ProcStackSampler) addStacks(acc telegraf.Accumulator, pid int, comm string, counts map[string]*stackCount, taken int, now time.Time) {
+	sorted := make([]*stackCount, 0, len(counts))
+	for _, count := range counts {
+		sorted = append(sorted, count)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].count != sorted[j].count {
+			return sorted[i].count > sorted[j].count
+		}
+		return strings.Join(sorted[i].frames, ";") < strings.Join(sorted[j].frames, ";")
+	})
+	if s.Top > 0 && len(sorted) > s.Top {
+		sorted = sorted[:s.Top]
+	}
+
+	for rank, count := range sorted {
+		tags := map[string]string{
+			"pid":   strconv.Itoa(pid),
+			"comm":  comm,
+			"frame": count.frames[0],
+			"rank":  strconv.Itoa(rank + 1),
+		}
+		// The stack is folded outermost frame first, as flame graph tools
+		// expect.
+		folded := make([]string, len(count.frames))
+		for i, frame := range count.frames {
+			folded[len(folded)-1-i] = frame
+		}
+		fields := map[string]interface{}{
+			"samples": count.count,
+			"share":   100 * float64(count.count) / float64(taken),
+			"stack":   strings.Join(folded, ";"),
+		}
+		acc.AddFields(measurement, fields, tags, now)
+	}
+}
+
+// matchState reports whether the state is among states, comparing the
+// first letter only.
+func matchState(state string, states []string) bool {
+	for _, s := range states {
+		if s != "" && strings.HasPrefix(state, s[:1]) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package proc_stack_sampler
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/gpapag/telegraf-plugins/pkg/psinfo/psinfotest"
+
+	"github.com/influxdata/telegraf/testutil"
+)
+
+// Kernel stacks of processes waiting for I/O, innermost function first.
+var (
+	fsyncStack = []string{
+		"io_schedule", "wait_on_page_bit_common", "wait_on_page_writeback",
+		"__filemap_fdatawait_range", "file_write_and_wait_range", "ext4_sync_file",
+		"__x64_sys_fsync", "do_syscall_64", "entry_SYSCALL_64_after_hwframe",
+	}
+	readStack = []string{
+		"io_schedule", "__lock_page_killable", "filemap_fault",
+		"__do_fault", "handle_mm_fault", "do_user_addr_fault", "exc_page_fault",
+	}
+)
+
+func TestGather(t *testing.T) {
+	proc := psinfotest.NewProc(t, psinfotest.Host{Uptime: 1000, BootTime: 1600000000, MemTotal: 8 << 20})
+	postgres := psinfotest.Process{Pid: 100, Ppid: 1, Comm: "postgres", State: "D", Stack: fsyncStack}
+	proc.Add(postgres)
+	proc.Add(psinfotest.Process{Pid: 200, Ppid: 1, Comm: "postgres", State: "D"})
+	proc.Add(psinfotest.Process{Pid: 300, Ppid: 1, Comm: "postgres", State: "S", Stack: []string{"do_select"}})
+	proc.Add(psinfotest.Process{Pid: 400, Ppid: 2, Comm: "kworker/u16:3", State: "D", Stack: readStack})
+
+	s := newProcStackSampler()
+	s.procFS = proc.ProcFS()
+	s.Comms = []string{"postgres*"}
+	s.Samples = 4
+	s.MaxDepth = 3
+	s.Top = 2
+
+	// The third sample of pid 100 catches it faulting a page in.
+	var slept []time.Duration
+	s.sleep = func(d time.Duration) {
+		slept = append(slept, d)
+		postgres.Stack = fsyncStack
+		if len(slept) == 2 {
+			postgres.Stack = readStack
+		}
+		proc.Add(postgres)
+	}
+
+	var acc testutil.Accumulator
+	if err := s.Gather(&acc); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(slept, []time.Duration{50 * time.Millisecond, 50 * time.Millisecond, 50 * time.Millisecond}) {
+		t.Errorf("slept %v", slept)
+	}
+
+	type metric struct {
+		Tags   map[string]string
+		Fields map[string]interface{}
+	}
+	expected := []metric{
+		{
+			map[string]string{"pid": "100", "comm": "postgres", "frame": "io_schedule", "rank": "1"},
+			map[string]interface{}{
+				"samples": 3, "share": 75.0,
+				"stack": "wait_on_page_writeback;wait_on_page_bit_common;io_schedule",
+			},
+		},
+		{
+			map[string]string{"pid": "100", "comm": "postgres", "frame": "io_schedule", "rank": "2"},
+			map[string]interface{}{
+				"samples": 1, "share": 25.0,
+				"stack": "filemap_fault;__lock_page_killable;io_schedule",
+			},
+		},
+		{
+			// A process running in user space has an empty stack.
+			map[string]string{"pid": "200", "comm": "postgres", "frame": "[running]", "rank": "1"},
+			map[string]interface{}{"samples": 4, "share": 100.0, "stack": "[running]"},
+		},
+	}
+	var got []metric
+	for _, m := range acc.Metrics {
+		if m.Measurement != measurement {
+			t.Errorf("measurement %q", m.Measurement)
+		}
+		got = append(got, metric{m.Tags, m.Fields})
+	}
+	// The processes are reported in no particular order.
+	sort.Slice(got, func(i, j int) bool {
+		return got[i].Tags["pid"]+got[i].Tags["rank"] < got[j].Tags["pid"]+got[j].Tags["rank"]
+	})
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("\n got %+v\nwant %+v", got, expected)
+	}
+}
+
+func TestGatherProcessGone(t *testing.T) {
+	proc := psinfotest.NewProc(t, psinfotest.Host{Uptime: 1000, BootTime: 1600000000, MemTotal: 8 << 20})
+	proc.Add(psinfotest.Process{Pid: 100, Ppid: 1, Comm: "rsync", State: "D", Stack: readStack})
+
+	s := newProcStackSampler()
+	s.procFS = proc.ProcFS()
+	s.Samples = 3
+	s.sleep = func(time.Duration) { proc.Remove(100) }
+
+	var acc testutil.Accumulator
+	if err := s.Gather(&acc); err != nil {
+		t.Fatal(err)
+	}
+	if len(acc.Metrics) != 1 || acc.Metrics[0].Fields["samples"] != 1 || acc.Metrics[0].Fields["share"] != 100.0 {
+		t.Errorf("metrics %v, expected the single sample taken", acc.Metrics)
+	}
+}
+
+func TestMatchState(t *testing.T) {
+	tests := []struct {
+		state  string
+		states []string
+		match  bool
+	}{
+		{"D", []string{"D"}, true},
+		{"D+", []string{"D"}, true},
+		{"S", []string{"D", "R"}, false},
+		{"R", []string{"", "Running"}, true},
+		{"Z", nil, false},
+	}
+	for _, tt := range tests {
+		if match := matchState(tt.state, tt.states); match != tt.match {
+			t.Errorf("%q in %q: %t, expected %t", tt.state, tt.states, match, tt.match)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package ps
+
+import (
+	"github.com/gpapag/telegraf-plugins/pkg/psinfo"
+)
+
+// affinityFields are the per_process fields read from the
+// Cpus_allowed_list line of /proc/<pid>/status.
+var affinityFields = []string{"cpus_allowed", "cpus_allowed_count"}
+
+// affinity returns the cpus process may run on, as a list of ranges, and
+// their number. Processes whose status could not be read, or on kernels not
+// listing the affinity, yield no fields.
+func (p *PS) affinity(process psinfo.Process) map[string]interface{} {
+	if !p.readAffinity {
+		return nil
+	}
+
+	status := process.ProcStatus
+	if status == nil || status.CpusAllowedList == "" {
+		return nil
+	}
+	count, err := psinfo.CountCPUs(status.CpusAllowedList)
+	if err != nil {
+		return nil
+	}
+	return map[string]interface{}{
+		"cpus_allowed":       status.CpusAllowedList,
+		"cpus_allowed_count": count,
+	}
+}
@@ -0,0 +1,64 @@
+package ps
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gpapag/telegraf-plugins/pkg/psinfo"
+)
+
+// Modes accepted by the args_mode option.
+const (
+	argsModeFull    = `full`
+	argsModeProgram = `program`
+	argsModeHash    = `hash`
+)
+
+// argsHashLength is the number of bytes of the SHA-256 of the arguments
+// kept by args_mode = "hash", shown as twice as many hex digits.
+const argsHashLength = 8
+
+// reportedArgs returns args as reported in the metrics, shortened as
+// selected by the args_mode and args_max_length options. Selections and
+// tag templates see the arguments whole.
+func (p *PS) reportedArgs(args string) string {
+	switch p.ArgsMode {
+	case argsModeProgram:
+		if i := strings.IndexByte(args, ' '); i >= 0 {
+			args = args[:i]
+		}
+	case argsModeHash:
+		// Kernel threads have no arguments to tell apart.
+		if args == "" {
+			return ""
+		}
+		sum := sha256.Sum256([]byte(args))
+		return hex.EncodeToString(sum[:argsHashLength])
+	}
+
+	if p.ArgsMaxLength > 0 && len(args) > p.ArgsMaxLength {
+		// The cut backs off to the start of a character.
+		cut := p.ArgsMaxLength
+		for cut > 0 && !utf8.RuneStart(args[cut]) {
+			cut--
+		}
+		args = args[:cut]
+	}
+	return args
+}
+
+// reportedProcesses returns processes with their arguments as reported,
+// leaving processes unchanged.
+func (p *PS) reportedProcesses(processes []psinfo.Process) []psinfo.Process {
+	if p.ArgsMode == argsModeFull && p.ArgsMaxLength == 0 {
+		return processes
+	}
+	reported := make([]psinfo.Process, len(processes))
+	for i, process := range processes {
+		process.Args = p.reportedArgs(process.Args)
+		reported[i] = process
+	}
+	return reported
+}
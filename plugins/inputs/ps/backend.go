@@ -0,0 +1,7 @@
+//go:build !windows
+// +build !windows
+
+package ps
+
+// defaultBackend is the backend of the ps plugin when none is configured.
+const defaultBackend = backendPS
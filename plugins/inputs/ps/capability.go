@@ -0,0 +1,48 @@
+package ps
+
+import (
+	"fmt"
+
+	"github.com/gpapag/telegraf-plugins/pkg/psinfo"
+)
+
+// capabilityBits maps the per_process boolean fields telling whether a
+// process holds a capability to the number of the capability, from
+// linux/capability.h.
+var capabilityBits = map[string]uint{
+	"has_cap_net_admin":  12,
+	"has_cap_net_raw":    13,
+	"has_cap_sys_ptrace": 19,
+	"has_cap_sys_admin":  21,
+}
+
+// capabilityFields are the per_process fields read from the CapEff line of
+// /proc/<pid>/status.
+var capabilityFields = []string{
+	"cap_eff",
+	"has_cap_net_admin",
+	"has_cap_net_raw",
+	"has_cap_sys_ptrace",
+	"has_cap_sys_admin",
+}
+
+// capabilities returns the effective capabilities of process, as the hex
+// mask of the status file and a boolean per capability of note. Processes
+// whose status could not be read yield no fields.
+func (p *PS) capabilities(process psinfo.Process) map[string]interface{} {
+	if !p.readCapabilities {
+		return nil
+	}
+
+	status := process.ProcStatus
+	if status == nil || !status.HasCapEff {
+		return nil
+	}
+	fields := map[string]interface{}{
+		"cap_eff": fmt.Sprintf("%016x", status.CapEff),
+	}
+	for field, bit := range capabilityBits {
+		fields[field] = status.CapEff&(1<<bit) != 0
+	}
+	return fields
+}
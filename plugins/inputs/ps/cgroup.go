@@ -0,0 +1,53 @@
+package ps
+
+import (
+	"time"
+
+	"github.com/gpapag/telegraf-plugins/pkg/psinfo"
+
+	"github.com/influxdata/telegraf"
+)
+
+const cgroupMeasurement = `ps_cgroup`
+
+// cgroupFiles maps the interface files of a cgroup v2 read for the
+// cgroup_rollups option to the fields holding their value.
+var cgroupFiles = map[string]string{
+	"memory.current": "memory_current",
+	"pids.current":   "pids_current",
+}
+
+// addCgroups stores in acc one metric per cgroup v2 holding processes,
+// with the totals of those processes and the memory and task counts the
+// cgroup accounts itself. Processes outside the unified hierarchy, as on
+// hosts mounting cgroup v1 only, are left out.
+func (p *PS) addCgroups(acc telegraf.Accumulator, processes []psinfo.Process, cpuUsage map[int]float64, now time.Time) {
+	groups := make(map[string]*processGroup)
+	for _, process := range processes {
+		cgroup, err := p.procFS.ReadUnifiedCgroup(process.Pid)
+		if err != nil || cgroup == "" {
+			continue
+		}
+		group, ok := groups[cgroup]
+		if !ok {
+			group = &processGroup{}
+			groups[cgroup] = group
+		}
+		group.add(process, cpuUsage)
+	}
+
+	for cgroup, group := range groups {
+		fields := p.groupFields(group)
+		// Controllers not enabled for the cgroup have no interface files.
+		for file, field := range cgroupFiles {
+			if value, err := psinfo.ReadCgroupValue(p.CgroupRoot, cgroup, file); err == nil {
+				fields[field] = value
+			}
+		}
+		tags := map[string]string{
+			"plugin": tag,
+			"cgroup": cgroup,
+		}
+		acc.AddFields(cgroupMeasurement, fields, tags, now)
+	}
+}
@@ -0,0 +1,24 @@
+package ps
+
+import "time"
+
+// Clock is the source of time of PS. Timestamps, retry delays and the
+// intervals between process samples all come from it, so that they can be
+// simulated deterministically.
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+}
+
+// systemClock is the Clock of the host.
+type systemClock struct{}
+
+// Now returns the current local time.
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// Sleep pauses the current goroutine for at least d.
+func (systemClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
@@ -0,0 +1,164 @@
+package ps
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kballard/go-shellquote"
+
+	"github.com/gpapag/telegraf-plugins/pkg/psinfo"
+)
+
+const backendPS = `ps`
+
+// ProcessCollector gathers the process table in two steps. Select lists the
+// candidate processes with at least the attributes needed to choose among
+// them, and Collect completes the attributes of the chosen ones, so that
+// backends can skip expensive work for processes that are not reported.
+// Backends reading every process on their own stop at deadline, the end of
+// the gather, unless it is zero.
+type ProcessCollector interface {
+	Select(deadline time.Time) ([]psinfo.Process, error)
+	Collect(processes []psinfo.Process, deadline time.Time) ([]psinfo.Process, error)
+}
+
+// partialCollector is implemented by the backends unable to report some
+// of the per_process fields, which are then left out of the metrics.
+type partialCollector interface {
+	Unsupported() []string
+}
+
+// parsingCollector is implemented by the backends parsing the output of a
+// command, which skip the lines they cannot parse. ParseErrors returns the
+// number of lines skipped by the latest Select.
+type parsingCollector interface {
+	ParseErrors() int
+}
+
+// collectorCreator returns a new ProcessCollector configured from p.
+type collectorCreator func(p *PS) (ProcessCollector, error)
+
+// collectors holds the available backends by name.
+var collectors = map[string]collectorCreator{}
+
+// addCollector registers a backend under name.
+func addCollector(name string, creator collectorCreator) {
+	collectors[name] = creator
+}
+
+// psCollector is a ProcessCollector running the ps command.
+type psCollector struct {
+	runner  Runner
+	command string
+	columns []psinfo.Column
+	timeout time.Duration
+	fixed   bool
+
+	// exactCPU tells whether the cpu times of ps, in whole seconds, are
+	// replaced by those of /proc/<pid>/stat, in clock ticks.
+	exactCPU bool
+	procFS   psinfo.ProcFS
+	pool     workerPool
+
+	// mu guards dropped, as overlapping gathers share the collector.
+	mu      sync.Mutex
+	dropped int
+}
+
+func init() {
+	addCollector(backendPS, func(p *PS) (ProcessCollector, error) {
+		command := p.psCommand()
+		if p.UseSudo {
+			command = sudoCommand + " " + command
+		}
+		return &psCollector{
+			runner:  p.runner,
+			command: command,
+			columns: p.columns,
+			timeout: p.Timeout.Duration,
+			fixed:   p.ParseMode == parseModeFixedWidth,
+
+			exactCPU: runtime.GOOS == "linux",
+			procFS:   p.procFS,
+			pool:     p.pool,
+		}, nil
+	})
+}
+
+// psCommand returns the ps command line listing every process with the
+// columns of p.
+func (p *PS) psCommand() string {
+	ps := shellquote.Join(append([]string{p.PsPath}, p.PsArgs...)...)
+	format := psinfo.FormatColumns(p.columns)
+	if p.ParseMode == parseModeFixedWidth {
+		format = psinfo.FormatFixedColumns(p.columns)
+	}
+	return strings.Join([]string{ps, p.procSelection, format}, " ")
+}
+
+// Select runs ps and returns every process it reports. The command is
+// bounded by the timeout option rather than deadline, so that retries get
+// the same time as the first attempt.
+func (c *psCollector) Select(deadline time.Time) ([]psinfo.Process, error) {
+	out, err := c.runner.Run(c.command, c.timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	parse := psinfo.ParseDropped
+	if c.fixed {
+		parse = psinfo.ParseFixed
+	}
+	processes, dropped, err := parse(string(out), c.columns)
+	c.mu.Lock()
+	c.dropped = dropped
+	c.mu.Unlock()
+	return processes, err
+}
+
+// ParseErrors returns the number of lines of ps output the latest Select
+// could not parse.
+func (c *psCollector) ParseErrors() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.dropped
+}
+
+// Collect returns processes, as ps reports every attribute at once, with
+// the cpu times of /proc/<pid>/stat when exactCPU is set: those of ps have a
+// resolution of one second, far too coarse for the usage over an interval.
+// The times of ps are all kept unless every process is read by deadline,
+// and so are those of the processes that exited, or whose pid was reused,
+// since ps ran. The stat files read are kept in the processes.
+func (c *psCollector) Collect(processes []psinfo.Process, deadline time.Time) ([]psinfo.Process, error) {
+	if !c.exactCPU {
+		return processes, nil
+	}
+
+	stats := make([]*psinfo.Stat, len(processes))
+	err := c.pool.forEach(len(processes), deadline, func(i int) {
+		stats[i] = c.readStat(processes[i])
+	})
+	if err != nil {
+		return processes, nil
+	}
+	for i, stat := range stats {
+		if stat != nil {
+			processes[i].CPUTime = float64(stat.Utime+stat.Stime) / psinfo.ClockTicks
+			processes[i].ProcStat = stat
+		}
+	}
+	return processes, nil
+}
+
+// readStat returns the /proc/<pid>/stat of process, or nil if it cannot be
+// read for process.
+func (c *psCollector) readStat(process psinfo.Process) *psinfo.Stat {
+	stat, err := c.procFS.ReadStat(process.Pid)
+	if err != nil || stat.Comm != process.Comm {
+		return nil
+	}
+	return &stat
+}
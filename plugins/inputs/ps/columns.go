@@ -0,0 +1,91 @@
+package ps
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gpapag/telegraf-plugins/pkg/psinfo"
+)
+
+// Types accepted by the type of the columns option.
+const (
+	columnTypeString  = `string`
+	columnTypeInteger = `integer`
+	columnTypeFloat   = `float`
+)
+
+// ColumnConfig is a ps column, in addition to those the plugin reads, that
+// is emitted as a per_process field.
+type ColumnConfig struct {
+	Spec  string
+	Field string
+	Type  string
+}
+
+// extraColumns returns the ps columns of the columns option, after checking
+// that their fields do not clash with the fields of the plugin.
+func (p *PS) extraColumns() ([]psinfo.Column, error) {
+	if len(p.Columns) > 0 && p.Backend != backendPS {
+		return nil, fmt.Errorf("columns only apply to the %s backend", backendPS)
+	}
+
+	columns := make([]psinfo.Column, 0, len(p.Columns))
+	fields := make(map[string]bool)
+	for _, c := range p.Columns {
+		if c.Spec == "" || strings.ContainsAny(c.Spec, " \t,=") {
+			return nil, fmt.Errorf("columns: invalid spec %q", c.Spec)
+		}
+		// Fixed widths are requested as spec:width.
+		if p.ParseMode == parseModeFixedWidth && strings.Contains(c.Spec, ":") {
+			return nil, fmt.Errorf("columns: spec %q sets a width, which parse_mode %s sets itself", c.Spec, p.ParseMode)
+		}
+		if c.Field == "" {
+			c.Field = c.Spec
+		}
+		if _, ok := fieldColumns[c.Field]; ok || fields[c.Field] {
+			return nil, fmt.Errorf("columns: field %q is already emitted", c.Field)
+		}
+		fields[c.Field] = true
+		switch c.Type {
+		case "", columnTypeString, columnTypeInteger, columnTypeFloat:
+		default:
+			return nil, fmt.Errorf("columns: unknown type %q of %s", c.Type, c.Spec)
+		}
+		columns = append(columns, psinfo.ExtraColumn(c.Spec))
+	}
+	return columns, nil
+}
+
+// extraFields returns the fields of the columns option for process. Values
+// that do not parse as the type of their column are left out.
+func (p *PS) extraFields(process psinfo.Process) map[string]interface{} {
+	if len(p.Columns) == 0 {
+		return nil
+	}
+
+	fields := make(map[string]interface{}, len(p.Columns))
+	for _, c := range p.Columns {
+		value, ok := process.Extra[c.Spec]
+		if !ok || value == "-" {
+			continue
+		}
+		field := c.Field
+		if field == "" {
+			field = c.Spec
+		}
+		switch c.Type {
+		case columnTypeInteger:
+			if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+				fields[field] = i
+			}
+		case columnTypeFloat:
+			if f, err := strconv.ParseFloat(value, 64); err == nil {
+				fields[field] = f
+			}
+		default:
+			fields[field] = value
+		}
+	}
+	return fields
+}
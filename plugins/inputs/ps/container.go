@@ -0,0 +1,53 @@
+package ps
+
+import (
+	"time"
+
+	"github.com/gpapag/telegraf-plugins/pkg/psinfo"
+
+	"github.com/influxdata/telegraf"
+)
+
+// addCgroupTags adds to tags the container and the Kubernetes pod process
+// pid runs in, told from its cgroup, as selected by the container_tags and
+// pod_tags options. Host processes, and processes whose cgroup cannot be
+// read, get no tags.
+func (p *PS) addCgroupTags(acc telegraf.Accumulator, tags map[string]string, pid int, now time.Time) {
+	if !p.ContainerTags && !p.PodTags {
+		return
+	}
+
+	cgroup, err := p.procFS.ReadCgroup(pid)
+	if err != nil {
+		return
+	}
+
+	if p.ContainerTags {
+		if id := psinfo.ContainerID(cgroup); id != "" {
+			tags["container_id"] = id
+			if runtime := psinfo.ContainerRuntime(cgroup); runtime != "" {
+				tags["container_runtime"] = runtime
+			}
+		}
+	}
+
+	if !p.PodTags {
+		return
+	}
+	uid := psinfo.PodUID(cgroup)
+	if uid == "" {
+		return
+	}
+	tags["pod_uid"] = uid
+	if p.kubelet == nil {
+		return
+	}
+	pod, ok, err := p.lookupPod(uid, now)
+	if err != nil {
+		acc.AddError(p.errorf("unable to list the pods of the kubelet: %w", err))
+	}
+	if ok {
+		tags["pod_name"] = pod.name
+		tags["pod_namespace"] = pod.namespace
+	}
+}
@@ -0,0 +1,93 @@
+package ps
+
+import (
+	"time"
+
+	"github.com/gpapag/telegraf-plugins/pkg/psinfo"
+)
+
+// deltaSuffix is appended to the name of a counter field to name the field
+// holding its increase since the previous gather.
+const deltaSuffix = `_delta`
+
+// ctxtSwitchFields are the per_process counter fields read from
+// /proc/<pid>/status.
+var ctxtSwitchFields = []string{
+	"voluntary_ctxt_switches",
+	"nonvoluntary_ctxt_switches",
+	"voluntary_ctxt_switches" + deltaSuffix,
+	"nonvoluntary_ctxt_switches" + deltaSuffix,
+}
+
+// faultFields are the per_process counter fields read from
+// /proc/<pid>/stat.
+var faultFields = []string{
+	"minflt",
+	"majflt",
+	"minflt" + deltaSuffix,
+	"majflt" + deltaSuffix,
+}
+
+// counterSample holds the counters of a process at the previous gather.
+type counterSample struct {
+	comm   string
+	values map[string]int64
+}
+
+// readCounters returns the counters of process taken from its status and
+// stat files, by field name. Counters whose file could not be read, such as
+// those of the processes of other users, are missing.
+func (p *PS) readCounters(process psinfo.Process) map[string]int64 {
+	values := make(map[string]int64)
+	if p.readCtxtSwitches && process.ProcStatus != nil {
+		values["voluntary_ctxt_switches"] = int64(process.ProcStatus.VoluntaryCtxtSwitches)
+		values["nonvoluntary_ctxt_switches"] = int64(process.ProcStatus.NonvoluntaryCtxtSwitches)
+	}
+	if p.readFaults && process.ProcStat != nil {
+		values["minflt"] = int64(process.ProcStat.Minflt)
+		values["majflt"] = int64(process.ProcStat.Majflt)
+	}
+	return values
+}
+
+// counters returns the counter fields of processes by pid, along with the
+// increase of each counter since the previous gather. Processes absent from
+// the previous gather, or whose pid was reused since, have no increase, nor
+// does any process in a gather overtaken by a more recent one. Processes
+// whose files were not read have no counters.
+func (p *PS) counters(processes []psinfo.Process, now time.Time) map[int]map[string]interface{} {
+	if !p.readCtxtSwitches && !p.readFaults {
+		return nil
+	}
+
+	samples := make(map[int]counterSample, len(processes))
+	for _, process := range processes {
+		if values := p.readCounters(process); len(values) > 0 {
+			samples[process.Pid] = counterSample{comm: process.Comm, values: values}
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	overtaken := now.Before(p.countersAt)
+	fields := make(map[int]map[string]interface{}, len(samples))
+	for pid, sample := range samples {
+		previous, ok := p.counterSamples[pid]
+		known := ok && !overtaken && previous.comm == sample.comm
+		f := make(map[string]interface{}, 2*len(sample.values))
+		for name, value := range sample.values {
+			f[name] = value
+			if last, ok := previous.values[name]; known && ok && value >= last {
+				f[name+deltaSuffix] = value - last
+			}
+		}
+		fields[pid] = f
+	}
+
+	if !overtaken {
+		p.counterSamples = samples
+		p.countersAt = now
+	}
+	return fields
+}
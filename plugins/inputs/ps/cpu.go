@@ -0,0 +1,45 @@
+package ps
+
+import (
+	"time"
+
+	"github.com/gpapag/telegraf-plugins/pkg/psinfo"
+)
+
+// cpuSample is the cpu time a process had used at the previous gather.
+type cpuSample struct {
+	comm    string
+	cpuTime float64
+}
+
+// cpuUsage returns the cpu usage of the processes since the previous
+// gather, in percent of one cpu, by pid. The ps %cpu column averages the
+// usage over the lifetime of a process instead. Processes absent from the
+// previous gather, or whose pid was reused since, have no usage, nor does
+// any process on the first gather or in a gather overtaken by a more
+// recent one.
+func (p *PS) cpuUsage(processes []psinfo.Process, now time.Time) map[int]float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if now.Before(p.cpuAt) {
+		return nil
+	}
+
+	usage := make(map[int]float64, len(processes))
+	elapsed := now.Sub(p.cpuAt).Seconds()
+	samples := make(map[int]cpuSample, len(processes))
+	for _, process := range processes {
+		samples[process.Pid] = cpuSample{comm: process.Comm, cpuTime: process.CPUTime}
+
+		previous, ok := p.cpuSamples[process.Pid]
+		if !ok || previous.comm != process.Comm || process.CPUTime < previous.cpuTime || elapsed <= 0 {
+			continue
+		}
+		usage[process.Pid] = round1(100 * (process.CPUTime - previous.cpuTime) / elapsed)
+	}
+
+	p.cpuSamples = samples
+	p.cpuAt = now
+	return usage
+}
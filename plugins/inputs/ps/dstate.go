@@ -0,0 +1,56 @@
+package ps
+
+import (
+	"time"
+
+	"github.com/gpapag/telegraf-plugins/pkg/psinfo"
+)
+
+// dstateSample tells since when a process has been seen in uninterruptible
+// sleep at every gather.
+type dstateSample struct {
+	comm  string
+	since time.Time
+}
+
+// dstateDurations returns, by pid, the number of seconds the processes have
+// been in uninterruptible sleep at every gather since, and 0 for processes
+// in any other state. A process first seen in the D state counts from the
+// current gather, as do processes whose pid was reused, so that a process
+// briefly in D never builds up a duration. Processes without a state, and
+// every process in a gather overtaken by a more recent one, have none.
+func (p *PS) dstateDurations(processes []psinfo.Process, now time.Time) map[int]int64 {
+	if !p.readDState {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if now.Before(p.dstateAt) {
+		return nil
+	}
+
+	durations := make(map[int]int64, len(processes))
+	samples := make(map[int]dstateSample)
+	for _, process := range processes {
+		if process.Stat == "" {
+			continue
+		}
+		if process.Stat[0] != 'D' {
+			durations[process.Pid] = 0
+			continue
+		}
+
+		since := now
+		if previous, ok := p.dstateSamples[process.Pid]; ok && previous.comm == process.Comm {
+			since = previous.since
+		}
+		samples[process.Pid] = dstateSample{comm: process.Comm, since: since}
+		durations[process.Pid] = int64(now.Sub(since) / time.Second)
+	}
+
+	p.dstateSamples = samples
+	p.dstateAt = now
+	return durations
+}
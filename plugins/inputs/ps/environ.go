@@ -0,0 +1,62 @@
+package ps
+
+import (
+	"strings"
+
+	"github.com/gpapag/telegraf-plugins/pkg/psinfo"
+)
+
+const envFieldPrefix = `env_`
+
+// environ returns the environment variables of process pid permitted by the
+// env_allowlist option, capped at env_max_count entries, and those named by
+// the env_tags option, all truncated to env_max_value_length. The variables
+// of env_tags are only returned as tags. Processes whose environment cannot
+// be read, such as those of other users, yield no variables and are
+// recorded in denied.
+func (p *PS) environ(pid int, denied *denials) (fields, tags map[string]string) {
+	if p.envFilter == nil && len(p.EnvTags) == 0 {
+		return nil, nil
+	}
+
+	entries, err := p.procFS.ReadEnviron(pid)
+	if err != nil {
+		denied.check("environ", err)
+		return nil, nil
+	}
+
+	fields = make(map[string]string)
+	tags = make(map[string]string)
+	for _, entry := range entries {
+		keyValue := strings.SplitN(entry, "=", 2)
+		if len(keyValue) != 2 {
+			continue
+		}
+		tagged := p.envTagged(keyValue[0])
+		if !tagged && (p.envFilter == nil || !p.envFilter.Match(keyValue[0]) || len(fields) >= p.EnvMaxCount) {
+			continue
+		}
+		value := keyValue[1]
+		if len(value) > p.EnvMaxValueLength {
+			value = value[:p.EnvMaxValueLength]
+		}
+		if tagged {
+			tags[keyValue[0]] = psinfo.Sanitize(value)
+		} else {
+			fields[keyValue[0]] = psinfo.Sanitize(value)
+		}
+	}
+
+	return fields, tags
+}
+
+// envTagged reports whether the variable name is listed by the env_tags
+// option.
+func (p *PS) envTagged(name string) bool {
+	for _, tagged := range p.EnvTags {
+		if name == tagged {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package ps
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gpapag/telegraf-plugins/pkg/psinfo"
+
+	"github.com/influxdata/telegraf"
+)
+
+const eventMeasurement = `ps_event`
+
+// Timestamps accepted by the event_time option.
+const (
+	eventTimeGather       = `gather`
+	eventTimeProcessStart = `process_start`
+)
+
+// addEvents stores in acc a started event for every process not present in
+// the previous gather, and an exited event for every process of the
+// previous gather now gone. Processes named by a pid file or belonging to a
+// systemd unit keep the process_name or systemd_unit tag they had when
+// alive in their exited event. Nothing is reported on the first gather,
+// when every process would otherwise look new, nor by a gather overtaken by
+// a more recent one.
+func (p *PS) addEvents(acc telegraf.Accumulator, processes []psinfo.Process, names, units map[int]string, now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if now.Before(p.knownAt) {
+		return
+	}
+
+	current := make(map[int]psinfo.Process, len(processes))
+	currentTags := make(map[int]map[string]string)
+	for _, process := range processes {
+		current[process.Pid] = process
+		tags := make(map[string]string)
+		if name, ok := names[process.Pid]; ok {
+			tags["process_name"] = name
+		}
+		if unit, ok := units[process.Pid]; ok {
+			tags["systemd_unit"] = unit
+		}
+		currentTags[process.Pid] = tags
+	}
+
+	if p.knownProcesses != nil {
+		for _, process := range processes {
+			known, ok := p.knownProcesses[process.Pid]
+			if ok && known.Comm == process.Comm {
+				continue
+			}
+			if ok {
+				// The pid was reused by another command.
+				p.addEvent(acc, "exited", known, p.knownTags[known.Pid], now)
+			}
+			p.addEvent(acc, "started", process, currentTags[process.Pid], p.startedAt(process, now))
+		}
+		for pid, known := range p.knownProcesses {
+			if _, ok := current[pid]; !ok {
+				p.addEvent(acc, "exited", known, p.knownTags[pid], now)
+			}
+		}
+	}
+
+	p.knownProcesses = current
+	p.knownTags = currentTags
+	p.knownAt = now
+}
+
+// addEvent stores in acc a single lifecycle event about process, with the
+// extra tags.
+func (p *PS) addEvent(acc telegraf.Accumulator, event string, process psinfo.Process, extra map[string]string, at time.Time) {
+	tags := map[string]string{
+		"plugin": tag,
+		"event":  event,
+		"pid":    strconv.Itoa(process.Pid),
+		"comm":   process.Comm,
+	}
+	for key, value := range extra {
+		tags[key] = value
+	}
+	p.addUserTags(tags, process)
+
+	fields := map[string]interface{}{
+		"ppid": process.Ppid,
+		"args": p.reportedArgs(process.Args),
+	}
+	acc.AddFields(eventMeasurement, fields, tags, at)
+}
+
+// startedAt returns the timestamp of the started event of process, seen for
+// the first time at now.
+func (p *PS) startedAt(process psinfo.Process, now time.Time) time.Time {
+	if p.EventTime != eventTimeProcessStart {
+		return now
+	}
+	return time.Unix(startTime(process, now), 0).UTC()
+}
+
+// startTime returns the time process started, in seconds since the epoch.
+// The ps command only tells the seconds elapsed since, which yield a start
+// time off by up to a second from one gather to the next.
+func startTime(process psinfo.Process, now time.Time) int64 {
+	if process.Started != 0 {
+		return process.Started
+	}
+	return now.Unix() - int64(process.Etimes)
+}
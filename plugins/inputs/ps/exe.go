@@ -0,0 +1,104 @@
+package ps
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/gpapag/telegraf-plugins/pkg/psinfo"
+)
+
+// maxExeSums bounds the number of checksums kept by exeCache; the cache
+// starts over once it is full.
+const maxExeSums = 4096
+
+// exeKey identifies the content of an executable: a file replaced or
+// modified in place gets another inode or change time.
+type exeKey struct {
+	dev   uint64
+	ino   uint64
+	size  int64
+	ctime int64
+}
+
+// exeCache holds the SHA-256 checksums of executables by identity, so
+// that each binary is only hashed once whatever the number of processes
+// running it. It is shared by the workers of a gather.
+type exeCache struct {
+	mu   sync.Mutex
+	sums map[exeKey]string
+}
+
+// exeFields returns whether the executable of process pid was deleted or
+// replaced since it started, and its SHA-256 checksum. Processes whose
+// executable cannot be read, such as kernel threads and the processes of
+// other users, yield no fields; those denied are recorded in denied.
+func (p *PS) exeFields(pid int, denied *denials) map[string]interface{} {
+	fields := make(map[string]interface{})
+	if p.readExeDeleted {
+		target, err := p.procFS.ReadExe(pid)
+		if err == nil {
+			fields["exe_deleted"] = strings.HasSuffix(target, psinfo.DeletedSuffix)
+		}
+		denied.check("exe", err)
+	}
+	if p.readExe {
+		sum, err := p.exeChecksum(pid)
+		if err == nil {
+			fields["exe_sha256"] = sum
+		}
+		denied.check("exe", err)
+	}
+	return fields
+}
+
+// exeChecksum returns the SHA-256 checksum of the executable of process
+// pid, hashed on first sight of the binary.
+func (p *PS) exeChecksum(pid int) (string, error) {
+	file, err := p.procFS.OpenExe(pid)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+	key, ok := fileIdentity(info)
+	if ok {
+		if sum, ok := p.exeSums.get(key); ok {
+			return sum, nil
+		}
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	sum := hex.EncodeToString(hash.Sum(nil))
+	if ok {
+		p.exeSums.put(key, sum)
+	}
+	return sum, nil
+}
+
+// get returns the checksum cached for key.
+func (c *exeCache) get(key exeKey) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	sum, ok := c.sums[key]
+	return sum, ok
+}
+
+// put caches sum as the checksum of key.
+func (c *exeCache) put(key exeKey, sum string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.sums == nil || len(c.sums) >= maxExeSums {
+		c.sums = make(map[exeKey]string)
+	}
+	c.sums[key] = sum
+}
@@ -0,0 +1,24 @@
+//go:build linux
+// +build linux
+
+package ps
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileIdentity returns the identity of the file described by info. The
+// change time cannot be set by users, unlike the modification time.
+func fileIdentity(info os.FileInfo) (exeKey, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return exeKey{}, false
+	}
+	return exeKey{
+		dev:   uint64(stat.Dev),
+		ino:   uint64(stat.Ino),
+		size:  stat.Size,
+		ctime: stat.Ctim.Nano(),
+	}, true
+}
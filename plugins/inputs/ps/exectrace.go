@@ -0,0 +1,193 @@
+package ps
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gpapag/telegraf-plugins/pkg/psinfo"
+
+	"github.com/influxdata/telegraf"
+)
+
+const shortLivedMeasurement = `ps_short_lived`
+
+// maxTracedProcesses bounds the number of running processes an execTracer
+// remembers between two gathers, against fork bombs.
+const maxTracedProcesses = 65536
+
+// errSourceClosed is returned by the Read method of a closed
+// procEventSource.
+var errSourceClosed = errors.New("event source closed")
+
+// procEvent is the exec of a program, or else the exit of a process, as
+// reported by the kernel.
+type procEvent struct {
+	exec bool
+	pid  int
+}
+
+// procEventSource delivers the exec and exit events of every process of
+// the host.
+type procEventSource interface {
+	// Read blocks until the next event. The number of times events were
+	// lost before it is returned as well.
+	Read() (event procEvent, lost int, err error)
+	Close() error
+}
+
+// shortLived holds the totals of the processes of a command that both
+// started and exited between two gathers.
+type shortLived struct {
+	processes int
+	cpuTime   float64 // seconds
+}
+
+// execTracer records the programs executed and exited between two gathers,
+// which ps never sees.
+type execTracer struct {
+	source procEventSource
+	procFS psinfo.ProcFS
+	wg     sync.WaitGroup
+
+	// mu guards the state below, updated as events arrive.
+	mu sync.Mutex
+	// running holds the command of the processes executed since the
+	// previous gather and still running, by pid.
+	running map[int]string
+	exited  map[string]*shortLived
+	lost    int
+}
+
+// newExecTracer returns a pointer to a new execTracer reading events from
+// source until it is stopped.
+func newExecTracer(source procEventSource, procFS psinfo.ProcFS) *execTracer {
+	t := &execTracer{
+		source:  source,
+		procFS:  procFS,
+		running: make(map[int]string),
+		exited:  make(map[string]*shortLived),
+	}
+	t.wg.Add(1)
+	go t.run()
+	return t
+}
+
+// run handles the events of the source until it is closed.
+func (t *execTracer) run() {
+	defer t.wg.Done()
+	for {
+		event, lost, err := t.source.Read()
+		if lost > 0 {
+			t.mu.Lock()
+			t.lost += lost
+			t.mu.Unlock()
+		}
+		if err != nil {
+			return
+		}
+		t.handle(event)
+	}
+}
+
+// handle records the command of a program being executed, and adds the
+// exit of a process executed since the previous gather to the totals of
+// its command. The cpu time of an exiting process is read before its
+// parent reaps it, which may be too late.
+func (t *execTracer) handle(event procEvent) {
+	if event.exec {
+		comm, err := t.procFS.ReadComm(event.pid)
+		if err != nil {
+			return
+		}
+		t.mu.Lock()
+		if len(t.running) < maxTracedProcesses {
+			t.running[event.pid] = comm
+		}
+		t.mu.Unlock()
+		return
+	}
+
+	t.mu.Lock()
+	comm, ok := t.running[event.pid]
+	delete(t.running, event.pid)
+	t.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	var cpuTime float64
+	if stat, err := t.procFS.ReadStat(event.pid); err == nil {
+		cpuTime = float64(stat.Utime+stat.Stime) / psinfo.ClockTicks
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	totals, ok := t.exited[comm]
+	if !ok {
+		totals = &shortLived{}
+		t.exited[comm] = totals
+	}
+	totals.processes++
+	totals.cpuTime += cpuTime
+}
+
+// flush returns the totals of the short-lived processes by command and the
+// number of times events were lost since the previous flush, and starts
+// over: the processes running from now on are left to ps.
+func (t *execTracer) flush() (map[string]*shortLived, int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	exited, lost := t.exited, t.lost
+	t.running = make(map[int]string)
+	t.exited = make(map[string]*shortLived)
+	t.lost = 0
+	return exited, lost
+}
+
+// stop stops reading events and waits for the tracer to finish.
+func (t *execTracer) stop() {
+	t.source.Close()
+	t.wg.Wait()
+}
+
+// Start starts tracing the programs executed between gathers when the
+// exec_tracing option is set.
+func (p *PS) Start(acc telegraf.Accumulator) error {
+	if !p.ExecTracing {
+		return nil
+	}
+	if err := p.setup(); err != nil {
+		return p.errorf("invalid configuration: %w", err)
+	}
+	source, err := openProcEvents()
+	if err != nil {
+		return p.errorf("unable to trace processes: %w", err)
+	}
+	p.tracer = newExecTracer(source, p.procFS)
+	return nil
+}
+
+// Stop stops tracing the programs executed.
+func (p *PS) Stop() {
+	if p.tracer != nil {
+		p.tracer.stop()
+	}
+}
+
+// addShortLived stores in acc one metric per command with the totals of
+// its processes that started and exited since the previous gather.
+func (p *PS) addShortLived(acc telegraf.Accumulator, exited map[string]*shortLived, now time.Time) {
+	for comm, totals := range exited {
+		tags := map[string]string{
+			"plugin": tag,
+			"comm":   comm,
+		}
+		fields := map[string]interface{}{
+			"processes": totals.processes,
+			"cpu_time":  totals.cpuTime,
+		}
+		acc.AddFields(shortLivedMeasurement, fields, tags, now)
+	}
+}
@@ -0,0 +1,155 @@
+//go:build linux
+// +build linux
+
+package ps
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync/atomic"
+	"syscall"
+	"unsafe"
+)
+
+// Constants of the proc connector, from linux/connector.h and
+// linux/cn_proc.h.
+const (
+	cnIdxProc         = 1
+	cnValProc         = 1
+	procCnMcastListen = 1
+	procEventExec     = 0x00000002
+	procEventExit     = 0x80000000
+
+	// cnMsgSize is the size of struct cn_msg, and procEventHeaderSize the
+	// size of the what, cpu and timestamp_ns members of struct proc_event
+	// preceding its event data.
+	cnMsgSize           = 20
+	procEventHeaderSize = 16
+)
+
+// nativeEndian is the byte order of the host, in which the kernel writes
+// the proc connector messages.
+var nativeEndian binary.ByteOrder = binary.LittleEndian
+
+func init() {
+	x := uint16(1)
+	if *(*byte)(unsafe.Pointer(&x)) == 0 {
+		nativeEndian = binary.BigEndian
+	}
+}
+
+// connectorSource is a procEventSource listening to the proc connector of
+// the kernel over netlink, which requires CAP_NET_ADMIN.
+type connectorSource struct {
+	fd      int
+	closed  int32
+	buf     []byte
+	pending []procEvent
+}
+
+// openProcEvents subscribes to the exec and exit events of the proc
+// connector.
+func openProcEvents() (procEventSource, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_DGRAM, syscall.NETLINK_CONNECTOR)
+	if err != nil {
+		return nil, fmt.Errorf("netlink socket: %v", err)
+	}
+	s := &connectorSource{fd: fd, buf: make([]byte, syscall.Getpagesize())}
+
+	// Bursts of forks overflow the default receive buffer. The timeout
+	// lets Read notice Close, which does not interrupt a blocked recvfrom.
+	_ = syscall.SetsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_RCVBUF, 1<<20)
+	timeout := syscall.Timeval{Sec: 1}
+	if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &timeout); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("netlink socket: %v", err)
+	}
+	if err := syscall.Bind(fd, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK, Groups: cnIdxProc}); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("proc connector: %v", err)
+	}
+
+	// The subscription is a netlink message holding a cn_msg whose data
+	// is the PROC_CN_MCAST_LISTEN operation.
+	msg := make([]byte, syscall.NLMSG_HDRLEN+cnMsgSize+4)
+	nativeEndian.PutUint32(msg[0:], uint32(len(msg)))
+	nativeEndian.PutUint16(msg[4:], syscall.NLMSG_DONE)
+	cn := msg[syscall.NLMSG_HDRLEN:]
+	nativeEndian.PutUint32(cn[0:], cnIdxProc)
+	nativeEndian.PutUint32(cn[4:], cnValProc)
+	nativeEndian.PutUint16(cn[16:], 4)
+	nativeEndian.PutUint32(cn[cnMsgSize:], procCnMcastListen)
+	if err := syscall.Sendto(fd, msg, 0, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("proc connector: %v", err)
+	}
+	return s, nil
+}
+
+// Read returns the next exec of a program or exit of a process. The
+// overflows of the receive buffer, each losing an unknown number of
+// events, are counted in lost.
+func (s *connectorSource) Read() (procEvent, int, error) {
+	var lost int
+	for len(s.pending) == 0 {
+		if atomic.LoadInt32(&s.closed) != 0 {
+			syscall.Close(s.fd)
+			return procEvent{}, lost, errSourceClosed
+		}
+		n, _, err := syscall.Recvfrom(s.fd, s.buf, 0)
+		switch {
+		case err == syscall.EAGAIN || err == syscall.EINTR:
+			continue
+		case err == syscall.ENOBUFS:
+			lost++
+			continue
+		case err != nil:
+			atomic.StoreInt32(&s.closed, 1)
+			syscall.Close(s.fd)
+			return procEvent{}, lost, err
+		}
+		msgs, err := syscall.ParseNetlinkMessage(s.buf[:n])
+		if err != nil {
+			continue
+		}
+		for _, msg := range msgs {
+			if event, ok := parseProcEvent(msg.Data); ok {
+				s.pending = append(s.pending, event)
+			}
+		}
+	}
+
+	event := s.pending[0]
+	s.pending = s.pending[1:]
+	return event, lost, nil
+}
+
+// Close unsubscribes from the proc connector. The socket is closed by Read,
+// which returns within a second, as closing it would not interrupt a
+// blocked recvfrom and the descriptor could be reused under it.
+func (s *connectorSource) Close() error {
+	atomic.StoreInt32(&s.closed, 1)
+	return nil
+}
+
+// parseProcEvent returns the event described by the data of a proc
+// connector message, or false for the events other than the exec of a
+// program and the exit of a whole process.
+func parseProcEvent(data []byte) (procEvent, bool) {
+	// The event data starts with the pid and tgid of the process.
+	const offset = cnMsgSize + procEventHeaderSize
+	if len(data) < offset+8 {
+		return procEvent{}, false
+	}
+	what := nativeEndian.Uint32(data[cnMsgSize:])
+	pid := int(nativeEndian.Uint32(data[offset:]))
+	tgid := int(nativeEndian.Uint32(data[offset+4:]))
+
+	switch {
+	case what == procEventExec:
+		return procEvent{exec: true, pid: tgid}, true
+	case what == procEventExit && pid == tgid:
+		return procEvent{pid: pid}, true
+	}
+	return procEvent{}, false
+}
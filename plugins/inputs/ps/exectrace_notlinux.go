@@ -0,0 +1,12 @@
+//go:build !linux
+// +build !linux
+
+package ps
+
+import "errors"
+
+// openProcEvents fails, as only Linux reports the exec and exit events of
+// processes.
+func openProcEvents() (procEventSource, error) {
+	return nil, errors.New("exec tracing is only supported on Linux")
+}
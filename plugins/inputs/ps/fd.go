@@ -0,0 +1,29 @@
+package ps
+
+// nofileLimit is the name /proc/<pid>/limits gives to the limit on open
+// file descriptors.
+const nofileLimit = `Max open files`
+
+// fdFields returns the number of open file descriptors of process pid and,
+// with the fd_limits option, its soft and hard limit on them. An unlimited
+// limit is reported as -1. Processes whose descriptors cannot be listed,
+// such as those of other users, yield no fields and are recorded in denied.
+func (p *PS) fdFields(pid int, denied *denials) map[string]interface{} {
+	fields := make(map[string]interface{})
+	if p.readFDs {
+		count, err := p.procFS.CountFDs(pid)
+		if err == nil {
+			fields["fd_count"] = count
+		}
+		denied.check("fd", err)
+	}
+	if p.readFDLimits {
+		limits, err := p.procFS.ReadLimits(pid)
+		if limit, ok := limits[nofileLimit]; ok && err == nil {
+			fields["fd_limit_soft"] = limit.Soft
+			fields["fd_limit_hard"] = limit.Hard
+		}
+		denied.check("fd_limits", err)
+	}
+	return fields
+}
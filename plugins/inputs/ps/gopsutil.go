@@ -0,0 +1,175 @@
+package ps
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"time"
+
+	"github.com/gpapag/telegraf-plugins/pkg/psinfo"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+const backendGopsutil = `gopsutil`
+
+// gopsutilCollector is a ProcessCollector reading the process table through
+// gopsutil, which wraps the process APIs of each operating system, such as
+// those of Windows, where there is no ps command.
+type gopsutilCollector struct {
+	pool      workerPool
+	userNames bool
+	uids      bool
+}
+
+func init() {
+	addCollector(backendGopsutil, newGopsutilCollector)
+}
+
+// newGopsutilCollector returns a new gopsutilCollector configured from p.
+// Windows has no numeric user ids, so only users by name are reported
+// there.
+func newGopsutilCollector(p *PS) (ProcessCollector, error) {
+	uids := p.UserIdentity != userIdentityName
+	if uids && runtime.GOOS == "windows" {
+		return nil, fmt.Errorf("user_identity %q needs numeric user ids, which Windows lacks", p.UserIdentity)
+	}
+	return &gopsutilCollector{
+		pool:      p.pool,
+		userNames: p.UserIdentity != userIdentityUID,
+		uids:      uids,
+	}, nil
+}
+
+// Select lists the parent, command, arguments and owner of every process.
+// Processes exiting while they are read are left out.
+func (c *gopsutilCollector) Select(deadline time.Time) ([]psinfo.Process, error) {
+	pids, err := process.Pids()
+	if err != nil {
+		return nil, err
+	}
+
+	processes := make([]psinfo.Process, len(pids))
+	read := make([]bool, len(pids))
+	err = c.pool.forEach(len(pids), deadline, func(i int) {
+		processes[i], read[i] = c.selectProcess(pids[i])
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	selected := processes[:0]
+	for i, process := range processes {
+		if read[i] {
+			selected = append(selected, process)
+		}
+	}
+	return selected, nil
+}
+
+// selectProcess returns the attributes read by Select of process pid, or
+// false if the process exited.
+func (c *gopsutilCollector) selectProcess(pid int32) (psinfo.Process, bool) {
+	proc, err := process.NewProcess(pid)
+	if err != nil {
+		return psinfo.Process{}, false
+	}
+	name, err := proc.Name()
+	if err != nil {
+		return psinfo.Process{}, false
+	}
+	// Reading the command line of the processes of other users, and of the
+	// system processes, is denied.
+	args, err := proc.Cmdline()
+	if err != nil || args == "" {
+		args = "[" + name + "]"
+	}
+
+	ppid, _ := proc.Ppid()
+	p := psinfo.Process{
+		Pid:  int(pid),
+		Ppid: int(ppid),
+		Comm: psinfo.Sanitize(name),
+		Args: psinfo.Sanitize(args),
+	}
+	// A single user is reported, named DOMAIN\user on Windows.
+	if c.userNames {
+		p.Ruser, _ = proc.Username()
+		p.Euser = p.Ruser
+	}
+	// The uids are the real, effective, saved and filesystem ones. A
+	// process whose uids cannot be read is left out rather than reported
+	// as root.
+	if c.uids {
+		uids, err := proc.Uids()
+		if err != nil || len(uids) < 2 {
+			return psinfo.Process{}, false
+		}
+		p.Ruid, p.Euid = int(uids[0]), int(uids[1])
+	}
+	return p, true
+}
+
+// Collect completes the selected processes with their threads, memory and
+// cpu time, computing the cpu percentage the way ps does. Processes that
+// exited since Select are left out.
+func (c *gopsutilCollector) Collect(processes []psinfo.Process, deadline time.Time) ([]psinfo.Process, error) {
+	now := time.Now()
+
+	read := make([]bool, len(processes))
+	err := c.pool.forEach(len(processes), deadline, func(i int) {
+		read[i] = collectProcess(&processes[i], now)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	collected := processes[:0]
+	for i, p := range processes {
+		if read[i] {
+			collected = append(collected, p)
+		}
+	}
+	return collected, nil
+}
+
+// collectProcess completes p with the attributes read by Collect, or
+// returns false if the process exited.
+func collectProcess(p *psinfo.Process, now time.Time) bool {
+	proc, err := process.NewProcess(int32(p.Pid))
+	if err != nil {
+		return false
+	}
+	memory, err := proc.MemoryInfo()
+	if err != nil {
+		return false
+	}
+
+	threads, _ := proc.NumThreads()
+	mem, _ := proc.MemoryPercent()
+	p.Nlwp = int(threads)
+	p.Rss = int(memory.RSS / 1024)
+	p.Vsz = int(memory.VMS / 1024)
+	p.Mem = round1(float64(mem))
+	if times, err := proc.Times(); err == nil {
+		p.CPUTime = times.User + times.System
+	}
+
+	// CreateTime is in milliseconds since the epoch.
+	if created, err := proc.CreateTime(); err == nil && created > 0 {
+		p.Started = created / 1000
+		elapsed := now.Sub(time.Unix(0, created*int64(time.Millisecond))).Seconds()
+		if elapsed > 0 {
+			p.Etimes = int(math.Floor(elapsed))
+			p.CPU = round1(100 * p.CPUTime / elapsed)
+		}
+	}
+	return true
+}
+
+// Unsupported returns the fields gopsutil has no portable equivalent for:
+// the processor a process last ran on, its state and the time spent in it,
+// its scheduling, which Windows expresses as priority classes instead, and
+// its terminal, session and process group in the Unix sense.
+func (c *gopsutilCollector) Unsupported() []string {
+	return []string{"processor", "status", "dstate_duration_s", "nice", "priority", "sched_policy", "tty", "sid", "pgid"}
+}
@@ -0,0 +1,37 @@
+package ps
+
+import (
+	"os"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestGopsutilUIDs(t *testing.T) {
+	p := newPS(nil, systemClock{})
+	p.Backend = backendGopsutil
+	p.UserIdentity = userIdentityUID
+	p.EffectiveUser = true
+
+	var acc testutil.Accumulator
+	err := p.Gather(&acc)
+	if runtime.GOOS == "windows" {
+		if err == nil {
+			t.Fatal("user_identity uid accepted on Windows")
+		}
+		return
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := findProcessMetric(t, p, &acc, strconv.Itoa(os.Getpid()))
+	if uid := strconv.Itoa(os.Getuid()); m.Tags["uid"] != uid {
+		t.Errorf("uid %q, expected %s", m.Tags["uid"], uid)
+	}
+	if euid := strconv.Itoa(os.Geteuid()); m.Tags["effective_uid"] != euid {
+		t.Errorf("effective_uid %q, expected %s", m.Tags["effective_uid"], euid)
+	}
+}
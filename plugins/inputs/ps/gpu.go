@@ -0,0 +1,127 @@
+package ps
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/kballard/go-shellquote"
+)
+
+// gpuFields are the per_process fields read from nvidia-smi.
+var gpuFields = []string{"gpu_memory_mib", "gpu_utilization", "gpu_memory_utilization"}
+
+// gpuUsage holds the usage of the NVIDIA GPUs by a process, summed over the
+// GPUs it runs on.
+type gpuUsage struct {
+	memory int64 // MiB
+
+	// utilization and memoryUtilization are the percentage of time the
+	// process kept the streaming multiprocessors and the memory of the
+	// GPUs busy over the latest sampling period of the driver.
+	utilization       float64
+	memoryUtilization float64
+	utilizationKnown  bool
+}
+
+// fields returns the per_process fields of u.
+func (u *gpuUsage) fields() map[string]interface{} {
+	fields := map[string]interface{}{
+		"gpu_memory_mib": u.memory,
+	}
+	if u.utilizationKnown {
+		fields["gpu_utilization"] = u.utilization
+		fields["gpu_memory_utilization"] = u.memoryUtilization
+	}
+	return fields
+}
+
+// gpuUsages returns the usage of the GPUs by the processes running compute
+// work on them, by pid, as reported by nvidia-smi. The memory is listed by
+// --query-compute-apps, while the utilization is sampled by pmon.
+func (p *PS) gpuUsages() (map[int]*gpuUsage, error) {
+	query := shellquote.Join(p.NvidiaSmiPath, "--query-compute-apps=pid,used_memory", "--format=csv,noheader,nounits")
+	out, err := p.runner.Run(query, p.Timeout.Duration)
+	if err != nil {
+		return nil, err
+	}
+	usages := make(map[int]*gpuUsage)
+	for _, line := range strings.Split(string(out), "\n") {
+		// Lines look like "12345, 1024"; the memory is "[N/A]" when the
+		// driver cannot tell it, as under some virtualized GPUs.
+		parts := strings.Split(line, ",")
+		if len(parts) != 2 {
+			continue
+		}
+		pid, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+		if err != nil {
+			continue
+		}
+		usage, ok := usages[pid]
+		if !ok {
+			usage = &gpuUsage{}
+			usages[pid] = usage
+		}
+		if memory, err := strconv.ParseInt(strings.TrimSpace(parts[1]), 10, 64); err == nil {
+			usage.memory += memory
+		}
+	}
+	if len(usages) == 0 {
+		return usages, nil
+	}
+
+	pmon := shellquote.Join(p.NvidiaSmiPath, "pmon", "-c", "1", "-s", "u")
+	out, err = p.runner.Run(pmon, p.Timeout.Duration)
+	if err != nil {
+		return nil, err
+	}
+	addGPUUtilization(usages, string(out))
+	return usages, nil
+}
+
+// addGPUUtilization adds to usages the utilization sampled by nvidia-smi
+// pmon, whose output starts with a header naming the columns, such as
+// "# gpu pid type sm mem enc dec command", and shows "-" for the values
+// unknown. Only the processes already in usages are kept.
+func addGPUUtilization(usages map[int]*gpuUsage, out string) {
+	columns := make(map[string]int)
+	for _, line := range strings.Split(out, "\n") {
+		parts := strings.Fields(line)
+		if len(parts) == 0 {
+			continue
+		}
+		if parts[0] == "#" {
+			// The second header line gives the units.
+			if len(columns) == 0 {
+				for i, name := range parts[1:] {
+					columns[name] = i
+				}
+			}
+			continue
+		}
+		pidColumn, okPid := columns["pid"]
+		smColumn, okSM := columns["sm"]
+		memColumn, okMem := columns["mem"]
+		if !okPid || !okSM || !okMem || len(parts) <= smColumn || len(parts) <= memColumn {
+			continue
+		}
+		pid, err := strconv.Atoi(parts[pidColumn])
+		if err != nil {
+			continue
+		}
+		usage, ok := usages[pid]
+		if !ok {
+			continue
+		}
+		sm, err := strconv.ParseFloat(parts[smColumn], 64)
+		if err != nil {
+			continue
+		}
+		mem, err := strconv.ParseFloat(parts[memColumn], 64)
+		if err != nil {
+			continue
+		}
+		usage.utilization += sm
+		usage.memoryUtilization += mem
+		usage.utilizationKnown = true
+	}
+}
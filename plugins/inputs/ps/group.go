@@ -0,0 +1,104 @@
+package ps
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gpapag/telegraf-plugins/pkg/psinfo"
+
+	"github.com/influxdata/telegraf"
+)
+
+// Groupings accepted by the group_by option.
+const (
+	groupByNone = ``
+	groupByComm = `comm`
+	groupByUser = `user`
+)
+
+// processGroup holds the totals of the processes sharing a group key.
+type processGroup struct {
+	processes int
+	threads   int
+	rss       int
+	vsz       int
+	mem       float64
+	cpu       float64
+	cpuUsage  float64
+
+	// cpuUsageKnown tells whether the usage over the interval of any
+	// process of the group is known.
+	cpuUsageKnown bool
+}
+
+// groupKey returns the tag and the value process is grouped by with the
+// group_by option. Users are grouped by uid when user_identity leaves their
+// name out.
+func (p *PS) groupKey(process psinfo.Process) (string, string) {
+	switch {
+	case p.GroupBy == groupByComm:
+		return "comm", process.Comm
+	case p.UserIdentity == userIdentityUID:
+		return "uid", strconv.Itoa(process.Ruid)
+	default:
+		return "user", process.Ruser
+	}
+}
+
+// add adds process to the totals of g.
+func (g *processGroup) add(process psinfo.Process, cpuUsage map[int]float64) {
+	g.processes++
+	g.threads += process.Nlwp
+	g.rss += process.Rss
+	g.vsz += process.Vsz
+	g.mem += process.Mem
+	g.cpu += process.CPU
+	if usage, ok := cpuUsage[process.Pid]; ok {
+		g.cpuUsage += usage
+		g.cpuUsageKnown = true
+	}
+}
+
+// groupFields returns the fields of the metric of group.
+func (p *PS) groupFields(group *processGroup) map[string]interface{} {
+	fields := map[string]interface{}{
+		"processes": group.processes,
+		"threads":   group.threads,
+		"rss":       group.rss,
+		"vsz":       group.vsz,
+		"mem":       round1(group.mem),
+		"cpu":       round1(group.cpu),
+	}
+	if group.cpuUsageKnown {
+		fields["cpu_usage_interval"] = round1(group.cpuUsage)
+	}
+	for _, field := range p.unsupported {
+		delete(fields, field)
+	}
+	return fields
+}
+
+// addGroups stores in acc one metric per group of processes, as selected
+// by the group_by option, with the totals of the group.
+func (p *PS) addGroups(acc telegraf.Accumulator, processes []psinfo.Process, cpuUsage map[int]float64, now time.Time) {
+	var key string
+	groups := make(map[string]*processGroup)
+	for _, process := range processes {
+		var value string
+		key, value = p.groupKey(process)
+		group, ok := groups[value]
+		if !ok {
+			group = &processGroup{}
+			groups[value] = group
+		}
+		group.add(process, cpuUsage)
+	}
+
+	for value, group := range groups {
+		tags := map[string]string{
+			"plugin": tag,
+			key:      value,
+		}
+		acc.AddFields(p.GroupMeasurement, p.groupFields(group), tags, now)
+	}
+}
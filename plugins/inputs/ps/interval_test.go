@@ -0,0 +1,133 @@
+package ps
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/gpapag/telegraf-plugins/pkg/psinfo/psinfotest"
+)
+
+// intervalProcess returns a process stuck in uninterruptible sleep, which
+// has used cpu ticks and taken faults and context switches.
+func intervalProcess(ticks, faults, switches uint64) psinfotest.Process {
+	return psinfotest.Process{
+		Pid: 4242, Ppid: 1, Comm: "sync", Args: []string{"sync"},
+		State: "D", StartTime: 90000,
+		Utime: ticks, Minflt: faults, VoluntaryCtxtSwitches: switches,
+	}
+}
+
+// intervalFields are the fields of the process of intervalProcess that
+// depend on the previous gathers.
+var intervalFields = []string{
+	"cpu_usage_interval",
+	"minflt_delta",
+	"voluntary_ctxt_switches_delta",
+	"dstate_duration_s",
+}
+
+// gatherInterval runs a gather of p and returns the fields of intervalFields
+// of process 4242 it reported.
+func gatherInterval(t *testing.T, p *PS) map[string]interface{} {
+	t.Helper()
+	var acc testutil.Accumulator
+	if err := p.Gather(&acc); err != nil {
+		t.Fatal(err)
+	}
+	if len(acc.Errors) > 0 {
+		t.Fatalf("errors: %v", acc.Errors)
+	}
+	m := findProcessMetric(t, p, &acc, "4242")
+	fields := make(map[string]interface{})
+	for _, name := range intervalFields {
+		if value, ok := m.Fields[name]; ok {
+			fields[name] = value
+		}
+	}
+	return fields
+}
+
+func TestGatherIntervals(t *testing.T) {
+	proc := psinfotest.NewProc(t, testHost)
+	clock := newFakeClock()
+	p := newPS(nil, clock)
+	p.Backend = backendProcFS
+	p.ProcRoot = proc.Root
+	p.ProcFields = []string{procFieldsCtxtSwitches, procFieldsFaults}
+
+	steps := []struct {
+		name     string
+		advance  time.Duration
+		process  psinfotest.Process
+		expected map[string]interface{}
+	}{
+		{
+			name:    "first gather",
+			process: intervalProcess(100, 1000, 10),
+			expected: map[string]interface{}{
+				"dstate_duration_s": int64(0),
+			},
+		},
+		{
+			name:    "second gather",
+			advance: 10 * time.Second,
+			process: intervalProcess(350, 1040, 25),
+			expected: map[string]interface{}{
+				"cpu_usage_interval":            25.0,
+				"minflt_delta":                  int64(40),
+				"voluntary_ctxt_switches_delta": int64(15),
+				"dstate_duration_s":             int64(10),
+			},
+		},
+		{
+			// A gather whose time precedes that of the latest gather, as
+			// when overtaken by the next one, has nothing to compare with.
+			name:     "overtaken gather",
+			advance:  -5 * time.Second,
+			process:  intervalProcess(400, 1050, 30),
+			expected: map[string]interface{}{},
+		},
+		{
+			// The overtaken gather left the samples of the second one.
+			name:    "third gather",
+			advance: 25 * time.Second,
+			process: intervalProcess(550, 1100, 40),
+			expected: map[string]interface{}{
+				"cpu_usage_interval":            10.0,
+				"minflt_delta":                  int64(60),
+				"voluntary_ctxt_switches_delta": int64(15),
+				"dstate_duration_s":             int64(30),
+			},
+		},
+		{
+			// A process reusing the pid starts over.
+			name:    "pid reused",
+			advance: 10 * time.Second,
+			process: func() psinfotest.Process {
+				process := intervalProcess(10, 10, 1)
+				process.Comm, process.Args = "flush", []string{"flush"}
+				return process
+			}(),
+			expected: map[string]interface{}{
+				"dstate_duration_s": int64(0),
+			},
+		},
+	}
+
+	for _, step := range steps {
+		clock.advance(step.advance)
+		proc.Add(step.process)
+		fields := gatherInterval(t, p)
+		if len(fields) != len(step.expected) {
+			t.Errorf("%s: fields %v, expected %v", step.name, fields, step.expected)
+			continue
+		}
+		for name, value := range step.expected {
+			if fields[name] != value {
+				t.Errorf("%s: field %s %#v, expected %#v", step.name, name, fields[name], value)
+			}
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package ps
+
+// ioFields are the per_process fields read from /proc/<pid>/io.
+var ioFields = []string{"read_bytes", "write_bytes", "syscr", "syscw"}
+
+// ioCounters returns the I/O counter fields of process pid. Processes whose
+// counters cannot be read, such as those of other users when telegraf lacks
+// CAP_SYS_PTRACE, yield no fields and are recorded in denied.
+func (p *PS) ioCounters(pid int, denied *denials) map[string]interface{} {
+	if !p.readIO {
+		return nil
+	}
+
+	counters, err := p.procFS.ReadIO(pid)
+	if err != nil {
+		denied.check("io", err)
+		return nil
+	}
+
+	return map[string]interface{}{
+		"read_bytes":  int64(counters.ReadBytes),
+		"write_bytes": int64(counters.WriteBytes),
+		"syscr":       int64(counters.Syscr),
+		"syscw":       int64(counters.Syscw),
+	}
+}
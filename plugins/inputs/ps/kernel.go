@@ -0,0 +1,33 @@
+package ps
+
+import "github.com/gpapag/telegraf-plugins/pkg/psinfo"
+
+// kthreadd is the command of the Linux kernel thread spawning every other
+// kernel thread.
+const kthreadd = `kthreadd`
+
+// excludeKernelThreads returns processes without the kernel threads:
+// kthreadd, which has no parent, and its children. Matching the parent
+// rather than the bracketed command ps shows for processes without a
+// command line keeps the zombies of user processes, which have none
+// either.
+func excludeKernelThreads(processes []psinfo.Process) []psinfo.Process {
+	kernel := make(map[int]bool)
+	for _, process := range processes {
+		if process.Comm == kthreadd && process.Ppid == 0 {
+			kernel[process.Pid] = true
+		}
+	}
+	if len(kernel) == 0 {
+		return processes
+	}
+
+	var selected []psinfo.Process
+	for _, process := range processes {
+		if kernel[process.Pid] || kernel[process.Ppid] {
+			continue
+		}
+		selected = append(selected, process)
+	}
+	return selected
+}
@@ -0,0 +1,143 @@
+package ps
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// podRefreshInterval is the minimum delay between two listings of the pods
+// of the kubelet, which are only listed again when an unknown pod shows up.
+const podRefreshInterval = time.Minute
+
+// pod identifies a Kubernetes pod.
+type pod struct {
+	name      string
+	namespace string
+}
+
+// podList is the pod list returned by the /pods endpoint of the kubelet.
+type podList struct {
+	Items []struct {
+		Metadata struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+			UID       string `json:"uid"`
+		} `json:"metadata"`
+	} `json:"items"`
+}
+
+// kubeletClient lists the pods running on the node through the kubelet
+// API.
+type kubeletClient struct {
+	url       string
+	tokenFile string
+	http      *http.Client
+}
+
+// newKubeletClient returns a client for the kubelet API at url,
+// authenticating with the bearer token of tokenFile when set, and checking
+// the kubelet certificate against the authorities of caFile when set.
+func newKubeletClient(url, tokenFile, caFile string, insecureSkipVerify bool, timeout time.Duration) (*kubeletClient, error) {
+	config := &tls.Config{InsecureSkipVerify: insecureSkipVerify}
+	if caFile != "" {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate in %s", caFile)
+		}
+	}
+
+	return &kubeletClient{
+		url:       strings.TrimSuffix(url, "/"),
+		tokenFile: tokenFile,
+		http: &http.Client{
+			Timeout:   timeout,
+			Transport: &http.Transport{TLSClientConfig: config},
+		},
+	}, nil
+}
+
+// pods returns the pods running on the node by uid. The token is read at
+// every call, as service account tokens are rotated.
+func (c *kubeletClient) pods() (map[string]pod, error) {
+	req, err := http.NewRequest(http.MethodGet, c.url+"/pods", nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.tokenFile != "" {
+		token, err := ioutil.ReadFile(c.tokenFile)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("/pods: %s", resp.Status)
+	}
+
+	var list podList
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("/pods: unable to parse response: %s", err)
+	}
+	pods := make(map[string]pod, len(list.Items))
+	for _, item := range list.Items {
+		pods[item.Metadata.UID] = pod{name: item.Metadata.Name, namespace: item.Metadata.Namespace}
+	}
+	return pods, nil
+}
+
+// lookupPod returns the pod of uid, listing the pods of the kubelet again
+// if uid is unknown and the list was not refreshed recently. The kubelet is
+// queried without holding p.mu, so that overlapping gathers looking up
+// known pods do not wait on it, and by one gather at a time, the others
+// using the list it got. The new list replaces the previous one, so that
+// the pods deleted since are forgotten.
+func (p *PS) lookupPod(uid string, now time.Time) (pod, bool, error) {
+	if found, ok, recent := p.cachedPod(uid, now); ok || recent {
+		return found, ok, nil
+	}
+
+	p.podsMu.Lock()
+	defer p.podsMu.Unlock()
+	if found, ok, recent := p.cachedPod(uid, now); ok || recent {
+		return found, ok, nil
+	}
+
+	pods, err := p.kubelet.pods()
+	p.mu.Lock()
+	p.podsAt = now
+	if err == nil {
+		p.pods = pods
+	}
+	p.mu.Unlock()
+	if err != nil {
+		return pod{}, false, err
+	}
+	found, ok := pods[uid]
+	return found, ok, nil
+}
+
+// cachedPod returns the pod of uid in the latest list of the pods, and
+// whether that list is recent enough not to be refreshed for an unknown
+// pod.
+func (p *PS) cachedPod(uid string, now time.Time) (found pod, ok, recent bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	found, ok = p.pods[uid]
+	return found, ok, now.Sub(p.podsAt) < podRefreshInterval
+}
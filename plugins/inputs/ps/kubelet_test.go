@@ -0,0 +1,179 @@
+package ps
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/gpapag/telegraf-plugins/pkg/psinfo/psinfotest"
+)
+
+const (
+	testPodUID   = "0b6d7f2e-1c3a-4e5f-8a9b-0c1d2e3f4a5b"
+	testOtherUID = "9f8e7d6c-5b4a-4392-8170-6f5e4d3c2b1a"
+)
+
+// newKubelet returns a kubelet serving the pods of testPodUID and
+// testOtherUID, which calls wait, if set, before every response. It counts
+// the listings in requests.
+func newKubelet(t *testing.T, wait func(), requests *int32) *httptest.Server {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		if wait != nil {
+			wait()
+		}
+		fmt.Fprintf(w, `{"items": [
+			{"metadata": {"name": "web-0", "namespace": "shop", "uid": %q}},
+			{"metadata": {"name": "db-0", "namespace": "shop", "uid": %q}}
+		]}`, testPodUID, testOtherUID)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestLookupPodWhileListing(t *testing.T) {
+	listing := make(chan struct{}, 1)
+	release := make(chan struct{})
+	var requests int32
+	s := newKubelet(t, func() {
+		select {
+		case listing <- struct{}{}:
+		default:
+		}
+		<-release
+	}, &requests)
+
+	p := newPS(nil, systemClock{})
+	p.kubelet = &kubeletClient{url: s.URL, http: s.Client()}
+	p.pods = map[string]pod{"known": {name: "known-0", namespace: "shop"}}
+	now := time.Now()
+
+	type result struct {
+		pod pod
+		ok  bool
+		err error
+	}
+	results := make(chan result, 2)
+	lookup := func(uid string) {
+		found, ok, err := p.lookupPod(uid, now)
+		results <- result{found, ok, err}
+	}
+	go lookup(testPodUID)
+	<-listing
+	go lookup(testOtherUID)
+
+	// While the kubelet lists the pods, the known pods and the state of
+	// the other gathers remain available.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if found, ok, err := p.lookupPod("known", now); !ok || err != nil || found.name != "known-0" {
+			t.Errorf("known pod %v %v %v", found, ok, err)
+		}
+		p.cpuUsage(nil, now)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		close(release)
+		t.Fatal("lookup of a known pod blocked by the listing")
+	}
+
+	close(release)
+	expected := map[string]bool{"web-0": true, "db-0": true}
+	for i := 0; i < 2; i++ {
+		r := <-results
+		if r.err != nil || !r.ok || !expected[r.pod.name] {
+			t.Errorf("pod %v %v %v", r.pod, r.ok, r.err)
+		}
+		delete(expected, r.pod.name)
+	}
+	// The second lookup waited for the list of the first.
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("%d listings, expected 1", n)
+	}
+}
+
+func TestLookupPodRefresh(t *testing.T) {
+	var requests int32
+	s := newKubelet(t, nil, &requests)
+
+	// The list of the pods predates the deletion of the pod of staleUID.
+	const staleUID = "3c2b1a09-8f7e-4d6c-9b5a-4e3d2c1b0a9f"
+	p := newPS(nil, systemClock{})
+	p.kubelet = &kubeletClient{url: s.URL, http: s.Client()}
+	now := time.Now()
+	p.pods = map[string]pod{staleUID: {name: "job-0", namespace: "batch"}}
+	p.podsAt = now.Add(-2 * podRefreshInterval)
+
+	if found, ok, err := p.lookupPod(testPodUID, now); err != nil || !ok || found.name != "web-0" {
+		t.Fatalf("pod %v %v %v", found, ok, err)
+	}
+	if len(p.pods) != 2 {
+		t.Errorf("pods %v, expected those of the kubelet only", p.pods)
+	}
+	// The stale pod is unknown, without listing the pods again.
+	if found, ok, err := p.lookupPod(staleUID, now.Add(time.Second)); err != nil || ok {
+		t.Errorf("stale pod %v %v %v", found, ok, err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("%d listings, expected 1", n)
+	}
+}
+
+func TestGatherOverlapping(t *testing.T) {
+	var requests int32
+	s := newKubelet(t, nil, &requests)
+
+	proc := newTestProc(t)
+	proc.Add(psinfotest.Process{
+		Pid: 5000, Ppid: 1, Comm: "nginx", Args: []string{"nginx"},
+		StartTime: 95000, Utime: 10,
+		Cgroup: "/kubepods/burstable/pod" + testPodUID + "/" + testContainerID,
+	})
+
+	p := newPS(nil, systemClock{})
+	p.Backend = backendProcFS
+	p.ProcRoot = proc.Root
+	p.ContainerTags = true
+	p.PodTags = true
+	p.KubeletURL = s.URL
+	p.KubeletTokenFile = ""
+
+	// The gathers share the collector and the samples of the cpu usage,
+	// counters, D state durations and pods.
+	const gathers = 8
+	var wg sync.WaitGroup
+	accs := make([]testutil.Accumulator, gathers)
+	for i := range accs {
+		wg.Add(1)
+		go func(acc *testutil.Accumulator) {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				if err := p.Gather(acc); err != nil {
+					t.Error(err)
+				}
+			}
+		}(&accs[i])
+	}
+	wg.Wait()
+
+	for i := range accs {
+		if len(accs[i].Errors) > 0 {
+			t.Fatalf("errors: %v", accs[i].Errors)
+		}
+		m := findProcessMetric(t, p, &accs[i], "5000")
+		if m.Tags["pod_name"] != "web-0" || m.Tags["pod_namespace"] != "shop" {
+			t.Errorf("tags %v", m.Tags)
+		}
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("%d listings, expected 1", n)
+	}
+}
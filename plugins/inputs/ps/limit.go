@@ -0,0 +1,61 @@
+package ps
+
+import (
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// limitedAccumulator forwards metrics to an accumulator as long as the total
+// number of fields stays within a budget, and counts what it drops.
+type limitedAccumulator struct {
+	telegraf.Accumulator
+
+	remaining      int
+	droppedMetrics int
+	droppedFields  int
+}
+
+// newLimitedAccumulator returns a pointer to a new limitedAccumulator that
+// passes at most maxFields fields to acc.
+func newLimitedAccumulator(acc telegraf.Accumulator, maxFields int) *limitedAccumulator {
+	return &limitedAccumulator{Accumulator: acc, remaining: maxFields}
+}
+
+// AddFields adds a metric if the fields fit in the remaining budget.
+func (a *limitedAccumulator) AddFields(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	if a.allow(fields) {
+		a.Accumulator.AddFields(measurement, fields, tags, t...)
+	}
+}
+
+// AddGauge adds a gauge if the fields fit in the remaining budget.
+func (a *limitedAccumulator) AddGauge(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	if a.allow(fields) {
+		a.Accumulator.AddGauge(measurement, fields, tags, t...)
+	}
+}
+
+// AddCounter adds a counter if the fields fit in the remaining budget.
+func (a *limitedAccumulator) AddCounter(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	if a.allow(fields) {
+		a.Accumulator.AddCounter(measurement, fields, tags, t...)
+	}
+}
+
+// allow charges fields to the budget, or records them as dropped when they
+// do not fit.
+func (a *limitedAccumulator) allow(fields map[string]interface{}) bool {
+	if len(fields) > a.remaining {
+		a.droppedMetrics++
+		a.droppedFields += len(fields)
+		return false
+	}
+	a.remaining -= len(fields)
+	return true
+}
+
+// truncated reports whether any metric was dropped.
+func (a *limitedAccumulator) truncated() bool {
+	return a.droppedMetrics > 0
+}
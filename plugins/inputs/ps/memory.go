@@ -0,0 +1,44 @@
+package ps
+
+import (
+	"strconv"
+
+	"github.com/gpapag/telegraf-plugins/pkg/psinfo"
+)
+
+// numaFieldPrefix and numaFieldSuffix surround the number of the node in
+// the names of the fields holding the memory of a process on a NUMA node.
+const (
+	numaFieldPrefix = `numa_node`
+	numaFieldSuffix = `_kb`
+)
+
+// memoryFields returns the memory usage fields of process read from /proc.
+// Processes whose memory cannot be read yield no fields, and those denied
+// are recorded in denied; kernel threads have no VmSwap line and report no
+// swap.
+func (p *PS) memoryFields(process psinfo.Process, denied *denials) map[string]interface{} {
+	pid := process.Pid
+	fields := make(map[string]interface{})
+	if p.readSwap && process.ProcStatus != nil {
+		fields["swap_kb"] = process.ProcStatus.VmSwap
+	}
+	if p.readSmaps {
+		// The unique set size is the memory freed if the process exited:
+		// its pages shared with no other process.
+		smaps, err := p.procFS.ReadSmapsRollup(pid)
+		if err == nil {
+			fields["pss_kb"] = smaps.Pss
+			fields["uss_kb"] = smaps.PrivateClean + smaps.PrivateDirty
+		}
+		denied.check("smaps", err)
+	}
+	if p.readNUMA {
+		nodes, err := p.procFS.ReadNumaMaps(pid)
+		for node, kb := range nodes {
+			fields[numaFieldPrefix+strconv.Itoa(node)+numaFieldSuffix] = kb
+		}
+		denied.check("numa", err)
+	}
+	return fields
+}
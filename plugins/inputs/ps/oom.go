@@ -0,0 +1,20 @@
+package ps
+
+// oomFields returns the badness score of process pid, telling how likely
+// the kernel is to kill it when the host runs out of memory, and the
+// adjustment applied to that score. Processes whose scores cannot be read
+// yield no fields.
+func (p *PS) oomFields(pid int) map[string]interface{} {
+	fields := make(map[string]interface{})
+	if p.readOOMScore {
+		if score, err := p.procFS.ReadOOMScore(pid); err == nil {
+			fields["oom_score"] = score
+		}
+	}
+	if p.readOOMScoreAdj {
+		if adj, err := p.procFS.ReadOOMScoreAdj(pid); err == nil {
+			fields["oom_score_adj"] = adj
+		}
+	}
+	return fields
+}
@@ -0,0 +1,72 @@
+package ps
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gpapag/telegraf-plugins/pkg/psinfo"
+
+	"github.com/influxdata/telegraf"
+)
+
+// processNames returns the logical name of each process whose pid is read
+// from the pid_file and pid_files options, by pid. The process of pid_file
+// is named after process_name; the others, and pid_file without
+// process_name, after the base name of their pid file without extension.
+// Pid files that cannot be read are reported to acc and skipped.
+func (p *PS) processNames(acc telegraf.Accumulator) map[int]string {
+	files := make(map[string]string)
+	for _, file := range p.PidFiles {
+		files[file] = pidFileName(file)
+	}
+	if p.PidFile != "" {
+		files[p.PidFile] = p.ProcessName
+		if p.ProcessName == "" {
+			files[p.PidFile] = pidFileName(p.PidFile)
+		}
+	}
+
+	names := make(map[int]string, len(files))
+	for file, name := range files {
+		pid, err := readPidFile(file)
+		if err != nil {
+			acc.AddError(p.errorf("pid file: %w", err))
+			continue
+		}
+		names[pid] = name
+	}
+	return names
+}
+
+// readPidFile returns the pid written in file.
+func readPidFile(file string) (int, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return 0, err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, err
+	}
+	return pid, nil
+}
+
+// pidFileName returns the name of the process of a pid file, such as nginx
+// for /var/run/nginx.pid.
+func pidFileName(file string) string {
+	base := filepath.Base(file)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// selectPids returns the processes whose pid is a key of pids.
+func selectPids(pids map[int]string, processes []psinfo.Process) []psinfo.Process {
+	var selected []psinfo.Process
+	for _, process := range processes {
+		if _, ok := pids[process.Pid]; ok {
+			selected = append(selected, process)
+		}
+	}
+	return selected
+}
@@ -0,0 +1,67 @@
+package ps
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gpapag/telegraf-plugins/pkg/psinfo"
+)
+
+// workerPool spreads the work done for every process of a gather, such as
+// reading its files under /proc, over several goroutines.
+type workerPool struct {
+	workers int
+	clock   Clock
+}
+
+// forEach calls fn with every index below n, from up to w.workers
+// goroutines at once. Once deadline has passed no further call is started,
+// and an error wrapping psinfo.ErrTimeout is returned when the calls in
+// progress are done; a zero deadline never passes. fn must only write to
+// the data of the index it is given.
+func (w workerPool) forEach(n int, deadline time.Time, fn func(i int)) error {
+	var (
+		mu      sync.Mutex
+		next    int
+		expired bool
+	)
+	take := func() (int, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		if next >= n || expired {
+			return 0, false
+		}
+		if !deadline.IsZero() && w.clock.Now().After(deadline) {
+			expired = true
+			return 0, false
+		}
+		next++
+		return next - 1, true
+	}
+
+	workers := w.workers
+	if workers > n {
+		workers = n
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i, ok := take()
+				if !ok {
+					return
+				}
+				fn(i)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if expired {
+		return fmt.Errorf("%w: only %d of %d processes read in time", psinfo.ErrTimeout, next, n)
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package ps
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/gpapag/telegraf-plugins/pkg/psinfo"
+)
+
+// sudoCommand prefixes the ps command run with the use_sudo option. sudo
+// fails rather than prompting for a password.
+const sudoCommand = `sudo -n`
+
+// permissionField is the per_process field naming the groups of fields of
+// a process left out for lack of privileges.
+const permissionField = `permission_denied`
+
+// denials collects the groups of per_process fields of a process that could
+// not be read for lack of privileges, such as "io" or "smaps".
+type denials []string
+
+// check records group as denied when err tells of missing privileges.
+func (d *denials) check(group string, err error) {
+	if !errors.Is(err, psinfo.ErrPermission) {
+		return
+	}
+	for _, denied := range *d {
+		if denied == group {
+			return
+		}
+	}
+	*d = append(*d, group)
+}
+
+// String returns the denied groups sorted and separated by commas.
+func (d denials) String() string {
+	groups := append([]string(nil), d...)
+	sort.Strings(groups)
+	return strings.Join(groups, ",")
+}
@@ -0,0 +1,67 @@
+package ps
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gpapag/telegraf-plugins/pkg/psinfo"
+)
+
+// Groups of per_process fields read from /proc accepted by the proc_fields
+// option.
+const (
+	procFieldsIO           = `io`
+	procFieldsFDCount      = `fd_count`
+	procFieldsCtxtSwitches = `ctxt_switches`
+	procFieldsFaults       = `faults`
+	procFieldsSwap         = `swap`
+	procFieldsAffinity     = `affinity`
+	procFieldsWchan        = `wchan`
+	procFieldsCapabilities = `capabilities`
+	procFieldsOOM          = `oom`
+	procFieldsExeDeleted   = `exe_deleted`
+)
+
+// procFieldGroups returns the groups of the proc_fields option, after
+// checking their names.
+func (p *PS) procFieldGroups() (map[string]bool, error) {
+	groups := make(map[string]bool, len(p.ProcFields))
+	for _, group := range p.ProcFields {
+		switch group {
+		case procFieldsIO, procFieldsFDCount, procFieldsCtxtSwitches, procFieldsFaults,
+			procFieldsSwap, procFieldsAffinity, procFieldsWchan, procFieldsCapabilities,
+			procFieldsOOM, procFieldsExeDeleted:
+			groups[group] = true
+		default:
+			return nil, fmt.Errorf("unknown group %q", group)
+		}
+	}
+	return groups, nil
+}
+
+// readProcFiles completes processes with the /proc/<pid>/status and
+// /proc/<pid>/stat files the fields of p are taken from, unless the backend
+// already read them, so that each is read once per process and gather.
+// Processes not reached by deadline are left without them, and have none
+// of their fields.
+func (p *PS) readProcFiles(processes []psinfo.Process, deadline time.Time) error {
+	readStatus := p.readCtxtSwitches || p.readSwap || p.readAffinity || p.readCapabilities
+	readStat := p.readFaults
+	if !readStatus && !readStat {
+		return nil
+	}
+
+	return p.pool.forEach(len(processes), deadline, func(i int) {
+		process := &processes[i]
+		if readStatus && process.ProcStatus == nil {
+			if status, err := p.procFS.ReadStatus(process.Pid); err == nil {
+				process.ProcStatus = &status
+			}
+		}
+		if readStat && process.ProcStat == nil {
+			if stat, err := p.procFS.ReadStat(process.Pid); err == nil {
+				process.ProcStat = &stat
+			}
+		}
+	})
+}
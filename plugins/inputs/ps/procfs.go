@@ -0,0 +1,210 @@
+package ps
+
+import (
+	"math"
+	"os"
+	"os/user"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gpapag/telegraf-plugins/pkg/psinfo"
+)
+
+const backendProcFS = `procfs`
+
+// procfsCollector is a ProcessCollector reading the proc filesystem
+// directly, without running any command.
+type procfsCollector struct {
+	procFS        psinfo.ProcFS
+	pool          workerPool
+	userNames     bool
+	effectiveUser bool
+
+	// mu guards users, as overlapping gathers share the collector.
+	mu    sync.Mutex
+	users map[int]string
+}
+
+// hostTotals holds the attributes of the host the attributes of its
+// processes are computed from.
+type hostTotals struct {
+	uptime   float64 // seconds
+	memTotal int64   // KiB
+	bootTime int64   // seconds since the epoch
+	pageKiB  int64
+}
+
+func init() {
+	addCollector(backendProcFS, func(p *PS) (ProcessCollector, error) {
+		return &procfsCollector{
+			procFS:        p.procFS,
+			pool:          p.pool,
+			userNames:     p.UserIdentity != userIdentityUID,
+			effectiveUser: p.EffectiveUser,
+			users:         make(map[int]string),
+		}, nil
+	})
+}
+
+// Select reads the parent, command, arguments and users of every process
+// from /proc/<pid>/status and /proc/<pid>/cmdline. Processes exiting while
+// they are read are left out.
+func (c *procfsCollector) Select(deadline time.Time) ([]psinfo.Process, error) {
+	pids, err := c.procFS.Pids()
+	if err != nil {
+		return nil, err
+	}
+
+	processes := make([]psinfo.Process, len(pids))
+	read := make([]bool, len(pids))
+	err = c.pool.forEach(len(pids), deadline, func(i int) {
+		processes[i], read[i] = c.selectProcess(pids[i])
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	selected := processes[:0]
+	for i, process := range processes {
+		if read[i] {
+			selected = append(selected, process)
+		}
+	}
+	return selected, nil
+}
+
+// selectProcess returns the attributes read by Select of process pid, or
+// false if the process exited.
+func (c *procfsCollector) selectProcess(pid int) (psinfo.Process, bool) {
+	status, err := c.procFS.ReadStatus(pid)
+	if err != nil {
+		return psinfo.Process{}, false
+	}
+	args, err := c.procFS.ReadCmdline(pid)
+	if err != nil {
+		return psinfo.Process{}, false
+	}
+	// ps shows kernel threads, which have no command line, by their name
+	// in brackets.
+	if args == "" {
+		args = "[" + status.Name + "]"
+	}
+
+	process := psinfo.Process{
+		Pid:  pid,
+		Ppid: status.Ppid,
+		Comm: status.Name,
+		Args: args,
+		Nlwp: status.Threads,
+		Ruid: status.Ruid,
+		Euid: status.Euid,
+
+		ProcStatus: &status,
+	}
+	if c.userNames {
+		process.Ruser = c.userName(status.Ruid)
+		if c.effectiveUser {
+			process.Euser = c.userName(status.Euid)
+		}
+	}
+	return process, true
+}
+
+// Collect completes the selected processes with the attributes of
+// /proc/<pid>/stat, computing the cpu and memory percentages the way ps
+// does. Processes that exited since Select are left out.
+func (c *procfsCollector) Collect(processes []psinfo.Process, deadline time.Time) ([]psinfo.Process, error) {
+	var host hostTotals
+	var err error
+	if host.uptime, err = c.procFS.ReadUptime(); err != nil {
+		return nil, err
+	}
+	if host.memTotal, err = c.procFS.ReadMemTotal(); err != nil {
+		return nil, err
+	}
+	if host.bootTime, err = c.procFS.ReadBootTime(); err != nil {
+		return nil, err
+	}
+	host.pageKiB = int64(os.Getpagesize() / 1024)
+
+	read := make([]bool, len(processes))
+	err = c.pool.forEach(len(processes), deadline, func(i int) {
+		read[i] = c.collectProcess(&processes[i], host)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	collected := processes[:0]
+	for i, process := range processes {
+		if read[i] {
+			collected = append(collected, process)
+		}
+	}
+	return collected, nil
+}
+
+// collectProcess completes process with the attributes read by Collect, or
+// returns false if the process exited.
+func (c *procfsCollector) collectProcess(process *psinfo.Process, host hostTotals) bool {
+	stat, err := c.procFS.ReadStat(process.Pid)
+	if err != nil {
+		return false
+	}
+
+	process.Ppid = stat.Ppid
+	process.Nlwp = stat.NumThreads
+	process.Rss = int(stat.Rss * host.pageKiB)
+	process.Vsz = int(stat.Vsize / 1024)
+	process.Psr = stat.Processor
+	process.Stat = psinfo.StatCode(stat)
+	process.Nice = stat.Nice
+	process.Priority = stat.Priority
+	process.Policy = psinfo.PolicyName(stat.Policy)
+	process.Tty = psinfo.TtyName(stat.TtyNr)
+	process.Sid = stat.Session
+	process.Pgid = stat.Pgrp
+	process.CPUTime = float64(stat.Utime+stat.Stime) / psinfo.ClockTicks
+	process.Started = host.bootTime + int64(stat.StartTime/psinfo.ClockTicks)
+	process.ProcStat = &stat
+
+	// %cpu is the cpu time used over the lifetime of the process, and %mem
+	// the resident memory over the memory of the host.
+	elapsed := host.uptime - float64(stat.StartTime)/psinfo.ClockTicks
+	if elapsed > 0 {
+		process.Etimes = int(elapsed)
+		process.CPU = round1(100 * process.CPUTime / elapsed)
+	}
+	if host.memTotal > 0 {
+		process.Mem = round1(100 * float64(process.Rss) / float64(host.memTotal))
+	}
+	return true
+}
+
+// userName returns the name of the user uid, or the uid itself if the user
+// is unknown. Names are cached for the lifetime of the collector.
+func (c *procfsCollector) userName(uid int) string {
+	c.mu.Lock()
+	name, ok := c.users[uid]
+	c.mu.Unlock()
+	if ok {
+		return name
+	}
+
+	name = strconv.Itoa(uid)
+	if u, err := user.LookupId(name); err == nil {
+		name = u.Username
+	}
+
+	c.mu.Lock()
+	c.users[uid] = name
+	c.mu.Unlock()
+	return name
+}
+
+// round1 rounds x to one decimal, the precision ps reports percentages
+// with.
+func round1(x float64) float64 {
+	return math.Round(x*10) / 10
+}
@@ -0,0 +1,265 @@
+package ps
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/gpapag/telegraf-plugins/pkg/psinfo"
+	"github.com/gpapag/telegraf-plugins/pkg/psinfo/psinfotest"
+)
+
+// testHost is the host of the synthetic proc filesystems of the tests,
+// booted 1000 seconds ago with 8 GiB of memory.
+var testHost = psinfotest.Host{Uptime: 1000, BootTime: 1600000000, MemTotal: 8 << 20}
+
+// newTestProc returns a synthetic proc filesystem holding init, a kernel
+// thread and a sleep run from a terminal, in uninterruptible sleep.
+func newTestProc(t *testing.T) *psinfotest.Proc {
+	proc := psinfotest.NewProc(t, testHost)
+	proc.Add(psinfotest.Process{
+		Pid: 1, Comm: "systemd", Args: []string{"/sbin/init", "splash"},
+		Pgrp: 1, Session: 1,
+		Utime: 300, Stime: 200, StartTime: 100,
+		Vsize: 160 << 20, Rss: 2048,
+		Cgroup: "/init.scope",
+		FDs:    []string{"/dev/null", "/dev/null", "socket:[1234]"},
+	})
+	proc.Add(psinfotest.Process{
+		Pid: 2, Comm: "kthreadd", StartTime: 100,
+	})
+	proc.Add(psinfotest.Process{
+		Pid: 4242, Ppid: 1, Comm: "sleep", Args: []string{"sleep", "60"},
+		State: "D", Ruid: 1000, Euid: 1000,
+		Pgrp: 4242, Session: 4200, TtyNr: 136<<8 | 3, Tpgid: 4242,
+		Utime: 40, Stime: 10, StartTime: 90000,
+		Vsize: 8 << 20, Rss: 256,
+		IO:     psinfo.IO{ReadBytes: 4096, WriteBytes: 512, Syscr: 7, Syscw: 3},
+		Cgroup: "/system.slice/docker-" + testContainerID + ".scope",
+		FDs:    []string{"/dev/pts/3", "/dev/pts/3", "/dev/pts/3"},
+	})
+	return proc
+}
+
+const testContainerID = "4f3b2c1d0e9f8a7b6c5d4e3f2a1b0c9d8e7f6a5b4c3d2e1f0a9b8c7d6e5f4a3b"
+
+// testDeadline returns the deadline of the reads of the tests, far enough
+// not to be reached.
+func testDeadline() time.Time {
+	return time.Now().Add(time.Minute)
+}
+
+// findProcessMetric returns the per_process metric of process pid gathered
+// by p into acc, failing t if there is none.
+func findProcessMetric(t *testing.T, p *PS, acc *testutil.Accumulator, pid string) *testutil.Metric {
+	t.Helper()
+	for _, m := range acc.Metrics {
+		if m.Measurement == p.DetailMeasurement && m.Tags["pid"] == pid {
+			return m
+		}
+	}
+	t.Fatalf("no metric of process %s", pid)
+	return nil
+}
+
+// newTestProcfsCollector returns a procfsCollector reading proc.
+func newTestProcfsCollector(proc *psinfotest.Proc) *procfsCollector {
+	return &procfsCollector{
+		procFS: proc.ProcFS(),
+		pool:   workerPool{workers: 2, clock: systemClock{}},
+		users:  make(map[int]string),
+	}
+}
+
+func TestProcfsCollector(t *testing.T) {
+	proc := newTestProc(t)
+	c := newTestProcfsCollector(proc)
+
+	selected, err := c.Select(testDeadline())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(selected) != 3 {
+		t.Fatalf("%d processes selected, expected 3", len(selected))
+	}
+	processes, err := c.Collect(selected, testDeadline())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pageKiB := os.Getpagesize() / 1024
+	expected := []psinfo.Process{
+		{
+			Pid: 1, Comm: "systemd", Args: "/sbin/init splash",
+			Nlwp: 1, Rss: 2048 * pageKiB, Vsz: 160 << 10, CPU: 0.5, Mem: round1(100 * float64(2048*pageKiB) / float64(testHost.MemTotal)),
+			Stat: "Ss", Etimes: 999, CPUTime: 5, Started: testHost.BootTime + 1,
+			Priority: 20, Policy: "SCHED_OTHER", Sid: 1, Pgid: 1,
+		},
+		{
+			Pid: 2, Comm: "kthreadd", Args: "[kthreadd]",
+			Nlwp: 1, Stat: "S", Etimes: 999, Started: testHost.BootTime + 1,
+			Priority: 20, Policy: "SCHED_OTHER",
+		},
+		{
+			Pid: 4242, Ppid: 1, Comm: "sleep", Args: "sleep 60",
+			Nlwp: 1, Rss: 256 * pageKiB, Vsz: 8 << 10, CPU: 0.5, Mem: round1(100 * float64(256*pageKiB) / float64(testHost.MemTotal)),
+			Ruid: 1000, Euid: 1000,
+			Stat: "D+", Etimes: 100, CPUTime: 0.5, Started: testHost.BootTime + 900,
+			Priority: 20, Policy: "SCHED_OTHER", Tty: "pts/3", Sid: 4200, Pgid: 4242,
+		},
+	}
+	if len(processes) != len(expected) {
+		t.Fatalf("%d processes collected, expected %d", len(processes), len(expected))
+	}
+	for i, process := range processes {
+		// The status and stat files read are kept for the fields of
+		// proc_fields.
+		if process.ProcStatus == nil || process.ProcStat == nil {
+			t.Errorf("process %d: status %v, stat %v", i, process.ProcStatus, process.ProcStat)
+		}
+		process.ProcStatus, process.ProcStat = nil, nil
+		if !reflect.DeepEqual(process, expected[i]) {
+			t.Errorf("process %d:\n got %+v\nwant %+v", i, process, expected[i])
+		}
+	}
+}
+
+func TestProcfsCollectorExited(t *testing.T) {
+	proc := newTestProc(t)
+	c := newTestProcfsCollector(proc)
+
+	selected, err := c.Select(testDeadline())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The process exits between Select and Collect.
+	proc.Remove(4242)
+	processes, err := c.Collect(selected, testDeadline())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var pids []int
+	for _, process := range processes {
+		pids = append(pids, process.Pid)
+	}
+	if !reflect.DeepEqual(pids, []int{1, 2}) {
+		t.Errorf("pids %v collected, expected [1 2]", pids)
+	}
+}
+
+func TestGatherProcfs(t *testing.T) {
+	proc := newTestProc(t)
+	p := newPS(nil, systemClock{})
+	p.Backend = backendProcFS
+	p.ProcRoot = proc.Root
+	p.UserIdentity = userIdentityUID
+	p.ContainerTags = true
+	p.ProcFields = []string{procFieldsIO, procFieldsFDCount}
+
+	var acc testutil.Accumulator
+	if err := p.Gather(&acc); err != nil {
+		t.Fatal(err)
+	}
+	if len(acc.Errors) > 0 {
+		t.Fatalf("errors: %v", acc.Errors)
+	}
+
+	m := findProcessMetric(t, p, &acc, "4242")
+	if m.Tags["comm"] != "sleep" || m.Tags["uid"] != "1000" {
+		t.Errorf("tags %v", m.Tags)
+	}
+	if m.Tags["container_id"] != testContainerID {
+		t.Errorf("container_id %q, expected %q", m.Tags["container_id"], testContainerID)
+	}
+	expected := map[string]interface{}{
+		"status":      "D+",
+		"tty":         "pts/3",
+		"sid":         4200,
+		"pgid":        4242,
+		"read_bytes":  int64(4096),
+		"write_bytes": int64(512),
+		"syscr":       int64(7),
+		"syscw":       int64(3),
+		"fd_count":    3,
+	}
+	for name, value := range expected {
+		if m.Fields[name] != value {
+			t.Errorf("field %s: %#v, expected %#v", name, m.Fields[name], value)
+		}
+	}
+
+	m = findProcessMetric(t, p, &acc, "1")
+	if m.Fields["fd_count"] != 3 {
+		t.Errorf("fd_count of init %#v, expected 3", m.Fields["fd_count"])
+	}
+	if _, ok := m.Fields["tty"]; ok {
+		t.Errorf("tty reported for init")
+	}
+
+	// The snapshot is recorded in the shared cache of proc_root, which
+	// knows the process after it exited.
+	proc.Remove(4242)
+	if identity, ok := psinfo.SharedCache(proc.ProcFS()).Lookup(4242); !ok || identity.Comm != "sleep" {
+		t.Errorf("identity %+v in the shared cache of %s", identity, proc.Root)
+	}
+}
+
+func TestGatherProcFieldsOptIn(t *testing.T) {
+	proc := newTestProc(t)
+	p := newPS(nil, systemClock{})
+	p.Backend = backendProcFS
+	p.ProcRoot = proc.Root
+
+	var acc testutil.Accumulator
+	if err := p.Gather(&acc); err != nil {
+		t.Fatal(err)
+	}
+	m := findProcessMetric(t, p, &acc, "4242")
+	for _, name := range []string{"read_bytes", "fd_count", "voluntary_ctxt_switches", "minflt", "swap_kb", "cpus_allowed", "cap_eff", "oom_score", "exe_deleted"} {
+		if _, ok := m.Fields[name]; ok {
+			t.Errorf("field %s reported without proc_fields", name)
+		}
+	}
+
+	p = newPS(nil, systemClock{})
+	p.ProcRoot = proc.Root
+	p.ProcFields = []string{"io", "dstate"}
+	if err := p.Gather(&acc); err == nil {
+		t.Error("unknown group accepted")
+	}
+}
+
+func TestReadProcFilesOnce(t *testing.T) {
+	proc := newTestProc(t)
+	p := newPS(nil, systemClock{})
+	p.Backend = backendProcFS
+	p.ProcRoot = proc.Root
+	p.ProcFields = []string{procFieldsCtxtSwitches, procFieldsFaults, procFieldsSwap}
+	if err := p.setup(); err != nil {
+		t.Fatal(err)
+	}
+
+	read := psinfo.Process{Pid: 1, ProcStatus: &psinfo.Status{}, ProcStat: &psinfo.Stat{}}
+	status, stat := read.ProcStatus, read.ProcStat
+	processes := []psinfo.Process{read, {Pid: 4242}}
+	if err := p.readProcFiles(processes, testDeadline()); err != nil {
+		t.Fatal(err)
+	}
+	// The files the backend read are not read again, the others are.
+	if processes[0].ProcStatus != status || processes[0].ProcStat != stat {
+		t.Error("status and stat of process 1 read again")
+	}
+	if processes[1].ProcStatus == nil || processes[1].ProcStat == nil {
+		t.Errorf("status %v and stat %v of process 4242", processes[1].ProcStatus, processes[1].ProcStat)
+	}
+
+	// An exited process keeps the files read before it exited.
+	proc.Remove(4242)
+	if values := p.readCounters(processes[1]); len(values) == 0 {
+		t.Error("no counters of the exited process")
+	}
+}
@@ -1,68 +1,297 @@
 package ps
 
 import (
-	"bufio"
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"os/exec"
-	"regexp"
+	"io/ioutil"
+	"os"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"text/template"
 	"time"
 
-	"github.com/kballard/go-shellquote"
+	"github.com/gpapag/telegraf-plugins/pkg/psinfo"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/plugins/inputs"
 )
 
 const (
-	processSelection = `-axo`
-	infoSelection    = `pid=,ppid=,comm=,args=,nlwp=,rss=,vsz=,%mem=,%cpu=,psr=,ruser=,stat=`
-	fieldName        = `ps`
-	tag              = `ps`
+	fieldName    = `ps`
+	tag          = `ps`
+	failureField = `failure`
 )
 
-type psInfo struct {
-	Pid   int     `json:"pid"`
-	Ppid  int     `json:"ppid"`
-	Comm  string  `json:"command"`
-	Args  string  `json:"args"`
-	Nlwp  int     `json:"threads"`
-	Rss   int     `json:"rss"`
-	Vsz   int     `json:"vsize"`
-	Mem   float64 `json:"mem"`
-	CPU   float64 `json:"cpu"`
-	Psr   int     `json:"processor"`
-	Ruser string  `json:"user"`
-	Stat  string  `json:"status"`
+// User identities accepted by the user_identity option.
+const (
+	userIdentityName = `name`
+	userIdentityUID  = `uid`
+	userIdentityBoth = `both`
+)
+
+// Orders accepted by the sort_by option.
+const (
+	sortByNone = ``
+	sortByPid  = `pid`
+	sortByName = `name`
+)
+
+// Modes accepted by the parse_mode option.
+const (
+	parseModeWhitespace = `whitespace`
+	parseModeFixedWidth = `fixed_width`
+)
+
+// Output formats accepted by the format option.
+const (
+	formatLegacyJSON = `legacy_json`
+	formatPerProcess = `per_process`
+	formatBoth       = `both`
+)
+
+// fieldColumns maps the per_process fields read from ps to the column
+// holding their value.
+var fieldColumns = map[string]string{
+	"ppid":      "ppid",
+	"args":      "args",
+	"threads":   "nlwp",
+	"rss":       "rss",
+	"vsz":       "vsz",
+	"mem":       "%mem",
+	"cpu":       "%cpu",
+	"processor": "psr",
+	"status":    "stat",
+
+	"cpu_usage_interval": "time",
+	"start_time":         "etimes",
+	"uptime_seconds":     "etimes",
+
+	"nice":         "ni",
+	"priority":     "priority",
+	"sched_policy": "policy",
+
+	"tty":  "tty",
+	"sid":  "sid",
+	"pgid": "pgid",
+
+	"dstate_duration_s": "stat",
 }
 
 // PS executes a ps command to collect information about the processes
 // running on the host.
-//
 type PS struct {
 	procSelection string
-	infoSelection string
+	runner        Runner
+	clock         Clock
+	procFS        psinfo.ProcFS
+	InstanceAlias string
+	Backend       string
+	Variant       string
+	ParseMode     string
+	PsPath        string
+	PsArgs        []string
+	ProcRoot      string
 	Timeout       internal.Duration
+	Workers       int
+	Retries       int
+	RetryBackoff  internal.Duration
+	Format        string
+	Fields        []string
+	ProcFields    []string
+	SortBy        string
+	TopN          int
+	TopNBy        string
+	MinCPUPercent float64
+	MinRSSKB      int
+
+	MaxFieldsPerGather int
+	MaxProcesses       int
+
+	Detail             bool
+	DetailMeasurement  string
+	Summary            bool
+	SummaryMeasurement string
+	GroupBy            string
+	GroupMeasurement   string
+	CgroupRollups      bool
+	CgroupRoot         string
+
+	Pattern         string
+	ExcludePattern  string
+	SelectionFile   string
+	ReportSelection bool
+	PidFile         string
+	ProcessName     string
+	PidFiles        []string
+	SystemdUnits    []string
+
+	ExcludeKernelThreads bool
+	UseSudo              bool
+
+	RemoteHosts   []string
+	RemoteTimeout internal.Duration
+	SSHPath       string
+	SSHKeyFile    string
+	SSHKnownHosts string
+
+	UserIdentity  string
+	EffectiveUser bool
+	ContainerTags bool
+
+	SecurityContext bool
+
+	PodTags                   bool
+	KubeletURL                string
+	KubeletTokenFile          string
+	KubeletCAFile             string
+	KubeletInsecureSkipVerify bool
+
+	FDLimits    bool
+	Smaps       bool
+	NUMA        bool
+	Sockets     bool
+	ExeChecksum bool
+
+	GPU           bool
+	NvidiaSmiPath string
+
+	LifecycleEvents bool
+	EventTime       string
+
+	Zombies      bool
+	ProcessTrees bool
+	ExecTracing  bool
+	PerThread    bool
+
+	ArgsMode      string
+	ArgsMaxLength int
+
+	TagTemplates map[string]string
+
+	EnvAllowlist      []string
+	EnvTags           []string
+	EnvMaxValueLength int
+	EnvMaxCount       int
+
+	Columns []ColumnConfig
+
+	// mu guards the state below against overlapping calls to Gather.
+	mu          sync.Mutex
+	initialized bool
+	fieldFilter filter.Filter
+	unitFilter  filter.Filter
+	envFilter   filter.Filter
+	columns     []psinfo.Column
+	unsupported []string
+	collector   ProcessCollector
+	pool        workerPool
+	templates   map[string]*template.Template
+
+	remotes           []remoteHost
+	remoteUnsupported []string
+
+	readIO           bool
+	readFDs          bool
+	readFDLimits     bool
+	readCtxtSwitches bool
+	readFaults       bool
+	readSwap         bool
+	readSmaps        bool
+	readNUMA         bool
+	readAffinity     bool
+	readWchan        bool
+	readDState       bool
+	readCapabilities bool
+	readOOMScore     bool
+	readOOMScoreAdj  bool
+	readSockets      bool
+	readGPU          bool
+	readExe          bool
+	readExeDeleted   bool
+
+	exeSums exeCache
+
+	// tracer is set by Start, before any gather.
+	tracer *execTracer
+
+	kubelet *kubeletClient
+	pods    map[string]pod
+	podsAt  time.Time
+	// podsMu serializes the listings of the pods, which wait on the kubelet
+	// and so are made without holding mu.
+	podsMu sync.Mutex
+
+	fileSelection    *selection
+	selectionModTime time.Time
+	mergedSelection  *selection
+	mergedFrom       *selection
+
+	knownProcesses map[int]psinfo.Process
+	knownTags      map[int]map[string]string
+	knownAt        time.Time
+
+	cpuSamples map[int]cpuSample
+	cpuAt      time.Time
+
+	counterSamples map[int]counterSample
+	countersAt     time.Time
+
+	threadSamples map[int]cpuSample
+	threadsAt     time.Time
+
+	dstateSamples map[int]dstateSample
+	dstateAt      time.Time
 }
 
 // init initializes the package.
 func init() {
 	inputs.Add("ps", func() telegraf.Input {
-		return newPS(processSelection, infoSelection)
+		return newPS(execRunner{}, systemClock{})
 	})
 }
 
 // newPS returns a pointer to a new PS object.
-func newPS(processSelection string, infoSelection string) *PS {
+func newPS(runner Runner, clock Clock) *PS {
 	return &PS{
-		procSelection: processSelection,
-		infoSelection: infoSelection,
-		Timeout:       internal.Duration{Duration: time.Second * 5},
+		runner:       runner,
+		clock:        clock,
+		procFS:       psinfo.DefaultProcFS,
+		Backend:      defaultBackend,
+		Variant:      defaultVariant,
+		ParseMode:    parseModeWhitespace,
+		PsPath:       "/bin/ps",
+		Timeout:      internal.Duration{Duration: time.Second * 5},
+		Workers:      4,
+		RetryBackoff: internal.Duration{Duration: time.Millisecond * 100},
+		Format:       formatPerProcess,
+		TopNBy:       topNByCPU,
+
+		Detail:             true,
+		DetailMeasurement:  fieldName,
+		SummaryMeasurement: fieldName + "_summary",
+		GroupMeasurement:   fieldName + "_group",
+		CgroupRoot:         psinfo.DefaultCgroupRoot,
+
+		RemoteTimeout: internal.Duration{Duration: time.Second * 10},
+		SSHPath:       "/usr/bin/ssh",
+
+		NvidiaSmiPath: "/usr/bin/nvidia-smi",
+
+		ArgsMode: argsModeFull,
+
+		UserIdentity: userIdentityName,
+		EventTime:    eventTimeGather,
+
+		KubeletTokenFile: "/var/run/secrets/kubernetes.io/serviceaccount/token",
+
+		EnvMaxValueLength: 256,
+		EnvMaxCount:       8,
 	}
 }
 
@@ -74,141 +303,1183 @@ func (p *PS) Description() string {
 // SampleConfig returns a sample configuration for the plugin.
 func (p *PS) SampleConfig() string {
 	return `
-	## Timeout for command to complete.
+	## Name telling this instance apart from other ps instances in errors
+	## and in the alias tag of the metrics describing the plugin itself.
+	#instance_alias = ""
+
+	## Backend collecting the process information, one of:
+	##   ps       - runs the ps command
+	##   procfs   - reads /proc/<pid>/stat, status and cmdline directly, with
+	##              no command to run every interval (Linux only)
+	##   gopsutil - reads the process table through gopsutil, on any system
+	##              it supports
+	##   windows  - uses the Windows process APIs (Windows only, and the
+	##              default there)
+	#backend = "ps"
+
+	## Flavour of the ps command: procps-ng, darwin, freebsd, bsd, busybox
+	## or toybox. Columns a flavour does not provide are not reported. The
+	## default is darwin on macOS, freebsd on FreeBSD and procps-ng
+	## elsewhere.
+	#variant = "procps-ng"
+
+	## How the lines of ps output are split into columns, one of:
+	##   whitespace  - at whitespace, the command and its arguments coming
+	##                 last; commands holding spaces shift the arguments
+	##   fixed_width - at the offsets of columns of fixed width, so any
+	##                 column may hold spaces (procps-ng only)
+	#parse_mode = "whitespace"
+
+	## Path of the ps command, and arguments passed to it before those
+	## selecting every process and the columns, such as "-ww" for unlimited
+	## width. Busybox environments may need ps_path = "/bin/busybox" with
+	## ps_args = ["ps"].
+	#ps_path = "/bin/ps"
+	#ps_args = []
+
+	## Mount point of the proc filesystem read by the procfs backend and
+	## for the fields read from /proc, such as "/host/proc" to monitor the
+	## host from a container. Defaults to the HOST_PROC environment
+	## variable, or else /proc.
+	#proc_root = "/proc"
+
+	## Timeout for command to complete. The procfs and windows backends,
+	## and the reading of the per-process fields from /proc, must also be
+	## done within it, counted from the start of the gather.
 	#timeout = "5s"
+
+	## Number of processes read from /proc, or through the Windows APIs, at
+	## once.
+	#workers = 4
+
+	## Number of times a failed collection is retried before the interval
+	## is reported as failed.
+	#retries = 0
+
+	## Delay before the first retry; doubled after every further attempt.
+	## No retry is made once the timeout has elapsed.
+	#retry_backoff = "100ms"
+
+	## Output format, one of:
+	##   per_process - one metric per process
+	##   legacy_json - a single metric holding the process table as JSON,
+	##                 the only format before per_process became the default
+	##   both        - emit both while consumers are migrated
+	#format = "per_process"
+
+	## Fields to emit in per_process metrics; glob patterns are supported.
+	## All fields are emitted when empty.
+	#fields = ["mem*", "cpu*", "rss", "vsz"]
+
+	## Groups of per_process fields read from /proc (Linux only), none when
+	## empty. Every group reads files of /proc for each emitted process at
+	## every gather:
+	##   io            - read_bytes, write_bytes, syscr and syscw
+	##   fd_count      - the number of open file descriptors
+	##   ctxt_switches - the voluntary and nonvoluntary context switches
+	##   faults        - the minor and major page faults
+	##   swap          - swap_kb
+	##   affinity      - cpus_allowed and cpus_allowed_count
+	##   wchan         - the wait channel of the processes in the D state
+	##   capabilities  - cap_eff and the has_cap_* fields
+	##   oom           - oom_score and oom_score_adj
+	##   exe_deleted   - whether the executable was deleted or replaced
+	## ctxt_switches, swap, affinity and capabilities share the status file of
+	## each process, read once, as the procfs backend already does.
+	#proc_fields = ["io", "fd_count"]
+
+	## How the args field reports the command line, to bound the series its
+	## values make up, one of:
+	##   full    - the whole command line
+	##   program - the program only, without its arguments
+	##   hash    - a stable 16 hex digit hash of the whole command line
+	#args_mode = "full"
+	## Command lines longer than this many bytes are truncated; 0 keeps
+	## them whole.
+	#args_max_length = 0
+
+	## Order in which processes are emitted, "pid" or "name" (then pid);
+	## the order is unspecified when empty.
+	#sort_by = ""
+
+	## Only emit the detail metrics of the top_n heaviest processes, ranked
+	## by top_n_by: cpu, rss, vsz or fd. Summaries and events still cover
+	## every selected process. All processes are emitted when 0.
+	#top_n = 0
+	#top_n_by = "cpu"
+
+	## Drop from the detail metrics the processes below both thresholds:
+	## the cpu usage in percent and the resident memory in KiB. A threshold
+	## of 0 is not applied.
+	#min_cpu_percent = 0.0
+	#min_rss_kb = 0
+
+	## Maximum number of fields, summed over all metrics, emitted per
+	## gather; 0 means no limit. Metrics beyond the limit are dropped and a
+	## ps metric with a truncated field is emitted instead.
+	#max_fields_per_gather = 0
+
+	## Maximum number of processes emitted in the detail metrics per
+	## gather, after top_n; 0 means no limit. Beyond it only the heaviest
+	## processes, ranked by top_n_by, are emitted, and a ps metric with an
+	## overflow_count field tells how many were left out.
+	#max_processes = 0
+
+	## Emit the detail metrics selected by format.
+	#detail = true
+	#detail_measurement = "ps"
+
+	## Emit a single low-cardinality summary metric for the whole host,
+	## with the totals and the number of processes in each state.
+	#summary = false
+	#summary_measurement = "ps_summary"
+
+	## Emit one metric per group of processes with their totals, grouping
+	## them by one of:
+	##   comm - the command name, such as all the workers of a web server
+	##   user - the user running them, by uid with user_identity = "uid"
+	## No groups are emitted when empty.
+	#group_by = ""
+	#group_measurement = "ps_group"
+
+	## Emit a ps_cgroup metric per cgroup v2 holding selected processes,
+	## with their totals and the memory.current and pids.current of the
+	## cgroup, read from the unified hierarchy mounted at cgroup_root.
+	#cgroup_rollups = false
+	#cgroup_root = "/sys/fs/cgroup"
+
+	## Regular expression matched against the command and its arguments;
+	## only the matching processes are reported.
+	#pattern = "nginx|postgres"
+
+	## Regular expression matched against the command and its arguments;
+	## the matching processes are never reported.
+	#exclude_pattern = "postgres: autovacuum"
+
+	## TOML file with the patterns, exclude_patterns and users lists that
+	## select the reported processes, in addition to pattern and
+	## exclude_pattern. The file is read again whenever it changes, without
+	## restarting Telegraf.
+	#selection_file = "/etc/telegraf/ps_selection.toml"
+
+	## Emit a ps_selection metric per pattern, exclude pattern and user of
+	## the selection with the number of processes it matches, to spot
+	## patterns matching nothing.
+	#report_selection = false
+
+	## Never report the Linux kernel threads, such as kworker and
+	## ksoftirqd: kthreadd and its children.
+	#exclude_kernel_threads = false
+
+	## Run ps through "sudo -n", which must be allowed to run it without a
+	## password, for hosts mounting /proc with hidepid. The files of /proc
+	## are always read by telegraf itself: grant it CAP_SYS_PTRACE or
+	## CAP_DAC_READ_SEARCH for those of other users. Fields that cannot be
+	## read are named in the permission_denied field of the process.
+	#use_sudo = false
+
+	## Hosts whose processes are also listed by running ps over ssh, as
+	## [user@]host[:port], for appliances where telegraf cannot be
+	## installed. Their per_process metrics hold the ps columns only and
+	## are tagged with the host. ssh authenticates with keys only and
+	## refuses hosts missing from the known hosts file.
+	#remote_hosts = []
+	## Time allowed to each host to connect and run ps.
+	#remote_timeout = "10s"
+	## Path of the ssh command, private key it authenticates with, and
+	## known hosts file listing the keys of the hosts, the defaults of ssh
+	## when empty.
+	#ssh_path = "/usr/bin/ssh"
+	#ssh_key_file = ""
+	#ssh_known_hosts = ""
+
+	## Only report the process whose pid is written in pid_file, tagged with
+	## process_name, or the base name of the file when empty.
+	#pid_file = "/var/run/nginx.pid"
+	#process_name = "nginx"
+
+	## Only report the processes whose pids are written in these files,
+	## each tagged with the base name of its file, such as "sshd" for
+	## /var/run/sshd.pid. Combines with pid_file.
+	#pid_files = ["/var/run/sshd.pid", "/var/run/crond.pid"]
+
+	## Only report the processes of these systemd units, read from their
+	## cgroup, each tagged with its unit, and emit a ps_systemd_unit metric
+	## with the totals of every unit; glob patterns are supported.
+	#systemd_units = ["nginx.service", "postgresql@*.service"]
+
+	## How the user owning a process is identified, one of:
+	##   name - the user name; resolving names may be slow with NSS/LDAP
+	##   uid  - the numeric user id only, no name resolution takes place
+	##   both - the user name and the numeric user id
+	#user_identity = "name"
+
+	## Also identify the effective user, in addition to the real user.
+	#effective_user = false
+
+	## Tag the processes running in a container with the container_id and
+	## container_runtime read from their cgroup (Linux only).
+	#container_tags = false
+
+	## Tag the processes with the security context SELinux or AppArmor
+	## gives them, read from /proc/<pid>/attr/current (Linux only).
+	#security_context = false
+
+	## Tag the processes running in a Kubernetes pod with the pod_uid read
+	## from their cgroup (Linux only). With kubelet_url set, the pod_name
+	## and pod_namespace tags are also resolved through the kubelet API.
+	#pod_tags = false
+	#kubelet_url = "https://127.0.0.1:10250"
+	#kubelet_token_file = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	#kubelet_ca_file = ""
+	#kubelet_insecure_skip_verify = false
+
+	## Also emit the soft and hard limits on open file descriptors of each
+	## process, next to fd_count.
+	#fd_limits = false
+
+	## Also emit the proportional (pss_kb) and unique (uss_kb) set sizes of
+	## each process, read from /proc/<pid>/smaps_rollup (Linux 4.14 and
+	## later). Reading them walks the memory mappings of every process and
+	## may take a while on hosts with many large processes.
+	#smaps = false
+
+	## Also emit the memory of each process resident on every NUMA node,
+	## as numa_node<N>_kb fields read from /proc/<pid>/numa_maps. Like
+	## smaps, reading it walks the memory mappings of every process.
+	#numa = false
+
+	## Also emit the number of TCP, UDP and UNIX sockets each process has
+	## open, looked up in the socket tables of its network namespace. The
+	## tables may be large on hosts with many connections.
+	#sockets = false
+
+	## Also emit the SHA-256 checksum of the executable of each process,
+	## read through /proc/<pid>/exe and hashed once per binary.
+	#exe_checksum = false
+
+	## Also emit the memory and utilization of the NVIDIA GPUs used by each
+	## process, as reported by nvidia-smi, which runs twice per gather.
+	#gpu = false
+	#nvidia_smi_path = "/usr/bin/nvidia-smi"
+
+	## Emit a ps_event metric whenever a selected process appears or exits.
+	#lifecycle_events = false
+
+	## Timestamp of the started events, one of:
+	##   gather        - the gather in which the process was first seen
+	##   process_start - the time the process actually started
+	#event_time = "gather"
+
+	## Emit a ps_zombies metric per process with defunct children it has
+	## not reaped, tagged with its pid and command.
+	#zombies = false
+
+	## Emit a ps_tree metric per tree of selected processes with the totals
+	## of its root and all its descendants, selected or not, to account for
+	## forking servers and shells as a whole.
+	#process_trees = false
+
+	## Trace the programs executed between gathers through the proc
+	## connector of Linux, and emit a ps_short_lived metric per command with
+	## the processes that started and exited unseen. Requires running as
+	## root or with CAP_NET_ADMIN.
+	#exec_tracing = false
+
+	## Emit a ps_thread metric per thread of the processes reported in
+	## detail, tagged with its tid and name, to find the hot threads of
+	## multithreaded daemons. Read from /proc/<pid>/task (Linux only).
+	#per_thread = false
+
+	## Environment variables that may be read from /proc/<pid>/environ and
+	## emitted as env_<NAME> fields in per_process metrics; glob patterns
+	## are supported. No environment is read when empty.
+	#env_allowlist = ["DEPLOY_ENV", "SERVICE_*"]
+
+	## Environment variables read from /proc/<pid>/environ and attached as
+	## env_<NAME> tags to per_process metrics instead, to group processes by
+	## deployment metadata.
+	#env_tags = ["DEPLOY_ENV", "SERVICE_NAME"]
+
+	## Values longer than this many bytes are truncated.
+	#env_max_value_length = 256
+
+	## Maximum number of variables emitted per process.
+	#env_max_count = 8
+
+	## Tags built from Go templates over the process attributes: Pid, Ppid,
+	## Comm, Args, Nlwp, Rss, Vsz, Mem, CPU, Psr, Ruser, Ruid, Euser, Euid
+	## and Stat.
+	#[inputs.ps.tag_templates]
+	#  service = "{{.Ruser}}/{{.Comm}}"
+
+	## Additional ps columns emitted as per_process fields (ps backend
+	## only): the column as given to ps -o, the field it is emitted as,
+	## the column name when empty, and its type, one of string, integer or
+	## float. Columns whose values may contain spaces, such as lstart, need
+	## parse_mode = "fixed_width".
+	#[[inputs.ps.columns]]
+	#  spec = "ni"
+	#  field = "nice"
+	#  type = "integer"
 	`
 }
 
 // Gather parses the output of the ps command and stores the output in
-// the accumulator acc.
+// the accumulator acc. Gather may be called again before a previous call
+// returned.
 func (p *PS) Gather(acc telegraf.Accumulator) error {
-	psCommand := strings.Join([]string{"/bin/ps", p.procSelection, p.infoSelection}, " ")
-	jsonArray, err := p.processCommand(psCommand)
+	stats := &gatherStats{start: p.clock.Now()}
+	defer p.addGatherStats(acc, stats)
+
+	// The work done for each process stops at the deadline, so that hosts
+	// with many processes cannot stall the gather indefinitely.
+	var deadline time.Time
+	if p.Timeout.Duration > 0 {
+		deadline = stats.start.Add(p.Timeout.Duration)
+	}
+
+	var emitLegacy, emitPerProcess bool
+	switch p.Format {
+	case formatLegacyJSON:
+		emitLegacy = true
+	case formatPerProcess:
+		emitPerProcess = true
+	case formatBoth:
+		emitLegacy, emitPerProcess = true, true
+	default:
+		err := p.errorf("unknown format %q", p.Format)
+		acc.AddError(err)
+		return err
+	}
+
+	if err := p.setup(); err != nil {
+		err = p.errorf("invalid configuration: %w", err)
+		acc.AddError(err)
+		return err
+	}
+
+	sel, err := p.reloadSelection()
 	if err != nil {
+		err = p.errorf("%w", err)
 		acc.AddError(err)
-		return fmt.Errorf("ps: unable to gather metrics: %s", err)
+		if sel == nil {
+			return err
+		}
 	}
 
-	metric, err := metric.New(
-		fieldName,
-		map[string]string{"plugin": tag},
-		map[string]interface{}{"fields": string(jsonArray)},
-		time.Now().UTC())
+	// The processes executed from now on are seen by this gather if they
+	// are still running, so only those exited before are short-lived.
+	if p.tracer != nil {
+		var exited map[string]*shortLived
+		exited, stats.traceOverflows = p.tracer.flush()
+		p.addShortLived(acc, exited, stats.start.UTC())
+	}
+
+	// The remote hosts are given remote_timeout from the start of the
+	// gather, independently of the deadline of the local processes.
+	var remotes *remoteProcesses
+	if len(p.remotes) > 0 && emitPerProcess && p.Detail {
+		remotes = p.selectRemotes(stats.start.Add(p.RemoteTimeout.Duration))
+	}
+
+	processes, err := p.collect(deadline)
+	if parsing, ok := p.collector.(parsingCollector); ok {
+		stats.parseErrors = parsing.ParseErrors()
+	}
+	stats.seen = len(processes)
 	if err != nil {
+		acc.AddFields(
+			fieldName,
+			map[string]interface{}{failureField: err.Error()},
+			p.selfTags(),
+			p.clock.Now().UTC())
+		err = p.errorf("unable to gather metrics: %w", err)
 		acc.AddError(err)
-		return fmt.Errorf("ps: unable to gather metrics: %s", err)
+		return err
+	}
+
+	psinfo.SharedCache(p.procFS).Update(processes)
+	if p.ExcludeKernelThreads {
+		processes = excludeKernelThreads(processes)
+	}
+
+	now := p.clock.Now().UTC()
+	if p.MaxFieldsPerGather > 0 {
+		limited := newLimitedAccumulator(acc, p.MaxFieldsPerGather)
+		defer p.addTruncation(acc, limited, now)
+		acc = limited
+	}
+	if p.ReportSelection {
+		p.addSelectionReport(acc, sel, processes, now)
+	}
+
+	var comms map[int]string
+	if p.Zombies {
+		comms = commsByPid(processes)
+	}
+	all := processes
+
+	processes = selectProcesses(sel, processes)
+	var names map[int]string
+	if p.PidFile != "" || len(p.PidFiles) > 0 {
+		names = p.processNames(acc)
+		processes = selectPids(names, processes)
+	}
+	var units map[int]string
+	if p.unitFilter != nil {
+		units = p.systemdUnits(processes)
+		processes = selectPids(units, processes)
+	}
+
+	var trees []psinfo.Process
+	if p.ProcessTrees {
+		selected := make(map[int]string, len(processes))
+		for _, process := range processes {
+			selected[process.Pid] = ""
+		}
+		extra := descendants(processes, all)
+		trees, err = p.collector.Collect(append(processes[:len(processes):len(processes)], extra...), deadline)
+		if err == nil {
+			processes = selectPids(selected, trees)
+		}
+	} else {
+		processes, err = p.collector.Collect(processes, deadline)
+	}
+	if err != nil {
+		err = p.errorf("unable to gather metrics: %w", err)
+		acc.AddError(err)
+		return err
+	}
+
+	// The usage of the descendants is known too when they are collected,
+	// for the totals of the trees.
+	sampled := processes
+	if trees != nil {
+		sampled = trees
+	}
+	cpuUsage := p.cpuUsage(sampled, now)
+	// Processes left out by the thresholds keep their durations.
+	dstate := p.dstateDurations(sampled, now)
+	p.sortProcesses(processes)
+
+	if p.Summary {
+		p.addSummary(acc, processes, now)
+	}
+	if units != nil {
+		p.addUnits(acc, processes, units, cpuUsage, now)
+	}
+	if p.GroupBy != groupByNone {
+		p.addGroups(acc, processes, cpuUsage, now)
+	}
+	if p.CgroupRollups {
+		p.addCgroups(acc, processes, cpuUsage, now)
+	}
+	if p.LifecycleEvents {
+		p.addEvents(acc, processes, names, units, now)
+	}
+	if p.Zombies {
+		p.addZombies(acc, processes, comms, now)
+	}
+	if trees != nil {
+		p.addTrees(acc, trees, cpuUsage, now)
+	}
+	if !p.Detail {
+		return nil
+	}
+	processes = p.aboveThresholds(processes, cpuUsage)
+	if p.TopN > 0 {
+		processes = p.topN(processes, cpuUsage, p.TopN)
+		p.sortProcesses(processes)
+	}
+	if p.MaxProcesses > 0 {
+		processes = p.capProcesses(acc, processes, cpuUsage, now)
+	}
+	if emitLegacy {
+		if err := p.addLegacyJSON(acc, processes, now); err != nil {
+			err = p.errorf("unable to gather metrics: %w", err)
+			acc.AddError(err)
+			return err
+		}
+		stats.emitted = len(processes)
+	}
+	if emitPerProcess {
+		// Processes not reached by the deadline are not reached by
+		// addPerProcess either, which reports it.
+		_ = p.readProcFiles(processes, deadline)
+		extras := processExtras{
+			cpuUsage: cpuUsage,
+			dstate:   dstate,
+			names:    names,
+			units:    units,
+			counters: p.counters(processes, now),
+		}
+		if p.readSockets {
+			extras.sockets = newSocketIndex(p.procFS)
+		}
+		if p.readGPU {
+			if extras.gpus, err = p.gpuUsages(); err != nil {
+				acc.AddError(p.errorf("unable to query the gpus: %w", err))
+			}
+		}
+		stats.emitted, stats.permissionDenied = p.addPerProcess(acc, processes, extras, now, deadline)
+		if remotes != nil {
+			stats.emitted += p.addRemotes(acc, sel, remotes)
+		}
+	}
+	if p.PerThread {
+		p.addThreads(acc, processes, now, deadline)
 	}
 
-	acc.AddFields(metric.Name(), metric.Fields(), metric.Tags(), metric.Time())
 	return nil
 }
 
-// processCommand executes the command and returns a slice of json objects
-// containing the results.
-func (p *PS) processCommand(command string) ([]byte, error) {
+// sortProcesses orders processes as selected by the sort_by option.
+func (p *PS) sortProcesses(processes []psinfo.Process) {
+	switch p.SortBy {
+	case sortByPid:
+		sort.Slice(processes, func(i, j int) bool {
+			return processes[i].Pid < processes[j].Pid
+		})
+	case sortByName:
+		sort.Slice(processes, func(i, j int) bool {
+			if processes[i].Comm != processes[j].Comm {
+				return processes[i].Comm < processes[j].Comm
+			}
+			return processes[i].Pid < processes[j].Pid
+		})
+	}
+}
+
+// addTruncation stores in acc a metric telling how much of the output was
+// dropped by limited, if anything was.
+func (p *PS) addTruncation(acc telegraf.Accumulator, limited *limitedAccumulator, now time.Time) {
+	if !limited.truncated() {
+		return
+	}
+
+	fields := map[string]interface{}{
+		"truncated":       true,
+		"dropped_metrics": limited.droppedMetrics,
+		"dropped_fields":  limited.droppedFields,
+	}
+	acc.AddFields(fieldName, fields, p.selfTags(), now)
+}
+
+// selfTags returns the tags of the metrics describing the plugin itself
+// rather than the processes.
+func (p *PS) selfTags() map[string]string {
+	tags := map[string]string{"plugin": tag}
+	if p.InstanceAlias != "" {
+		tags["alias"] = p.InstanceAlias
+	}
+	return tags
+}
+
+// errorf returns an error prefixed with the plugin name and, when set, the
+// instance alias, so that the failing instance can be told apart. Errors
+// formatted with %w stay wrapped.
+func (p *PS) errorf(format string, a ...interface{}) error {
+	prefix := "ps"
+	if p.InstanceAlias != "" {
+		prefix = fmt.Sprintf("ps [%s]", p.InstanceAlias)
+	}
+	return fmt.Errorf(prefix+": "+format, a...)
+}
+
+// setup compiles the configuration options on the first call to Gather.
+func (p *PS) setup() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.initialized {
+		return nil
+	}
+
+	switch {
+	case p.ProcRoot != "":
+		p.procFS = psinfo.ProcFS{Root: p.ProcRoot}
+	case os.Getenv("HOST_PROC") != "":
+		p.procFS = psinfo.ProcFS{Root: os.Getenv("HOST_PROC")}
+	}
+
 	var err error
+	p.fieldFilter, err = filter.Compile(p.Fields)
+	if err != nil {
+		return fmt.Errorf("fields: %s", err)
+	}
 
-	var splitCmd []string
-	splitCmd, err = shellquote.Split(command)
-	if err != nil || len(splitCmd) == 0 {
-		return nil, err
+	p.unitFilter, err = filter.Compile(p.SystemdUnits)
+	if err != nil {
+		return fmt.Errorf("systemd_units: %s", err)
+	}
+
+	p.envFilter, err = filter.Compile(p.EnvAllowlist)
+	if err != nil {
+		return fmt.Errorf("env_allowlist: %s", err)
+	}
+	for _, name := range p.EnvTags {
+		if name == "" || strings.Contains(name, "=") {
+			return fmt.Errorf("env_tags: invalid variable name %q", name)
+		}
+	}
+	if len(p.EnvTags) > 0 && p.EnvMaxValueLength <= 0 {
+		return fmt.Errorf("env_max_value_length must be positive")
+	}
+	if p.envFilter != nil && (p.EnvMaxValueLength <= 0 || p.EnvMaxCount <= 0) {
+		return fmt.Errorf("env_max_value_length and env_max_count must be positive")
 	}
+	groups, err := p.procFieldGroups()
+	if err != nil {
+		return fmt.Errorf("proc_fields: %s", err)
+	}
+	p.readIO = groups[procFieldsIO] && p.keepsAny(ioFields...)
+	p.readFDs = groups[procFieldsFDCount] && p.keepsAny("fd_count")
+	p.readFDLimits = p.FDLimits && p.keepsAny("fd_limit_soft", "fd_limit_hard")
+	p.readCtxtSwitches = groups[procFieldsCtxtSwitches] && p.keepsAny(ctxtSwitchFields...)
+	p.readFaults = groups[procFieldsFaults] && p.keepsAny(faultFields...)
+	p.readSwap = groups[procFieldsSwap] && p.keepsAny("swap_kb")
+	p.readSmaps = p.Smaps && p.keepsAny("pss_kb", "uss_kb")
+	// The fields are named after the nodes of the host, so the fields
+	// option only filters them once read.
+	p.readNUMA = p.NUMA
+	p.readAffinity = groups[procFieldsAffinity] && p.keepsAny(affinityFields...)
+	p.readWchan = groups[procFieldsWchan] && p.keepsAny("wchan")
+	p.readDState = p.keepsAny("dstate_duration_s")
+	p.readCapabilities = groups[procFieldsCapabilities] && p.keepsAny(capabilityFields...)
+	p.readOOMScore = groups[procFieldsOOM] && p.keepsAny("oom_score")
+	p.readOOMScoreAdj = groups[procFieldsOOM] && p.keepsAny("oom_score_adj")
+	p.readSockets = p.Sockets && p.keepsAny(socketFields...)
+	p.readGPU = p.GPU && p.keepsAny(gpuFields...)
+	p.readExe = p.ExeChecksum && p.keepsAny("exe_sha256")
+	p.readExeDeleted = groups[procFieldsExeDeleted] && p.keepsAny("exe_deleted")
 
-	var out bytes.Buffer
-	cmd := exec.Command(splitCmd[0], splitCmd[1:]...)
-	cmd.Stdout = &out
-	if err := internal.RunTimeout(cmd, p.Timeout.Duration); err != nil {
-		return nil, err
+	variant, err := psinfo.LookupVariant(p.Variant)
+	if err != nil {
+		return err
+	}
+	p.procSelection = variant.Flags
+	switch p.ParseMode {
+	case parseModeWhitespace:
+	case parseModeFixedWidth:
+		if p.Backend == backendPS && !variant.FixedWidth {
+			return fmt.Errorf("parse_mode %s is not supported by variant %s", p.ParseMode, variant.Name)
+		}
+	default:
+		return fmt.Errorf("unknown parse_mode %q", p.ParseMode)
 	}
 
-	var jsonInfo []psInfo
-	jsonInfo, err = p.parse(out.String())
+	columns, err := p.selectColumns()
 	if err != nil {
-		return nil, err
+		return err
 	}
+	p.columns = variant.Columns(columns)
 
-	var jsonArray []byte
-	jsonArray, err = json.Marshal(jsonInfo)
+	// The extra columns are passed as is, before the free-text comm and
+	// args columns that must come last.
+	extra, err := p.extraColumns()
 	if err != nil {
-		return nil, err
+		return err
 	}
+	text := len(p.columns) - 2
+	p.columns = append(p.columns[:text:text], append(extra, p.columns[text:]...)...)
 
-	return jsonArray, nil
-}
+	// The ps command lacks the columns its variant does not provide; the
+	// other backends tell the fields they lack themselves.
+	p.unsupported = nil
+	p.remoteUnsupported = nil
+	for field, spec := range fieldColumns {
+		if _, ok := variant.Specs[spec]; !ok {
+			p.remoteUnsupported = append(p.remoteUnsupported, field)
+		}
+	}
+	if p.Backend == backendPS {
+		p.unsupported = append(p.unsupported, p.remoteUnsupported...)
+	}
 
-// parse returns a slice of json objects based on the text in out.
-func (p *PS) parse(in string) ([]psInfo, error) {
-	var parser = regexp.MustCompile(`^\s*(\d+)\s+(\d+)\s+(.+?)\s+(.+?)\s+(\d+)\s+(\d+)\s+(\d+\.\d+)\s+(\d+\.\d+)\s+(.+?)\s+(.+?)$`)
+	switch p.ArgsMode {
+	case argsModeFull, argsModeProgram, argsModeHash:
+	default:
+		return fmt.Errorf("unknown args_mode %q", p.ArgsMode)
+	}
+	if p.ArgsMaxLength < 0 {
+		return fmt.Errorf("args_max_length must not be negative")
+	}
 
-	var psInfoArray []psInfo
-	scanner := bufio.NewScanner(strings.NewReader(in))
-	for scanner.Scan() {
-		results := parser.FindAllStringSubmatch(scanner.Text(), -1)
-		if results == nil {
-			continue
+	switch p.SortBy {
+	case sortByNone, sortByPid, sortByName:
+	default:
+		return fmt.Errorf("unknown sort_by %q", p.SortBy)
+	}
+
+	if p.TopN < 0 {
+		return fmt.Errorf("top_n must not be negative")
+	}
+	if p.MaxProcesses < 0 {
+		return fmt.Errorf("max_processes must not be negative")
+	}
+	switch p.TopNBy {
+	case topNByCPU, topNByRSS, topNByVSZ, topNByFD:
+	default:
+		return fmt.Errorf("unknown top_n_by %q", p.TopNBy)
+	}
+	switch p.GroupBy {
+	case groupByNone, groupByComm, groupByUser:
+	default:
+		return fmt.Errorf("unknown group_by %q", p.GroupBy)
+	}
+
+	if p.MinCPUPercent < 0 || p.MinRSSKB < 0 {
+		return fmt.Errorf("min_cpu_percent and min_rss_kb must not be negative")
+	}
+
+	creator, ok := collectors[p.Backend]
+	if !ok {
+		return fmt.Errorf("unknown backend %q", p.Backend)
+	}
+	if p.Workers <= 0 {
+		return fmt.Errorf("workers must be positive")
+	}
+	p.pool = workerPool{workers: p.Workers, clock: p.clock}
+	p.collector, err = creator(p)
+	if err != nil {
+		return fmt.Errorf("backend %s: %w", p.Backend, err)
+	}
+	if partial, ok := p.collector.(partialCollector); ok {
+		p.unsupported = append(p.unsupported, partial.Unsupported()...)
+	}
+
+	p.templates, err = compileTemplates(p.TagTemplates)
+	if err != nil {
+		return err
+	}
+
+	if len(p.RemoteHosts) > 0 {
+		if p.ParseMode == parseModeFixedWidth && !variant.FixedWidth {
+			return fmt.Errorf("parse_mode %s is not supported by variant %s", p.ParseMode, variant.Name)
+		}
+		if p.RemoteTimeout.Duration <= 0 {
+			return fmt.Errorf("remote_timeout must be positive")
 		}
-		psInfoElement, err := p.parseLine(results)
+	}
+	p.remotes, err = p.remoteHosts()
+	if err != nil {
+		return fmt.Errorf("remote_hosts: %s", err)
+	}
+
+	if p.PodTags && p.KubeletURL != "" {
+		p.kubelet, err = newKubeletClient(p.KubeletURL, p.KubeletTokenFile, p.KubeletCAFile,
+			p.KubeletInsecureSkipVerify, p.Timeout.Duration)
 		if err != nil {
-			continue
+			return fmt.Errorf("kubelet: %s", err)
 		}
-		psInfoArray = append(psInfoArray, *psInfoElement)
 	}
-	if err := scanner.Err(); err != nil {
-		return nil, err
+
+	if _, err := compilePatterns(nonEmpty(p.Pattern)); err != nil {
+		return fmt.Errorf("pattern: %s", err)
+	}
+	if _, err := compilePatterns(nonEmpty(p.ExcludePattern)); err != nil {
+		return fmt.Errorf("exclude_pattern: %s", err)
 	}
 
-	return psInfoArray, nil
+	p.initialized = true
+	return nil
 }
 
-// parseLine returns a psInfo struct with the information of a single process.
-func (p *PS) parseLine(results [][]string) (*psInfo, error) {
-	var err error
+// selectColumns returns the ps columns needed by the configuration.
+func (p *PS) selectColumns() ([]psinfo.Column, error) {
+	var byName, byUID bool
+	switch p.UserIdentity {
+	case userIdentityName:
+		byName = true
+	case userIdentityUID:
+		byUID = true
+	case userIdentityBoth:
+		byName, byUID = true, true
+	default:
+		return nil, fmt.Errorf("unknown user_identity %q", p.UserIdentity)
+	}
 
-	var pid int
-	pid, err = strconv.Atoi(results[0][1])
-	if err != nil {
-		return nil, err
+	columns := []psinfo.Column{
+		psinfo.ColumnPid,
+		psinfo.ColumnPpid,
+		psinfo.ColumnNlwp,
+		psinfo.ColumnRss,
+		psinfo.ColumnVsz,
+		psinfo.ColumnMem,
+		psinfo.ColumnCPU,
+		psinfo.ColumnPsr,
 	}
-	var ppid int
-	ppid, err = strconv.Atoi(results[0][2])
-	if err != nil {
-		return nil, err
+	if byName {
+		columns = append(columns, psinfo.ColumnRuser)
 	}
-	command := results[0][3]
-	args := results[0][4]
-	var rss int
-	rss, err = strconv.Atoi(results[0][5])
-	if err != nil {
-		return nil, err
+	if byUID {
+		columns = append(columns, psinfo.ColumnRuid)
+	}
+	if p.EffectiveUser && byName {
+		columns = append(columns, psinfo.ColumnEuser)
 	}
-	var vsize int
-	vsize, err = strconv.Atoi(results[0][6])
+	if p.EffectiveUser && byUID {
+		columns = append(columns, psinfo.ColumnEuid)
+	}
+
+	switch p.EventTime {
+	case eventTimeGather, eventTimeProcessStart:
+	default:
+		return nil, fmt.Errorf("unknown event_time %q", p.EventTime)
+	}
+	columns = append(columns, psinfo.ColumnEtimes)
+	columns = append(columns, psinfo.ColumnNice, psinfo.ColumnPri, psinfo.ColumnPolicy)
+	columns = append(columns, psinfo.ColumnTty, psinfo.ColumnSid, psinfo.ColumnPgid)
+
+	columns = append(columns, psinfo.ColumnTime, psinfo.ColumnStat, psinfo.ColumnComm, psinfo.ColumnArgs)
+
+	return columns, nil
+}
+
+// addLegacyJSON stores the whole process table in acc as a single metric
+// whose only field holds the table encoded as a json array.
+func (p *PS) addLegacyJSON(acc telegraf.Accumulator, processes []psinfo.Process, now time.Time) error {
+	jsonArray, err := json.Marshal(p.reportedProcesses(processes))
 	if err != nil {
-		return nil, err
+		return err
 	}
-	var mem float64
-	mem, err = strconv.ParseFloat(results[0][7], 64)
+
+	metric, err := metric.New(
+		p.DetailMeasurement,
+		map[string]string{"plugin": tag},
+		map[string]interface{}{"fields": string(jsonArray)},
+		now)
 	if err != nil {
-		return nil, err
+		return err
+	}
+
+	acc.AddFields(metric.Name(), metric.Fields(), metric.Tags(), metric.Time())
+	return nil
+}
+
+// processExtras holds what is known about the processes besides their
+// attributes, by pid.
+type processExtras struct {
+	// cpuUsage is the cpu usage over the interval, when known.
+	cpuUsage map[int]float64
+	// dstate is the time spent in uninterruptible sleep, in seconds.
+	dstate map[int]int64
+	// names are the names of the processes read from pid files.
+	names map[int]string
+	// units are the systemd units of the processes.
+	units map[int]string
+	// counters are the counter fields and their increase.
+	counters map[int]map[string]interface{}
+	// sockets holds the sockets of the processes, when counted.
+	sockets *socketIndex
+	// gpus is the usage of the GPUs by the processes using them.
+	gpus map[int]*gpuUsage
+}
+
+// addPerProcess stores one metric per process in acc, along with what
+// extras tell about it. The fields read from /proc are read for several
+// processes at once, and the processes not reached by deadline are left
+// out. It returns the number of metrics stored, and of processes missing
+// fields for lack of privileges.
+func (p *PS) addPerProcess(acc telegraf.Accumulator, processes []psinfo.Process, extras processExtras, now, deadline time.Time) (int, int) {
+	tags := make([]map[string]string, len(processes))
+	fields := make([]map[string]interface{}, len(processes))
+	denied := make([]denials, len(processes))
+	err := p.pool.forEach(len(processes), deadline, func(i int) {
+		tags[i], fields[i], denied[i] = p.processMetric(acc, processes[i], extras, now)
+	})
+	var added, incomplete int
+	for i := range processes {
+		if len(denied[i]) > 0 {
+			incomplete++
+		}
+		if len(fields[i]) == 0 {
+			continue
+		}
+		acc.AddFields(p.DetailMeasurement, fields[i], tags[i], now)
+		added++
 	}
-	var cpu float64
-	cpu, err = strconv.ParseFloat(results[0][8], 64)
 	if err != nil {
-		return nil, err
+		acc.AddError(p.errorf("unable to gather metrics: %w", err))
+	}
+	return added, incomplete
+}
+
+// processMetric returns the tags and fields of the metric of process, and
+// the groups of fields left out for lack of privileges.
+func (p *PS) processMetric(acc telegraf.Accumulator, process psinfo.Process, extras processExtras, now time.Time) (map[string]string, map[string]interface{}, denials) {
+	tags := map[string]string{
+		"plugin": tag,
+		"pid":    strconv.Itoa(process.Pid),
+		"comm":   process.Comm,
+	}
+	if name, ok := extras.names[process.Pid]; ok {
+		tags["process_name"] = name
+	}
+	if unit, ok := extras.units[process.Pid]; ok {
+		tags["systemd_unit"] = unit
+	}
+	p.addUserTags(tags, process)
+	p.addCgroupTags(acc, tags, process.Pid, now)
+	p.addTemplateTags(tags, process)
+	var denied denials
+	p.addSecurityTags(tags, process.Pid, &denied)
+	fields := p.columnFields(process, now)
+	if usage, ok := extras.cpuUsage[process.Pid]; ok {
+		fields["cpu_usage_interval"] = usage
+	}
+	for name, value := range extras.counters[process.Pid] {
+		fields[name] = value
+	}
+	for name, value := range p.extraFields(process) {
+		fields[name] = value
+	}
+	for name, value := range p.ioCounters(process.Pid, &denied) {
+		fields[name] = value
+	}
+	for name, value := range p.fdFields(process.Pid, &denied) {
+		fields[name] = value
+	}
+	for name, value := range p.memoryFields(process, &denied) {
+		fields[name] = value
+	}
+	for name, value := range p.affinity(process) {
+		fields[name] = value
+	}
+	for name, value := range p.wchanFields(process) {
+		fields[name] = value
+	}
+	if duration, ok := extras.dstate[process.Pid]; ok {
+		fields["dstate_duration_s"] = duration
+	}
+	for name, value := range p.capabilities(process) {
+		fields[name] = value
+	}
+	for name, value := range p.exeFields(process.Pid, &denied) {
+		fields[name] = value
+	}
+	for name, value := range p.oomFields(process.Pid) {
+		fields[name] = value
+	}
+	for name, value := range p.socketCounts(extras.sockets, process.Pid, &denied) {
+		fields[name] = value
+	}
+	if usage, ok := extras.gpus[process.Pid]; ok {
+		for name, value := range usage.fields() {
+			fields[name] = value
+		}
+	}
+	envFields, envTags := p.environ(process.Pid, &denied)
+	for name, value := range envFields {
+		fields[envFieldPrefix+name] = value
+	}
+	for name, value := range envTags {
+		tags[envFieldPrefix+name] = value
+	}
+	if len(denied) > 0 {
+		fields[permissionField] = denied.String()
+	}
+	for _, field := range p.unsupported {
+		delete(fields, field)
+	}
+	p.filterFields(fields)
+	return tags, fields, denied
+}
+
+// columnFields returns the per_process fields of process read from the
+// columns of ps.
+func (p *PS) columnFields(process psinfo.Process, now time.Time) map[string]interface{} {
+	fields := map[string]interface{}{
+		"ppid":      process.Ppid,
+		"args":      p.reportedArgs(process.Args),
+		"threads":   process.Nlwp,
+		"rss":       process.Rss,
+		"vsz":       process.Vsz,
+		"mem":       process.Mem,
+		"cpu":       process.CPU,
+		"processor": process.Psr,
+		"status":    process.Stat,
+	}
+	fields["start_time"] = startTime(process, now)
+	fields["uptime_seconds"] = process.Etimes
+	fields["nice"] = process.Nice
+	fields["priority"] = process.Priority
+	if process.Policy != "" {
+		fields["sched_policy"] = process.Policy
+	}
+	if process.Tty != "" {
+		fields["tty"] = process.Tty
+	}
+	fields["sid"] = process.Sid
+	fields["pgid"] = process.Pgid
+	return fields
+}
+
+// addUserTags adds to tags the identity of the user owning process, as
+// selected by the user_identity and effective_user options.
+func (p *PS) addUserTags(tags map[string]string, process psinfo.Process) {
+	byName := p.UserIdentity != userIdentityUID
+	byUID := p.UserIdentity != userIdentityName
+	if byName {
+		tags["user"] = process.Ruser
+	}
+	if byUID {
+		tags["uid"] = strconv.Itoa(process.Ruid)
+	}
+	if p.EffectiveUser && byName {
+		tags["effective_user"] = process.Euser
+	}
+	if p.EffectiveUser && byUID {
+		tags["effective_uid"] = strconv.Itoa(process.Euid)
+	}
+}
+
+// addTemplateTags adds to tags the tags built from the tag_templates
+// option.
+func (p *PS) addTemplateTags(tags map[string]string, process psinfo.Process) {
+	for key, tmpl := range p.templates {
+		var value bytes.Buffer
+		if err := tmpl.Execute(&value, process); err != nil {
+			continue
+		}
+		tags[key] = value.String()
+	}
+}
+
+// compileTemplates parses the tag templates, checking that they only refer
+// to known process attributes.
+func compileTemplates(templates map[string]string) (map[string]*template.Template, error) {
+	compiled := make(map[string]*template.Template, len(templates))
+	for key, text := range templates {
+		tmpl, err := template.New(key).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("tag_templates: %s", err)
+		}
+		if err := tmpl.Execute(ioutil.Discard, psinfo.Process{}); err != nil {
+			return nil, fmt.Errorf("tag_templates: %s", err)
+		}
+		compiled[key] = tmpl
+	}
+	return compiled, nil
+}
+
+// processStates maps the process state codes of the stat column to the
+// summary field counting the processes in that state. macOS marks the
+// uninterruptible processes with U rather than D, and the BSDs use I for the
+// processes sleeping for more than 20 seconds.
+var processStates = map[byte]string{
+	'R': "running",
+	'S': "sleeping",
+	'D': "uninterruptible",
+	'U': "uninterruptible",
+	'Z': "zombie",
+	'T': "stopped",
+	't': "stopped",
+	'I': "idle",
+}
+
+// addSummary stores in acc a single metric with the process table totals
+// and the number of processes in each state.
+func (p *PS) addSummary(acc telegraf.Accumulator, processes []psinfo.Process, now time.Time) {
+	var threads, rss, vsz int
+	var mem, cpu float64
+	states := map[string]int{"other": 0}
+	for _, state := range processStates {
+		states[state] = 0
+	}
+	for _, process := range processes {
+		threads += process.Nlwp
+		rss += process.Rss
+		vsz += process.Vsz
+		mem += process.Mem
+		cpu += process.CPU
+		if process.Stat == "" {
+			continue
+		}
+		state, ok := processStates[process.Stat[0]]
+		if !ok {
+			state = "other"
+		}
+		states[state]++
+	}
+
+	fields := map[string]interface{}{
+		"processes": len(processes),
+		"threads":   threads,
+		"rss":       rss,
+		"vsz":       vsz,
+		"mem":       mem,
+		"cpu":       cpu,
+	}
+	for state, count := range states {
+		fields[state] = count
+	}
+	acc.AddFields(p.SummaryMeasurement, fields, map[string]string{"plugin": tag}, now)
+}
+
+// filterFields removes from fields the keys not selected by the fields
+// option.
+func (p *PS) filterFields(fields map[string]interface{}) {
+	if p.fieldFilter == nil {
+		return
+	}
+	for key := range fields {
+		if !p.fieldFilter.Match(key) {
+			delete(fields, key)
+		}
+	}
+}
+
+// keepsAny reports whether the fields option keeps any of fields, so that
+// the information behind fields that are all dropped is not read for
+// nothing.
+func (p *PS) keepsAny(fields ...string) bool {
+	if p.fieldFilter == nil {
+		return true
+	}
+	for _, field := range fields {
+		if p.fieldFilter.Match(field) {
+			return true
+		}
+	}
+	return false
+}
+
+// collect lists the processes through the configured backend, reading
+// them by deadline. A failed attempt is retried up to p.Retries times,
+// doubling the delay between attempts, as long as deadline has not passed;
+// a zero deadline does not limit the retries.
+func (p *PS) collect(deadline time.Time) ([]psinfo.Process, error) {
+	var err error
+	backoff := p.RetryBackoff.Duration
+	attempts := 0
+	for {
+		var processes []psinfo.Process
+		processes, err = p.collector.Select(deadline)
+		attempts++
+		if err == nil {
+			return processes, nil
+		}
+		if attempts > p.Retries {
+			break
+		}
+		delay := backoff
+		if !deadline.IsZero() {
+			remaining := deadline.Sub(p.clock.Now())
+			if remaining <= 0 {
+				break
+			}
+			if delay > remaining {
+				delay = remaining
+			}
+		}
+		p.clock.Sleep(delay)
+		backoff *= 2
+		if !deadline.IsZero() && !p.clock.Now().Before(deadline) {
+			break
+		}
 	}
-	user := results[0][9]
-	status := results[0][10]
 
-	return &psInfo{
-		pid,
-		ppid,
-		command,
-		args,
-		rss,
-		vsize,
-		mem,
-		cpu,
-		user,
-		status,
-	}, nil
+	return nil, fmt.Errorf("failed after %d attempts: %w", attempts, err)
 }
@@ -0,0 +1,342 @@
+package ps
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/gpapag/telegraf-plugins/pkg/psinfo"
+	"github.com/gpapag/telegraf-plugins/pkg/psinfo/psinfotest"
+)
+
+// fakeRunner is a Runner standing in for ps. It fails its first calls with
+// errs, then prints processes, values by column spec, in the columns of the
+// format of the command.
+type fakeRunner struct {
+	mu        sync.Mutex
+	errs      []error
+	processes []map[string]string
+	commands  []string
+}
+
+func (r *fakeRunner) Run(command string, timeout time.Duration) ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.commands = append(r.commands, command)
+	if len(r.errs) > 0 {
+		err := r.errs[0]
+		r.errs = r.errs[1:]
+		return nil, err
+	}
+
+	// The format is the last argument, as in pid=,ppid=,comm=,args=, and
+	// ends the quoted command of ssh for remote hosts.
+	format := strings.TrimSuffix(command[strings.LastIndex(command, " ")+1:], "'")
+	var out strings.Builder
+	for _, process := range r.processes {
+		var values []string
+		for _, spec := range strings.Split(format, ",") {
+			value, ok := process[strings.TrimSuffix(spec, "=")]
+			if !ok {
+				value = "-"
+			}
+			values = append(values, value)
+		}
+		out.WriteString(strings.Join(values, " ") + "\n")
+	}
+	return []byte(out.String()), nil
+}
+
+// calls returns the number of commands run by r.
+func (r *fakeRunner) calls() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.commands)
+}
+
+// fakeClock is a Clock whose time only moves when told to, or when slept
+// on. It records the durations slept.
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	sleeps []time.Duration
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sleeps = append(c.sleeps, d)
+	c.now = c.now.Add(d)
+}
+
+// advance moves the time of c by d, backwards if d is negative.
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// testProcesses are the processes printed by the fakeRunner of the tests.
+var testProcesses = []map[string]string{
+	{
+		"pid": "1", "ppid": "0", "nlwp": "1", "rss": "11264", "vsz": "169352",
+		"%mem": "0.1", "%cpu": "0.0", "psr": "3", "ruser": "root", "etimes": "86400",
+		"ni": "0", "priority": "20", "policy": "TS", "tty": "?", "sid": "1", "pgid": "1",
+		"stat": "Ss", "time": "00:00:05", "comm": "systemd", "args": "/sbin/init splash",
+	},
+	{
+		"pid": "4242", "ppid": "1", "nlwp": "12", "rss": "524288", "vsz": "2097152",
+		"%mem": "3.2", "%cpu": "45.5", "psr": "7", "ruser": "www-data", "etimes": "3600",
+		"ni": "-5", "priority": "15", "policy": "FF", "tty": "pts/3", "sid": "4242", "pgid": "4242",
+		"stat": "Rl+", "time": "00:27:18", "comm": "nginx", "args": "nginx: worker process",
+	},
+}
+
+// newTestPS returns a PS running the ps backend through runner, with the
+// procps-ng columns whatever the host, and reading the cpu times of the
+// processes from an empty proc filesystem, so that those of ps are kept.
+func newTestPS(t *testing.T, runner Runner, clock Clock) *PS {
+	p := newPS(runner, clock)
+	p.Backend = backendPS
+	p.Variant = "procps-ng"
+	p.ProcRoot = psinfotest.NewProc(t, testHost).Root
+	return p
+}
+
+func TestGather(t *testing.T) {
+	runner := &fakeRunner{processes: testProcesses}
+	p := newTestPS(t, runner, newFakeClock())
+
+	var acc testutil.Accumulator
+	if err := p.Gather(&acc); err != nil {
+		t.Fatal(err)
+	}
+	if len(acc.Errors) > 0 {
+		t.Fatalf("errors: %v", acc.Errors)
+	}
+	if !strings.HasPrefix(runner.commands[0], "/bin/ps -axo pid=,ppid=,") {
+		t.Errorf("command %q", runner.commands[0])
+	}
+
+	m := findProcessMetric(t, p, &acc, "4242")
+	expectedTags := map[string]string{"plugin": tag, "pid": "4242", "comm": "nginx", "user": "www-data"}
+	if !reflect.DeepEqual(m.Tags, expectedTags) {
+		t.Errorf("tags %v, expected %v", m.Tags, expectedTags)
+	}
+	expected := map[string]interface{}{
+		"ppid":           1,
+		"args":           "nginx: worker process",
+		"threads":        12,
+		"rss":            524288,
+		"vsz":            2097152,
+		"mem":            3.2,
+		"cpu":            45.5,
+		"processor":      7,
+		"status":         "Rl+",
+		"uptime_seconds": 3600,
+		"nice":           -5,
+		"priority":       15,
+		"sched_policy":   "SCHED_FIFO",
+		"tty":            "pts/3",
+		"sid":            4242,
+		"pgid":           4242,
+	}
+	for name, value := range expected {
+		if m.Fields[name] != value {
+			t.Errorf("field %s: %#v, expected %#v", name, m.Fields[name], value)
+		}
+	}
+
+	m = findProcessMetric(t, p, &acc, "1")
+	if m.Tags["comm"] != "systemd" || m.Fields["args"] != "/sbin/init splash" {
+		t.Errorf("metric of init %v %v", m.Tags, m.Fields)
+	}
+}
+
+// failure returns the failure field of the metric gathered into acc when ps
+// fails, failing t if there is none.
+func failure(t *testing.T, acc *testutil.Accumulator) string {
+	t.Helper()
+	for _, m := range acc.Metrics {
+		if m.Measurement == fieldName {
+			if value, ok := m.Fields[failureField].(string); ok {
+				return value
+			}
+		}
+	}
+	t.Fatalf("no %s field", failureField)
+	return ""
+}
+
+func TestGatherExecFailed(t *testing.T) {
+	runner := &fakeRunner{
+		errs:      []error{fmt.Errorf("%w: exit status 1", psinfo.ErrExecFailed)},
+		processes: testProcesses,
+	}
+	clock := newFakeClock()
+	p := newTestPS(t, runner, clock)
+
+	var acc testutil.Accumulator
+	err := p.Gather(&acc)
+	if !errors.Is(err, psinfo.ErrExecFailed) {
+		t.Fatalf("error %v, expected %v", err, psinfo.ErrExecFailed)
+	}
+	if len(acc.Errors) != 1 || !errors.Is(acc.Errors[0], psinfo.ErrExecFailed) {
+		t.Errorf("errors %v, expected one %v", acc.Errors, psinfo.ErrExecFailed)
+	}
+	if f := failure(t, &acc); !strings.Contains(f, "failed after 1 attempts") {
+		t.Errorf("failure %q", f)
+	}
+	if runner.calls() != 1 || len(clock.sleeps) != 0 {
+		t.Errorf("%d calls and %d retries, expected 1 call and none", runner.calls(), len(clock.sleeps))
+	}
+}
+
+func TestGatherTimeout(t *testing.T) {
+	timeout := fmt.Errorf("%w: ps after 5s", psinfo.ErrTimeout)
+
+	t.Run("retries exhausted", func(t *testing.T) {
+		runner := &fakeRunner{errs: []error{timeout, timeout, timeout}, processes: testProcesses}
+		clock := newFakeClock()
+		p := newTestPS(t, runner, clock)
+		p.Retries = 2
+
+		var acc testutil.Accumulator
+		err := p.Gather(&acc)
+		if !errors.Is(err, psinfo.ErrTimeout) {
+			t.Fatalf("error %v, expected %v", err, psinfo.ErrTimeout)
+		}
+		if f := failure(t, &acc); !strings.Contains(f, "failed after 3 attempts") {
+			t.Errorf("failure %q", f)
+		}
+		if runner.calls() != 3 {
+			t.Errorf("%d calls, expected 3", runner.calls())
+		}
+		backoffs := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond}
+		if !reflect.DeepEqual(clock.sleeps, backoffs) {
+			t.Errorf("backoffs %v, expected %v", clock.sleeps, backoffs)
+		}
+	})
+
+	t.Run("retry succeeds", func(t *testing.T) {
+		runner := &fakeRunner{errs: []error{timeout}, processes: testProcesses}
+		clock := newFakeClock()
+		p := newTestPS(t, runner, clock)
+		p.Retries = 2
+
+		var acc testutil.Accumulator
+		if err := p.Gather(&acc); err != nil {
+			t.Fatal(err)
+		}
+		if len(acc.Errors) > 0 {
+			t.Errorf("errors: %v", acc.Errors)
+		}
+		if runner.calls() != 2 {
+			t.Errorf("%d calls, expected 2", runner.calls())
+		}
+		if !reflect.DeepEqual(clock.sleeps, []time.Duration{100 * time.Millisecond}) {
+			t.Errorf("backoffs %v, expected [100ms]", clock.sleeps)
+		}
+		findProcessMetric(t, p, &acc, "4242")
+	})
+}
+
+// slowRunner is a fakeRunner whose commands take duration on clock.
+type slowRunner struct {
+	fakeRunner
+	clock    *fakeClock
+	duration time.Duration
+}
+
+func (r *slowRunner) Run(command string, timeout time.Duration) ([]byte, error) {
+	r.clock.advance(r.duration)
+	return r.fakeRunner.Run(command, timeout)
+}
+
+func TestGatherRetryDeadline(t *testing.T) {
+	timeout := fmt.Errorf("%w: ps after 5s", psinfo.ErrTimeout)
+	errs := []error{timeout, timeout, timeout, timeout, timeout}
+
+	tests := []struct {
+		name     string
+		timeout  time.Duration
+		duration time.Duration
+		calls    int
+		backoffs []time.Duration
+	}{
+		{
+			// The second retry would start after the timeout.
+			name:     "backoff cut at the timeout",
+			timeout:  5 * time.Second,
+			calls:    2,
+			backoffs: []time.Duration{2 * time.Second, 3 * time.Second},
+		},
+		{
+			name:     "attempt past the timeout",
+			timeout:  5 * time.Second,
+			duration: 5 * time.Second,
+			calls:    1,
+		},
+		{
+			name:     "no timeout",
+			calls:    4,
+			backoffs: []time.Duration{2 * time.Second, 4 * time.Second, 8 * time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := newFakeClock()
+			runner := &slowRunner{fakeRunner{errs: errs}, clock, tt.duration}
+			p := newTestPS(t, runner, clock)
+			p.Retries = 3
+			p.RetryBackoff.Duration = 2 * time.Second
+			p.Timeout.Duration = tt.timeout
+
+			var acc testutil.Accumulator
+			if err := p.Gather(&acc); !errors.Is(err, psinfo.ErrTimeout) {
+				t.Fatalf("error %v, expected %v", err, psinfo.ErrTimeout)
+			}
+			if f := failure(t, &acc); !strings.Contains(f, fmt.Sprintf("failed after %d attempts", tt.calls)) {
+				t.Errorf("failure %q", f)
+			}
+			if runner.calls() != tt.calls {
+				t.Errorf("%d calls, expected %d", runner.calls(), tt.calls)
+			}
+			if !reflect.DeepEqual(clock.sleeps, tt.backoffs) {
+				t.Errorf("backoffs %v, expected %v", clock.sleeps, tt.backoffs)
+			}
+		})
+	}
+}
+
+func TestGatherSudo(t *testing.T) {
+	runner := &fakeRunner{processes: testProcesses}
+	p := newTestPS(t, runner, newFakeClock())
+	p.UseSudo = true
+
+	var acc testutil.Accumulator
+	if err := p.Gather(&acc); err != nil {
+		t.Fatal(err)
+	}
+	// Only ps goes through sudo; the files of /proc never do.
+	if len(runner.commands) != 1 || !strings.HasPrefix(runner.commands[0], "sudo -n /bin/ps ") {
+		t.Errorf("commands %q", runner.commands)
+	}
+}
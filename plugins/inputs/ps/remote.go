@@ -0,0 +1,166 @@
+package ps
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kballard/go-shellquote"
+
+	"github.com/gpapag/telegraf-plugins/pkg/psinfo"
+
+	"github.com/influxdata/telegraf"
+)
+
+// remoteHost is a host of the remote_hosts option, whose processes are
+// listed by running ps over ssh.
+type remoteHost struct {
+	hostname  string
+	collector *psCollector
+}
+
+// parseRemoteHost splits an entry of remote_hosts, [user@]host[:port],
+// into the destination and port given to ssh and the bare hostname.
+func parseRemoteHost(entry string) (destination, port, hostname string, err error) {
+	if entry == "" || strings.HasPrefix(entry, "-") || strings.ContainsAny(entry, " \t") {
+		return "", "", "", fmt.Errorf("invalid host %q", entry)
+	}
+	destination = entry
+	if host, p, err := net.SplitHostPort(entry); err == nil {
+		if _, err := strconv.ParseUint(p, 10, 16); err != nil {
+			return "", "", "", fmt.Errorf("invalid port in host %q", entry)
+		}
+		destination, port = host, p
+	}
+	hostname = destination
+	if i := strings.LastIndexByte(hostname, '@'); i >= 0 {
+		hostname = hostname[i+1:]
+	}
+	return destination, port, strings.Trim(hostname, "[]"), nil
+}
+
+// remoteHosts returns the hosts of the remote_hosts option, each listing
+// its processes with the ps command of p run over ssh. ssh authenticates
+// with keys only and refuses the hosts whose key is not already known,
+// so that no prompt can stall a gather.
+func (p *PS) remoteHosts() ([]remoteHost, error) {
+	var hosts []remoteHost
+	for _, entry := range p.RemoteHosts {
+		destination, port, hostname, err := parseRemoteHost(entry)
+		if err != nil {
+			return nil, err
+		}
+
+		// ConnectTimeout only takes whole seconds.
+		connect := int(math.Ceil(p.RemoteTimeout.Duration.Seconds()))
+		args := []string{p.SSHPath,
+			"-o", "BatchMode=yes",
+			"-o", "StrictHostKeyChecking=yes",
+			"-o", "ConnectTimeout=" + strconv.Itoa(connect),
+		}
+		if p.SSHKeyFile != "" {
+			args = append(args, "-i", p.SSHKeyFile, "-o", "IdentitiesOnly=yes")
+		}
+		if p.SSHKnownHosts != "" {
+			args = append(args, "-o", "UserKnownHostsFile="+p.SSHKnownHosts)
+		}
+		if port != "" {
+			args = append(args, "-p", port)
+		}
+		args = append(args, "--", destination, p.psCommand())
+
+		hosts = append(hosts, remoteHost{
+			hostname: hostname,
+			collector: &psCollector{
+				runner:  p.runner,
+				command: shellquote.Join(args...),
+				columns: p.columns,
+				timeout: p.RemoteTimeout.Duration,
+				fixed:   p.ParseMode == parseModeFixedWidth,
+			},
+		})
+	}
+	return hosts, nil
+}
+
+// remoteProcesses are the processes of the remote hosts, listed in the
+// background of a gather.
+type remoteProcesses struct {
+	wg        sync.WaitGroup
+	processes [][]psinfo.Process
+	errs      []error
+}
+
+// selectRemotes starts listing the processes of the remote hosts, all at
+// once and each bounded by the remote_timeout option, so that they are
+// read while the local processes are.
+func (p *PS) selectRemotes(deadline time.Time) *remoteProcesses {
+	remotes := &remoteProcesses{
+		processes: make([][]psinfo.Process, len(p.remotes)),
+		errs:      make([]error, len(p.remotes)),
+	}
+	for i := range p.remotes {
+		remotes.wg.Add(1)
+		go func(i int) {
+			defer remotes.wg.Done()
+			remotes.processes[i], remotes.errs[i] = p.remotes[i].collector.Select(deadline)
+		}(i)
+	}
+	return remotes
+}
+
+// addRemotes waits for the remote hosts listed by remotes and stores in acc
+// a per_process metric for every selected process, tagged with the
+// hostname. A host failing only loses its own metrics. It returns the
+// number of processes reported.
+func (p *PS) addRemotes(acc telegraf.Accumulator, sel *selection, remotes *remoteProcesses) int {
+	remotes.wg.Wait()
+
+	var emitted int
+	now := p.clock.Now().UTC()
+	for i, host := range p.remotes {
+		if remotes.errs[i] != nil {
+			acc.AddError(p.errorf("host %s: unable to gather metrics: %w", host.hostname, remotes.errs[i]))
+			continue
+		}
+		selected := remotes.processes[i]
+		if p.ExcludeKernelThreads {
+			selected = excludeKernelThreads(selected)
+		}
+		selected = selectProcesses(sel, selected)
+		p.sortProcesses(selected)
+		for _, process := range selected {
+			tags, fields := p.remoteMetric(host.hostname, process, now)
+			acc.AddFields(p.DetailMeasurement, fields, tags, now)
+		}
+		emitted += len(selected)
+	}
+	return emitted
+}
+
+// remoteMetric returns the tags and fields of the metric of process of a
+// remote host. Only the columns of ps are known, as the other fields are
+// read from the local /proc.
+func (p *PS) remoteMetric(hostname string, process psinfo.Process, now time.Time) (map[string]string, map[string]interface{}) {
+	tags := map[string]string{
+		"plugin": tag,
+		"host":   hostname,
+		"pid":    strconv.Itoa(process.Pid),
+		"comm":   process.Comm,
+	}
+	p.addUserTags(tags, process)
+	p.addTemplateTags(tags, process)
+	fields := p.columnFields(process, now)
+	for name, value := range p.extraFields(process) {
+		fields[name] = value
+	}
+	for _, field := range p.remoteUnsupported {
+		delete(fields, field)
+	}
+	p.filterFields(fields)
+	return tags, fields
+}
@@ -0,0 +1,133 @@
+package ps
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/gpapag/telegraf-plugins/pkg/psinfo"
+)
+
+// remoteRunner is a Runner answering for the local host and for remote hosts
+// over ssh with the same processes. Remote hosts only answer once the local
+// ps ran, as when they are slower than the local host.
+type remoteRunner struct {
+	fakeRunner
+	once  sync.Once
+	local chan struct{}
+}
+
+func newRemoteRunner() *remoteRunner {
+	return &remoteRunner{
+		fakeRunner: fakeRunner{processes: testProcesses},
+		local:      make(chan struct{}),
+	}
+}
+
+func (r *remoteRunner) Run(command string, timeout time.Duration) ([]byte, error) {
+	if !strings.HasPrefix(command, "/usr/bin/ssh ") {
+		defer r.once.Do(func() { close(r.local) })
+		return r.fakeRunner.Run(command, timeout)
+	}
+	select {
+	case <-r.local:
+		return r.fakeRunner.Run(command, timeout)
+	case <-time.After(10 * time.Second):
+		return nil, psinfo.ErrTimeout
+	}
+}
+
+// newRemotePS returns a PS listing the processes of runner locally and on
+// one remote host.
+func newRemotePS(t *testing.T, runner Runner) *PS {
+	p := newTestPS(t, runner, newFakeClock())
+	p.RemoteHosts = []string{"telegraf@appliance"}
+	return p
+}
+
+// gatherStat returns the field name of the ps_gather metric gathered into
+// acc.
+func gatherStat(t *testing.T, acc *testutil.Accumulator, name string) interface{} {
+	t.Helper()
+	for _, m := range acc.Metrics {
+		if m.Measurement == gatherMeasurement {
+			return m.Fields[name]
+		}
+	}
+	t.Fatalf("no %s metric", gatherMeasurement)
+	return nil
+}
+
+func TestGatherRemote(t *testing.T) {
+	p := newRemotePS(t, newRemoteRunner())
+
+	var acc testutil.Accumulator
+	if err := p.Gather(&acc); err != nil {
+		t.Fatal(err)
+	}
+	if len(acc.Errors) > 0 {
+		t.Fatalf("errors: %v", acc.Errors)
+	}
+
+	var local, remote int
+	for _, m := range acc.Metrics {
+		if m.Measurement != p.DetailMeasurement {
+			continue
+		}
+		if m.Tags["host"] == "appliance" {
+			remote++
+		} else {
+			local++
+		}
+	}
+	if local != 2 || remote != 2 {
+		t.Errorf("%d local and %d remote processes, expected 2 and 2", local, remote)
+	}
+	if emitted := gatherStat(t, &acc, "processes_emitted"); emitted != 4 {
+		t.Errorf("processes_emitted %v, expected 4", emitted)
+	}
+}
+
+func TestGatherRemoteLimited(t *testing.T) {
+	// The budget is that of the local metrics, leaving none for the remote
+	// host.
+	var acc testutil.Accumulator
+	if err := newTestPS(t, &fakeRunner{processes: testProcesses}, newFakeClock()).Gather(&acc); err != nil {
+		t.Fatal(err)
+	}
+	var budget int
+	for _, m := range acc.Metrics {
+		if m.Measurement == fieldName {
+			budget += len(m.Fields)
+		}
+	}
+
+	p := newRemotePS(t, newRemoteRunner())
+	p.MaxFieldsPerGather = budget
+	acc = testutil.Accumulator{}
+	if err := p.Gather(&acc); err != nil {
+		t.Fatal(err)
+	}
+
+	var truncated bool
+	for _, m := range acc.Metrics {
+		if m.Measurement != fieldName {
+			continue
+		}
+		if m.Tags["host"] == "appliance" {
+			t.Errorf("remote metric %v emitted beyond max_fields_per_gather", m.Tags)
+		}
+		if m.Fields["truncated"] == true {
+			truncated = true
+			if m.Fields["dropped_metrics"] != 2 {
+				t.Errorf("%v metrics dropped, expected 2", m.Fields["dropped_metrics"])
+			}
+		}
+	}
+	if !truncated {
+		t.Error("no truncation reported")
+	}
+}
@@ -0,0 +1,54 @@
+package ps
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/kballard/go-shellquote"
+
+	"github.com/gpapag/telegraf-plugins/pkg/psinfo"
+
+	"github.com/influxdata/telegraf/internal"
+)
+
+// Runner runs a command line and returns its standard output. PS uses a
+// Runner for every command it executes, so that canned outputs and failures
+// can be substituted for real commands.
+type Runner interface {
+	Run(command string, timeout time.Duration) ([]byte, error)
+}
+
+// execRunner is a Runner executing commands as child processes.
+type execRunner struct{}
+
+// Run executes command, killing it if it does not complete within timeout.
+// Errors wrap psinfo.ErrTimeout, psinfo.ErrPermission or
+// psinfo.ErrExecFailed.
+func (execRunner) Run(command string, timeout time.Duration) ([]byte, error) {
+	splitCmd, err := shellquote.Split(command)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", psinfo.ErrExecFailed, err)
+	}
+	if len(splitCmd) == 0 {
+		return nil, fmt.Errorf("%w: empty command", psinfo.ErrExecFailed)
+	}
+
+	var out bytes.Buffer
+	cmd := exec.Command(splitCmd[0], splitCmd[1:]...)
+	cmd.Stdout = &out
+	if err := internal.RunTimeout(cmd, timeout); err != nil {
+		switch {
+		case err == internal.ErrTimeout:
+			return nil, fmt.Errorf("%w: %s after %s", psinfo.ErrTimeout, splitCmd[0], timeout)
+		case os.IsPermission(err):
+			return nil, fmt.Errorf("%w: %v", psinfo.ErrPermission, err)
+		default:
+			return nil, fmt.Errorf("%w: %v", psinfo.ErrExecFailed, err)
+		}
+	}
+
+	return out.Bytes(), nil
+}
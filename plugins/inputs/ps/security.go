@@ -0,0 +1,20 @@
+package ps
+
+// addSecurityTags adds to tags the security context of process pid given
+// by SELinux or AppArmor, with the security_context option. Processes on
+// hosts without such a module, and those whose context cannot be read, get
+// no tag; those denied are recorded in denied.
+func (p *PS) addSecurityTags(tags map[string]string, pid int, denied *denials) {
+	if !p.SecurityContext {
+		return
+	}
+
+	context, err := p.procFS.ReadSecurityContext(pid)
+	if err != nil {
+		denied.check("security_context", err)
+		return
+	}
+	if context != "" {
+		tags["security_context"] = context
+	}
+}
@@ -0,0 +1,252 @@
+package ps
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gpapag/telegraf-plugins/pkg/psinfo"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/toml"
+)
+
+const (
+	selectionMeasurement = `ps_selection`
+	selectionSampleSize  = 5
+)
+
+// selection holds the rules deciding which processes are reported. A
+// process is selected when it runs as one of Users, given as numeric ids
+// when user names are not collected, its command or
+// arguments match one of Patterns and match none of ExcludePatterns. Empty
+// Users or Patterns select every process.
+type selection struct {
+	Patterns        []string `toml:"patterns"`
+	ExcludePatterns []string `toml:"exclude_patterns"`
+	Users           []string `toml:"users"`
+
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+	users   map[string]bool
+}
+
+// compile prepares the regular expressions and user set of s.
+func (s *selection) compile() error {
+	var err error
+	s.include, err = compilePatterns(s.Patterns)
+	if err != nil {
+		return fmt.Errorf("patterns: %s", err)
+	}
+	s.exclude, err = compilePatterns(s.ExcludePatterns)
+	if err != nil {
+		return fmt.Errorf("exclude_patterns: %s", err)
+	}
+
+	s.users = make(map[string]bool, len(s.Users))
+	for _, user := range s.Users {
+		s.users[user] = true
+	}
+
+	return nil
+}
+
+// match reports whether process is selected by s.
+func (s *selection) match(process psinfo.Process) bool {
+	if len(s.users) > 0 && !s.users[userKey(process)] {
+		return false
+	}
+	if len(s.include) > 0 && !matchAny(s.include, process) {
+		return false
+	}
+	return !matchAny(s.exclude, process)
+}
+
+// userKey returns the name of the user owning process, or its numeric id
+// when names are not collected.
+func userKey(process psinfo.Process) string {
+	if process.Ruser == "" {
+		return strconv.Itoa(process.Ruid)
+	}
+	return process.Ruser
+}
+
+// compilePatterns compiles each of patterns into a regular expression.
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	var compiled []*regexp.Regexp
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// nonEmpty returns pattern as a list, empty if pattern is empty.
+func nonEmpty(pattern string) []string {
+	if pattern == "" {
+		return nil
+	}
+	return []string{pattern}
+}
+
+// matchAny reports whether the command or the arguments of process match
+// any of the regular expressions in res.
+func matchAny(res []*regexp.Regexp, process psinfo.Process) bool {
+	for _, re := range res {
+		if re.MatchString(process.Comm) || re.MatchString(process.Args) {
+			return true
+		}
+	}
+	return false
+}
+
+// reloadSelection reads the selection file again when it was modified since
+// it was last loaded, and returns the selection in effect, nil meaning that
+// every process is selected. The pattern and exclude_pattern options are
+// merged into the patterns and exclude_patterns of the file. On failure the
+// previously loaded selection stays in effect.
+func (p *PS) reloadSelection() (*selection, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	fileSelection, err := p.loadSelectionFile()
+	if err != nil && fileSelection == nil && p.SelectionFile != "" {
+		return nil, err
+	}
+	return p.mergeSelection(fileSelection), err
+}
+
+// mergeSelection returns fileSelection extended with the pattern and
+// exclude_pattern options. The merged selection is kept until the file
+// selection changes.
+func (p *PS) mergeSelection(fileSelection *selection) *selection {
+	if p.Pattern == "" && p.ExcludePattern == "" {
+		return fileSelection
+	}
+	if p.mergedSelection != nil && p.mergedFrom == fileSelection {
+		return p.mergedSelection
+	}
+
+	var merged selection
+	if fileSelection != nil {
+		merged.Patterns = append(merged.Patterns, fileSelection.Patterns...)
+		merged.ExcludePatterns = append(merged.ExcludePatterns, fileSelection.ExcludePatterns...)
+		merged.Users = append(merged.Users, fileSelection.Users...)
+	}
+	merged.Patterns = append(merged.Patterns, nonEmpty(p.Pattern)...)
+	merged.ExcludePatterns = append(merged.ExcludePatterns, nonEmpty(p.ExcludePattern)...)
+	// The options were validated by setup and the file when it was loaded.
+	if err := merged.compile(); err != nil {
+		return fileSelection
+	}
+
+	p.mergedSelection = &merged
+	p.mergedFrom = fileSelection
+	return p.mergedSelection
+}
+
+// loadSelectionFile reads the selection file again when it was modified
+// since it was last loaded, and returns the selection it holds.
+func (p *PS) loadSelectionFile() (*selection, error) {
+	if p.SelectionFile == "" {
+		return nil, nil
+	}
+
+	info, err := os.Stat(p.SelectionFile)
+	if err != nil {
+		return p.fileSelection, fmt.Errorf("selection_file: %w", err)
+	}
+	if p.fileSelection != nil && info.ModTime().Equal(p.selectionModTime) {
+		return p.fileSelection, nil
+	}
+
+	data, err := ioutil.ReadFile(p.SelectionFile)
+	if err != nil {
+		return p.fileSelection, fmt.Errorf("selection_file: %w", err)
+	}
+
+	var s selection
+	if err := toml.Unmarshal(data, &s); err != nil {
+		return p.fileSelection, fmt.Errorf("selection_file %s: %s", p.SelectionFile, err)
+	}
+	if err := s.compile(); err != nil {
+		return p.fileSelection, fmt.Errorf("selection_file %s: %s", p.SelectionFile, err)
+	}
+
+	p.fileSelection = &s
+	p.selectionModTime = info.ModTime()
+	return p.fileSelection, nil
+}
+
+// selectProcesses returns the processes selected by sel.
+func selectProcesses(sel *selection, processes []psinfo.Process) []psinfo.Process {
+	if sel == nil {
+		return processes
+	}
+
+	var selected []psinfo.Process
+	for _, process := range processes {
+		if sel.match(process) {
+			selected = append(selected, process)
+		}
+	}
+	return selected
+}
+
+// addSelectionReport stores in acc one metric per pattern, exclude pattern
+// and user of sel, counting the processes each of them matches on its own
+// among all the processes collected.
+func (p *PS) addSelectionReport(acc telegraf.Accumulator, sel *selection, processes []psinfo.Process, now time.Time) {
+	if sel == nil {
+		return
+	}
+
+	report := func(group string, filter string, match func(psinfo.Process) bool) {
+		var matched int
+		var sample []string
+		seen := make(map[string]bool)
+		for _, process := range processes {
+			if !match(process) {
+				continue
+			}
+			matched++
+			if len(sample) < selectionSampleSize && !seen[process.Comm] {
+				seen[process.Comm] = true
+				sample = append(sample, process.Comm)
+			}
+		}
+
+		tags := p.selfTags()
+		tags["group"] = group
+		tags["filter"] = filter
+		fields := map[string]interface{}{
+			"matched": matched,
+			"sample":  strings.Join(sample, ","),
+		}
+		acc.AddFields(selectionMeasurement, fields, tags, now)
+	}
+
+	for i, re := range sel.include {
+		report("patterns", sel.Patterns[i], func(process psinfo.Process) bool {
+			return matchAny([]*regexp.Regexp{re}, process)
+		})
+	}
+	for i, re := range sel.exclude {
+		report("exclude_patterns", sel.ExcludePatterns[i], func(process psinfo.Process) bool {
+			return matchAny([]*regexp.Regexp{re}, process)
+		})
+	}
+	for _, user := range sel.Users {
+		user := user
+		report("users", user, func(process psinfo.Process) bool {
+			return userKey(process) == user
+		})
+	}
+}
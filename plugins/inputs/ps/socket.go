@@ -0,0 +1,87 @@
+package ps
+
+import (
+	"sync"
+
+	"github.com/gpapag/telegraf-plugins/pkg/psinfo"
+)
+
+// socketFields are the per_process fields counting the sockets of a
+// process by protocol.
+var socketFields = []string{"sockets_tcp", "sockets_udp", "sockets_unix"}
+
+// socketIndex holds the sockets of the network namespaces met during a
+// gather, each read once however many processes share the namespace.
+type socketIndex struct {
+	procFS psinfo.ProcFS
+
+	// mu guards namespaces, as processes are read concurrently.
+	mu         sync.Mutex
+	namespaces map[string]map[uint64]string
+}
+
+// newSocketIndex returns a pointer to a new socketIndex reading procFS.
+func newSocketIndex(procFS psinfo.ProcFS) *socketIndex {
+	return &socketIndex{
+		procFS:     procFS,
+		namespaces: make(map[string]map[uint64]string),
+	}
+}
+
+// sockets returns the protocol of the sockets of the network namespace of
+// process pid by inode.
+func (s *socketIndex) sockets(pid int) (map[uint64]string, error) {
+	ns, err := s.procFS.ReadNetNamespace(pid)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if sockets, ok := s.namespaces[ns]; ok {
+		return sockets, nil
+	}
+	sockets, err := s.procFS.ReadSockets(pid)
+	if err != nil {
+		return nil, err
+	}
+	s.namespaces[ns] = sockets
+	return sockets, nil
+}
+
+// socketCounts returns the number of tcp, udp and unix sockets process pid
+// has open, found by looking up the inodes of its socket descriptors in
+// the tables of its network namespace. Processes whose descriptors cannot
+// be listed, such as those of other users, yield no fields and are
+// recorded in denied.
+func (p *PS) socketCounts(index *socketIndex, pid int, denied *denials) map[string]interface{} {
+	if index == nil {
+		return nil
+	}
+	targets, err := p.procFS.ReadFDTargets(pid)
+	if err != nil {
+		denied.check("sockets", err)
+		return nil
+	}
+	sockets, err := index.sockets(pid)
+	if err != nil {
+		return nil
+	}
+
+	counts := map[string]int{"tcp": 0, "udp": 0, "unix": 0}
+	for _, target := range targets {
+		inode, ok := psinfo.SocketInode(target)
+		if !ok {
+			continue
+		}
+		if protocol, ok := sockets[inode]; ok {
+			counts[protocol]++
+		}
+	}
+
+	fields := make(map[string]interface{}, len(counts))
+	for protocol, count := range counts {
+		fields["sockets_"+protocol] = count
+	}
+	return fields
+}
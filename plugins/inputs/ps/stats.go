@@ -0,0 +1,42 @@
+package ps
+
+import (
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+const gatherMeasurement = `ps_gather`
+
+// gatherStats sums up a gather for the ps_gather metric.
+type gatherStats struct {
+	start time.Time
+	// seen is the number of processes listed by the backend, and emitted
+	// the number of processes reported in the detail metrics.
+	seen    int
+	emitted int
+	// parseErrors is the number of lines of ps output that were skipped.
+	parseErrors int
+	// traceOverflows is the number of times exec_tracing lost events.
+	traceOverflows int
+	// permissionDenied is the number of processes missing fields for lack
+	// of privileges.
+	permissionDenied int
+}
+
+// addGatherStats stores in acc the ps_gather metric describing the gather
+// summed up by stats, whether it succeeded or not.
+func (p *PS) addGatherStats(acc telegraf.Accumulator, stats *gatherStats) {
+	now := p.clock.Now()
+	fields := map[string]interface{}{
+		"gather_duration_ms": float64(now.Sub(stats.start)) / float64(time.Millisecond),
+		"processes_seen":     stats.seen,
+		"processes_emitted":  stats.emitted,
+		"parse_errors":       stats.parseErrors,
+		"permission_denied":  stats.permissionDenied,
+	}
+	if p.tracer != nil {
+		fields["trace_overflows"] = stats.traceOverflows
+	}
+	acc.AddFields(gatherMeasurement, fields, p.selfTags(), now.UTC())
+}
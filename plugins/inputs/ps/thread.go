@@ -0,0 +1,86 @@
+package ps
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gpapag/telegraf-plugins/pkg/psinfo"
+
+	"github.com/influxdata/telegraf"
+)
+
+const threadMeasurement = `ps_thread`
+
+// addThreads stores in acc one metric per thread of processes, read from
+// /proc/<pid>/task, with its state and cpu time. Processes not reached by
+// deadline, or exiting meanwhile, have no thread metrics.
+func (p *PS) addThreads(acc telegraf.Accumulator, processes []psinfo.Process, now, deadline time.Time) {
+	threads := make([][]psinfo.Stat, len(processes))
+	err := p.pool.forEach(len(processes), deadline, func(i int) {
+		threads[i], _ = p.procFS.ReadThreads(processes[i].Pid)
+	})
+	if err != nil {
+		acc.AddError(p.errorf("unable to read the threads: %w", err))
+	}
+
+	var all []psinfo.Stat
+	for _, t := range threads {
+		all = append(all, t...)
+	}
+	usage := p.threadUsage(all, now)
+
+	for i, process := range processes {
+		for _, thread := range threads[i] {
+			tags := map[string]string{
+				"plugin":      tag,
+				"pid":         strconv.Itoa(process.Pid),
+				"comm":        process.Comm,
+				"tid":         strconv.Itoa(thread.Pid),
+				"thread_name": thread.Comm,
+			}
+			fields := map[string]interface{}{
+				"state":     thread.State,
+				"cpu_time":  threadCPUTime(thread),
+				"processor": thread.Processor,
+			}
+			if cpu, ok := usage[thread.Pid]; ok {
+				fields["cpu_usage"] = cpu
+			}
+			acc.AddFields(threadMeasurement, fields, tags, now)
+		}
+	}
+}
+
+// threadCPUTime returns the cpu time used by thread, in seconds.
+func threadCPUTime(thread psinfo.Stat) float64 {
+	return float64(thread.Utime+thread.Stime) / psinfo.ClockTicks
+}
+
+// threadUsage returns the cpu usage of threads since the previous gather,
+// in percent of one cpu, by thread id, as cpuUsage does for processes.
+func (p *PS) threadUsage(threads []psinfo.Stat, now time.Time) map[int]float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if now.Before(p.threadsAt) {
+		return nil
+	}
+
+	usage := make(map[int]float64, len(threads))
+	elapsed := now.Sub(p.threadsAt).Seconds()
+	samples := make(map[int]cpuSample, len(threads))
+	for _, thread := range threads {
+		cpuTime := threadCPUTime(thread)
+		samples[thread.Pid] = cpuSample{comm: thread.Comm, cpuTime: cpuTime}
+
+		previous, ok := p.threadSamples[thread.Pid]
+		if !ok || previous.comm != thread.Comm || cpuTime < previous.cpuTime || elapsed <= 0 {
+			continue
+		}
+		usage[thread.Pid] = round1(100 * (cpuTime - previous.cpuTime) / elapsed)
+	}
+
+	p.threadSamples = samples
+	p.threadsAt = now
+	return usage
+}
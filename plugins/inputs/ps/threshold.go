@@ -0,0 +1,35 @@
+package ps
+
+import (
+	"github.com/gpapag/telegraf-plugins/pkg/psinfo"
+)
+
+// aboveThresholds returns the processes reaching the min_cpu_percent or the
+// min_rss_kb threshold; processes below every threshold set are idle noise.
+func (p *PS) aboveThresholds(processes []psinfo.Process, cpuUsage map[int]float64) []psinfo.Process {
+	if p.MinCPUPercent <= 0 && p.MinRSSKB <= 0 {
+		return processes
+	}
+
+	var kept []psinfo.Process
+	for _, process := range processes {
+		if p.MinCPUPercent > 0 && cpuPercent(process, cpuUsage) >= p.MinCPUPercent {
+			kept = append(kept, process)
+			continue
+		}
+		if p.MinRSSKB > 0 && process.Rss >= p.MinRSSKB {
+			kept = append(kept, process)
+		}
+	}
+	return kept
+}
+
+// cpuPercent returns the cpu usage of process over the interval, or its
+// lifetime average when the usage over the interval is not known yet, such
+// as on its first gather.
+func cpuPercent(process psinfo.Process, cpuUsage map[int]float64) float64 {
+	if usage, ok := cpuUsage[process.Pid]; ok {
+		return usage
+	}
+	return process.CPU
+}
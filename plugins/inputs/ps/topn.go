@@ -0,0 +1,70 @@
+package ps
+
+import (
+	"sort"
+	"time"
+
+	"github.com/gpapag/telegraf-plugins/pkg/psinfo"
+
+	"github.com/influxdata/telegraf"
+)
+
+// Rankings accepted by the top_n_by option.
+const (
+	topNByCPU = `cpu`
+	topNByRSS = `rss`
+	topNByVSZ = `vsz`
+	topNByFD  = `fd`
+)
+
+// topN returns the n heaviest processes as ranked by the top_n_by option,
+// heaviest first.
+func (p *PS) topN(processes []psinfo.Process, cpuUsage map[int]float64, n int) []psinfo.Process {
+	weights := make(map[int]float64, len(processes))
+	for _, process := range processes {
+		switch p.TopNBy {
+		case topNByCPU:
+			weights[process.Pid] = cpuPercent(process, cpuUsage)
+		case topNByRSS:
+			weights[process.Pid] = float64(process.Rss)
+		case topNByVSZ:
+			weights[process.Pid] = float64(process.Vsz)
+		case topNByFD:
+			// Processes whose descriptors cannot be listed rank last.
+			count, _ := p.procFS.CountFDs(process.Pid)
+			weights[process.Pid] = float64(count)
+		}
+	}
+
+	ranked := make([]psinfo.Process, len(processes))
+	copy(ranked, processes)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		wi, wj := weights[ranked[i].Pid], weights[ranked[j].Pid]
+		if wi != wj {
+			return wi > wj
+		}
+		return ranked[i].Pid < ranked[j].Pid
+	})
+	if len(ranked) > n {
+		ranked = ranked[:n]
+	}
+	return ranked
+}
+
+// capProcesses returns the p.MaxProcesses heaviest processes, ranked like
+// for top_n, and stores in acc how many processes were left out beyond
+// the cap.
+func (p *PS) capProcesses(acc telegraf.Accumulator, processes []psinfo.Process, cpuUsage map[int]float64, now time.Time) []psinfo.Process {
+	var overflow int
+	if len(processes) > p.MaxProcesses {
+		overflow = len(processes) - p.MaxProcesses
+		processes = p.topN(processes, cpuUsage, p.MaxProcesses)
+		p.sortProcesses(processes)
+	}
+
+	fields := map[string]interface{}{
+		"overflow_count": overflow,
+	}
+	acc.AddFields(fieldName, fields, p.selfTags(), now)
+	return processes
+}
@@ -0,0 +1,85 @@
+package ps
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gpapag/telegraf-plugins/pkg/psinfo"
+
+	"github.com/influxdata/telegraf"
+)
+
+const treeMeasurement = `ps_tree`
+
+// descendants returns the processes of all descending from one of
+// processes, other than processes themselves, so that the children of a
+// selected process are accounted to its tree even when they are not
+// selected.
+func descendants(processes []psinfo.Process, all []psinfo.Process) []psinfo.Process {
+	children := make(map[int][]psinfo.Process)
+	for _, process := range all {
+		children[process.Ppid] = append(children[process.Ppid], process)
+	}
+
+	seen := make(map[int]bool, len(processes))
+	var queue []int
+	for _, process := range processes {
+		seen[process.Pid] = true
+		queue = append(queue, process.Pid)
+	}
+
+	var found []psinfo.Process
+	for len(queue) > 0 {
+		pid := queue[0]
+		queue = queue[1:]
+		for _, child := range children[pid] {
+			// Pid 0 is the parent of both init and kthreadd.
+			if seen[child.Pid] || child.Pid == pid {
+				continue
+			}
+			seen[child.Pid] = true
+			found = append(found, child)
+			queue = append(queue, child.Pid)
+		}
+	}
+	return found
+}
+
+// addTrees stores in acc one metric per tree of processes with the totals
+// of its root and all its descendants. The roots are the processes of
+// processes whose parent is not among them.
+func (p *PS) addTrees(acc telegraf.Accumulator, processes []psinfo.Process, cpuUsage map[int]float64, now time.Time) {
+	byPid := make(map[int]psinfo.Process, len(processes))
+	for _, process := range processes {
+		byPid[process.Pid] = process
+	}
+
+	trees := make(map[int]*processGroup)
+	for _, process := range processes {
+		root := process
+		// The steps are bounded in case of a loop, as the parents of
+		// processes read at different times may be inconsistent.
+		for steps := 0; steps < len(processes); steps++ {
+			parent, ok := byPid[root.Ppid]
+			if !ok || parent.Pid == root.Pid {
+				break
+			}
+			root = parent
+		}
+		tree, ok := trees[root.Pid]
+		if !ok {
+			tree = &processGroup{}
+			trees[root.Pid] = tree
+		}
+		tree.add(process, cpuUsage)
+	}
+
+	for pid, tree := range trees {
+		tags := map[string]string{
+			"plugin":    tag,
+			"root_pid":  strconv.Itoa(pid),
+			"root_comm": byPid[pid].Comm,
+		}
+		acc.AddFields(treeMeasurement, p.groupFields(tree), tags, now)
+	}
+}
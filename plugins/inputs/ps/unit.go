@@ -0,0 +1,55 @@
+package ps
+
+import (
+	"time"
+
+	"github.com/gpapag/telegraf-plugins/pkg/psinfo"
+
+	"github.com/influxdata/telegraf"
+)
+
+const unitMeasurement = `ps_systemd_unit`
+
+// systemdUnits returns the systemd unit of each process belonging to a
+// unit selected by the systemd_units option, by pid. Every member of the
+// control group of a unit belongs to it, its main process included.
+func (p *PS) systemdUnits(processes []psinfo.Process) map[int]string {
+	units := make(map[int]string)
+	for _, process := range processes {
+		cgroup, err := p.procFS.ReadSystemdCgroup(process.Pid)
+		if err != nil {
+			continue
+		}
+		unit := psinfo.SystemdUnit(cgroup)
+		if unit != "" && p.unitFilter.Match(unit) {
+			units[process.Pid] = unit
+		}
+	}
+	return units
+}
+
+// addUnits stores in acc one metric per systemd unit with the totals of
+// its processes.
+func (p *PS) addUnits(acc telegraf.Accumulator, processes []psinfo.Process, units map[int]string, cpuUsage map[int]float64, now time.Time) {
+	groups := make(map[string]*processGroup)
+	for _, process := range processes {
+		unit, ok := units[process.Pid]
+		if !ok {
+			continue
+		}
+		group, ok := groups[unit]
+		if !ok {
+			group = &processGroup{}
+			groups[unit] = group
+		}
+		group.add(process, cpuUsage)
+	}
+
+	for unit, group := range groups {
+		tags := map[string]string{
+			"plugin":       tag,
+			"systemd_unit": unit,
+		}
+		acc.AddFields(unitMeasurement, p.groupFields(group), tags, now)
+	}
+}
@@ -0,0 +1,7 @@
+//go:build darwin
+// +build darwin
+
+package ps
+
+// defaultVariant is the flavour of the ps command shipped with macOS.
+const defaultVariant = `darwin`
@@ -0,0 +1,7 @@
+//go:build freebsd
+// +build freebsd
+
+package ps
+
+// defaultVariant is the flavour of the ps command shipped with FreeBSD.
+const defaultVariant = `freebsd`
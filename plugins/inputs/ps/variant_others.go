@@ -0,0 +1,8 @@
+//go:build !darwin && !freebsd
+// +build !darwin,!freebsd
+
+package ps
+
+// defaultVariant is the flavour of the ps command of the Linux
+// distributions.
+const defaultVariant = `procps-ng`
@@ -0,0 +1,21 @@
+package ps
+
+import (
+	"github.com/gpapag/telegraf-plugins/pkg/psinfo"
+)
+
+// wchanFields returns the kernel function process is blocked in when it is
+// in uninterruptible sleep, the D state of processes stuck on storage or
+// NFS. Other processes, and those whose wait channel cannot be read, yield
+// no fields.
+func (p *PS) wchanFields(process psinfo.Process) map[string]interface{} {
+	if !p.readWchan || process.Stat == "" || process.Stat[0] != 'D' {
+		return nil
+	}
+
+	wchan, err := p.procFS.ReadWchan(process.Pid)
+	if err != nil || wchan == "" {
+		return nil
+	}
+	return map[string]interface{}{"wchan": wchan}
+}
@@ -0,0 +1,16 @@
+//go:build windows
+// +build windows
+
+package ps
+
+// defaultBackend is the backend of the ps plugin when none is configured,
+// as Windows has no ps command.
+const defaultBackend = backendWindows
+
+// backendWindows reads the process table through the Windows process APIs,
+// as the gopsutil backend does on Windows.
+const backendWindows = `windows`
+
+func init() {
+	addCollector(backendWindows, newGopsutilCollector)
+}
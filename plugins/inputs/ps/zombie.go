@@ -0,0 +1,46 @@
+package ps
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gpapag/telegraf-plugins/pkg/psinfo"
+
+	"github.com/influxdata/telegraf"
+)
+
+const zombieMeasurement = `ps_zombies`
+
+// commsByPid returns the command name of every process by pid, to name the
+// parents of zombies whether or not the parents are selected themselves.
+func commsByPid(processes []psinfo.Process) map[int]string {
+	comms := make(map[int]string, len(processes))
+	for _, process := range processes {
+		comms[process.Pid] = process.Comm
+	}
+	return comms
+}
+
+// addZombies stores in acc one metric per parent of defunct processes with
+// the number of zombies it has not reaped yet. The parent, not the zombie,
+// is the process at fault.
+func (p *PS) addZombies(acc telegraf.Accumulator, processes []psinfo.Process, comms map[int]string, now time.Time) {
+	zombies := make(map[int]int)
+	for _, process := range processes {
+		if process.Stat != "" && process.Stat[0] == 'Z' {
+			zombies[process.Ppid]++
+		}
+	}
+
+	for ppid, count := range zombies {
+		tags := map[string]string{
+			"plugin":      tag,
+			"parent_pid":  strconv.Itoa(ppid),
+			"parent_comm": comms[ppid],
+		}
+		fields := map[string]interface{}{
+			"zombies": count,
+		}
+		acc.AddFields(zombieMeasurement, fields, tags, now)
+	}
+}
@@ -0,0 +1,121 @@
+package runit_s6
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const measurement = `runit_s6`
+
+// RunitS6 reports the state of the services supervised by runit or s6.
+type RunitS6 struct {
+	ServiceDirs []string
+}
+
+// init initializes the package.
+func init() {
+	inputs.Add("runit_s6", func() telegraf.Input {
+		return newRunitS6()
+	})
+}
+
+// newRunitS6 returns a pointer to a new RunitS6 object.
+func newRunitS6() *RunitS6 {
+	return &RunitS6{
+		ServiceDirs: []string{"/etc/service", "/var/service", "/run/service"},
+	}
+}
+
+// Description returns a short description about the plugin.
+func (r *RunitS6) Description() string {
+	return "Read the state of the services supervised by runit or s6."
+}
+
+// SampleConfig returns a sample configuration for the plugin.
+func (r *RunitS6) SampleConfig() string {
+	return `
+	## Scan directories holding one directory per supervised service;
+	## those missing are skipped.
+	#service_dirs = ["/etc/service", "/var/service", "/run/service"]
+	`
+}
+
+// Gather reads the supervise/status file of every service and stores the
+// state of the services in the accumulator acc.
+func (r *RunitS6) Gather(acc telegraf.Accumulator) error {
+	now := time.Now().UTC()
+
+	for _, dir := range r.ServiceDirs {
+		services, err := ioutil.ReadDir(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			acc.AddError(fmt.Errorf("runit_s6: unable to gather metrics: %s", err))
+			continue
+		}
+
+		for _, service := range services {
+			// Services are usually symlinks to their service directory.
+			path := filepath.Join(dir, service.Name())
+			if info, err := os.Stat(path); err != nil || !info.IsDir() || service.Name()[0] == '.' {
+				continue
+			}
+
+			content, err := ioutil.ReadFile(filepath.Join(path, "supervise", "status"))
+			if err != nil {
+				if !os.IsNotExist(err) {
+					acc.AddError(fmt.Errorf("runit_s6: service %s: %s", path, err))
+				}
+				continue
+			}
+			s, err := parseStatus(content)
+			if err != nil {
+				acc.AddError(fmt.Errorf("runit_s6: service %s: %s", path, err))
+				continue
+			}
+
+			r.addService(acc, dir, service.Name(), s, now)
+		}
+	}
+
+	return nil
+}
+
+// addService adds the state s of the service name to the accumulator acc.
+func (r *RunitS6) addService(acc telegraf.Accumulator, dir, name string, s status, now time.Time) {
+	up := 0
+	if s.state == "run" {
+		up = 1
+	}
+	fields := map[string]interface{}{
+		"up":      up,
+		"state":   s.state,
+		"pid":     s.pid,
+		"want_up": s.wantUp,
+		"paused":  s.paused,
+	}
+	if !s.since.IsZero() {
+		fields["state_duration"] = int64(now.Sub(s.since).Seconds())
+	}
+	if s.supervisor == "s6" {
+		fields["ready"] = s.ready
+		if s.exited {
+			fields["exit_code"] = s.exitCode
+			fields["exit_signal"] = s.signal
+		}
+	}
+
+	tags := map[string]string{
+		"service":    name,
+		"dir":        dir,
+		"supervisor": s.supervisor,
+	}
+	acc.AddFields(measurement, fields, tags, now)
+}
@@ -0,0 +1,113 @@
+package runit_s6
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// Sizes of the supervise/status files.
+const (
+	runitStatusSize = 20
+	s6StatusSize    = 35
+	s6PgidSize      = 43 // since s6 2.9, with the process group
+)
+
+// tai64Offset is the TAI64 label of the Unix epoch, accounting for the 10
+// seconds TAI was ahead of UTC in 1970 as daemontools does.
+const tai64Offset = 1<<62 + 10
+
+// status is the state of a supervised service.
+type status struct {
+	supervisor string
+	state      string
+	pid        int64
+	since      time.Time
+	wantUp     bool
+	paused     bool
+	ready      bool
+	exitCode   int
+	signal     int
+	exited     bool
+}
+
+// parseStatus decodes the supervise/status file of a runit or an s6
+// service, told apart by their size.
+func parseStatus(b []byte) (status, error) {
+	switch len(b) {
+	case runitStatusSize:
+		return parseRunit(b), nil
+	case s6StatusSize, s6PgidSize:
+		return parseS6(b), nil
+	default:
+		return status{}, fmt.Errorf("unknown status file of %d bytes", len(b))
+	}
+}
+
+// parseRunit decodes a runit status file: the TAI64N time of the last state
+// change, the pid in little endian, and the paused, want and term flags and
+// the state of the service.
+func parseRunit(b []byte) status {
+	s := status{
+		supervisor: "runit",
+		since:      tai64n(b[0:12]),
+		pid:        int64(binary.LittleEndian.Uint32(b[12:16])),
+		paused:     b[16] != 0,
+		wantUp:     b[17] == 'u',
+	}
+	switch b[19] {
+	case 1:
+		s.state = "run"
+	case 2:
+		s.state = "finish"
+	default:
+		s.state = "down"
+	}
+	if s.state != "run" {
+		s.pid = 0
+	}
+	return s
+}
+
+// parseS6 decodes an s6 status file: the TAI64N times of the last state
+// change and of readiness, the pid, the process group since s6 2.9, the
+// wait status of the last run and the flags.
+func parseS6(b []byte) status {
+	s := status{
+		supervisor: "s6",
+		since:      tai64n(b[0:12]),
+		pid:        int64(binary.BigEndian.Uint64(b[24:32])),
+	}
+	wstat := binary.BigEndian.Uint16(b[len(b)-3 : len(b)-1])
+	flags := b[len(b)-1]
+	s.paused = flags&0x01 != 0
+	s.wantUp = flags&0x04 != 0
+	s.ready = flags&0x08 != 0
+
+	switch {
+	case s.pid != 0 && flags&0x02 == 0:
+		s.state = "run"
+	case s.pid != 0:
+		s.state = "finish"
+	default:
+		s.state = "down"
+		s.exited = true
+		// wstat is a wait status: the exit code, or the terminating signal.
+		if wstat&0x7f == 0 {
+			s.exitCode = int(wstat >> 8)
+		} else {
+			s.signal = int(wstat & 0x7f)
+		}
+	}
+	return s
+}
+
+// tai64n converts a TAI64N label to a time.
+func tai64n(b []byte) time.Time {
+	seconds := binary.BigEndian.Uint64(b[0:8])
+	nanoseconds := binary.BigEndian.Uint32(b[8:12])
+	if seconds < tai64Offset {
+		return time.Time{}
+	}
+	return time.Unix(int64(seconds-tai64Offset), int64(nanoseconds)).UTC()
+}
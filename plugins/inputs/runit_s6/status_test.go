@@ -0,0 +1,132 @@
+package runit_s6
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+)
+
+// testSince is the time of the last state change of the services of
+// testdata.
+var testSince = time.Unix(1634284923, 0).UTC()
+
+func TestParseStatus(t *testing.T) {
+	// The files of testdata are laid out as runsv and s6-supervise write
+	// supervise/status, s6 before 2.9 lacking the process group.
+	tests := []struct {
+		file     string
+		expected status
+	}{
+		{"runit-run", status{
+			supervisor: "runit", state: "run", pid: 4242,
+			since: testSince.Add(500 * time.Millisecond), wantUp: true,
+		}},
+		{"runit-down", status{
+			supervisor: "runit", state: "down", since: testSince.Add(-time.Minute),
+		}},
+		{"runit-finish-paused", status{
+			supervisor: "runit", state: "finish", since: testSince, wantUp: true, paused: true,
+		}},
+		{"s6-2.8-run", status{
+			supervisor: "s6", state: "run", pid: 4242,
+			since: testSince.Add(250), wantUp: true, ready: true,
+		}},
+		{"s6-run-ready", status{
+			supervisor: "s6", state: "run", pid: 4242,
+			since: testSince.Add(250), wantUp: true, ready: true,
+		}},
+		{"s6-finishing", status{
+			supervisor: "s6", state: "finish", pid: 4242,
+			since: testSince.Add(250), wantUp: true,
+		}},
+		{"s6-down-exit", status{
+			supervisor: "s6", state: "down", since: testSince.Add(250),
+			exited: true, exitCode: 3,
+		}},
+		{"s6-down-signal-paused", status{
+			supervisor: "s6", state: "down", since: testSince.Add(250),
+			wantUp: true, paused: true, exited: true, signal: 9,
+		}},
+	}
+
+	for _, tt := range tests {
+		b, err := ioutil.ReadFile(filepath.Join("testdata", tt.file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		s, err := parseStatus(b)
+		if err != nil {
+			t.Errorf("%s: %v", tt.file, err)
+			continue
+		}
+		if !reflect.DeepEqual(s, tt.expected) {
+			t.Errorf("%s:\n got %+v\nwant %+v", tt.file, s, tt.expected)
+		}
+	}
+}
+
+func TestParseStatusErrors(t *testing.T) {
+	b, err := ioutil.ReadFile(filepath.Join("testdata", "truncated"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parseStatus(b); err == nil {
+		t.Error("truncated status file accepted")
+	}
+
+	// A label before the epoch, such as the zeros of a status file never
+	// written to, has no time.
+	if since := tai64n(make([]byte, 12)); !since.IsZero() {
+		t.Errorf("time %v of a zero label", since)
+	}
+}
+
+func TestGather(t *testing.T) {
+	dir := t.TempDir()
+	for service, file := range map[string]string{"web": "runit-run", "worker": "s6-down-exit", "broken": "truncated"} {
+		supervise := filepath.Join(dir, "sv", service, "supervise")
+		if err := os.MkdirAll(supervise, 0755); err != nil {
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadFile(filepath.Join("testdata", file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(supervise, "status"), b, 0644); err != nil {
+			t.Fatal(err)
+		}
+		// Services are linked into the scan directory.
+		if err := os.MkdirAll(filepath.Join(dir, "service"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(filepath.Join(dir, "sv", service), filepath.Join(dir, "service", service)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := newRunitS6()
+	r.ServiceDirs = []string{filepath.Join(dir, "service"), filepath.Join(dir, "missing")}
+	var acc testutil.Accumulator
+	if err := r.Gather(&acc); err != nil {
+		t.Fatal(err)
+	}
+	if len(acc.Errors) != 1 {
+		t.Errorf("errors %v, expected one for the broken service", acc.Errors)
+	}
+
+	metrics := make(map[string]*testutil.Metric)
+	for _, m := range acc.Metrics {
+		metrics[m.Tags["service"]] = m
+	}
+	if m := metrics["web"]; m == nil || m.Fields["up"] != 1 || m.Fields["pid"] != int64(4242) || m.Tags["supervisor"] != "runit" {
+		t.Errorf("metric of web %v", m)
+	}
+	if m := metrics["worker"]; m == nil || m.Fields["up"] != 0 || m.Fields["exit_code"] != 3 || m.Tags["supervisor"] != "s6" {
+		t.Errorf("metric of worker %v", m)
+	}
+}
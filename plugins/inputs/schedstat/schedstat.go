@@ -0,0 +1,177 @@
+package schedstat
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const (
+	measurement    = `schedstat`
+	cpuMeasurement = `schedstat_cpu`
+)
+
+// cpuFields names the counters of the cpu lines of /proc/schedstat, in the
+// order of its version 15, the second counter being unused.
+var cpuFields = []string{
+	"yields",
+	"",
+	"schedules",
+	"idle_schedules",
+	"wakeups",
+	"local_wakeups",
+	"running_ns",
+	"waiting_ns",
+	"timeslices",
+}
+
+// statFields maps the lines of /proc/stat read by the plugin to the names
+// of the fields.
+var statFields = map[string]string{
+	"ctxt":          "context_switches",
+	"processes":     "forks",
+	"procs_running": "procs_running",
+	"procs_blocked": "procs_blocked",
+}
+
+// Schedstat reports the scheduler statistics of the host and of each CPU.
+type Schedstat struct {
+	procRoot string
+	PerCPU   bool `toml:"per_cpu"`
+}
+
+// init initializes the package.
+func init() {
+	inputs.Add("schedstat", func() telegraf.Input {
+		return newSchedstat("/proc")
+	})
+}
+
+// newSchedstat returns a pointer to a new Schedstat object reading the
+// files in procRoot.
+func newSchedstat(procRoot string) *Schedstat {
+	return &Schedstat{
+		procRoot: procRoot,
+		PerCPU:   true,
+	}
+}
+
+// Description returns a short description about the plugin.
+func (s *Schedstat) Description() string {
+	return "Read the run queue and context switch statistics of the scheduler."
+}
+
+// SampleConfig returns a sample configuration for the plugin.
+func (s *Schedstat) SampleConfig() string {
+	return `
+	## Report the run queue statistics of each CPU from /proc/schedstat,
+	## which requires a kernel built with CONFIG_SCHEDSTATS.
+	#per_cpu = true
+	`
+}
+
+// Gather reads the scheduler statistics and stores them in the accumulator
+// acc.
+func (s *Schedstat) Gather(acc telegraf.Accumulator) error {
+	now := time.Now().UTC()
+
+	fields, err := s.readStat()
+	if err != nil {
+		acc.AddError(err)
+		return fmt.Errorf("schedstat: unable to gather metrics: %s", err)
+	}
+
+	if s.PerCPU {
+		cpus, err := s.readSchedstat()
+		if err != nil && !os.IsNotExist(err) {
+			acc.AddError(err)
+			return fmt.Errorf("schedstat: unable to gather metrics: %s", err)
+		}
+		var running, waiting int64
+		for cpu, cpuFields := range cpus {
+			running += cpuFields["running_ns"].(int64)
+			waiting += cpuFields["waiting_ns"].(int64)
+			acc.AddFields(cpuMeasurement, cpuFields, map[string]string{"cpu": cpu}, now)
+		}
+		if len(cpus) > 0 {
+			fields["running_ns"] = running
+			fields["waiting_ns"] = waiting
+		}
+	}
+
+	acc.AddFields(measurement, fields, map[string]string{}, now)
+
+	return nil
+}
+
+// readStat reads the context switches, forks and runnable and blocked
+// processes of /proc/stat.
+func (s *Schedstat) readStat() (map[string]interface{}, error) {
+	file, err := os.Open(s.procRoot + "/stat")
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	fields := make(map[string]interface{}, len(statFields))
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) != 2 {
+			continue
+		}
+		name, ok := statFields[parts[0]]
+		if !ok {
+			continue
+		}
+		value, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("stat: %s: %s", parts[0], err)
+		}
+		fields[name] = value
+	}
+
+	return fields, scanner.Err()
+}
+
+// readSchedstat reads the counters of each CPU of /proc/schedstat, whose
+// lines look like "cpu0 0 0 1234 567 890 12 3456789 123456 7890" and are
+// followed by the scheduling domains of the CPU.
+func (s *Schedstat) readSchedstat() (map[string]map[string]interface{}, error) {
+	file, err := os.Open(s.procRoot + "/schedstat")
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	cpus := make(map[string]map[string]interface{})
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) < 1+len(cpuFields) || !strings.HasPrefix(parts[0], "cpu") {
+			continue
+		}
+
+		fields := make(map[string]interface{}, len(cpuFields))
+		for i, name := range cpuFields {
+			if name == "" {
+				continue
+			}
+			value, err := strconv.ParseInt(parts[i+1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("schedstat: %s: %s", parts[0], err)
+			}
+			fields[name] = value
+		}
+		cpus[parts[0]] = fields
+	}
+
+	return cpus, scanner.Err()
+}
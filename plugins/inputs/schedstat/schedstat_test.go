@@ -0,0 +1,108 @@
+package schedstat
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestGather(t *testing.T) {
+	stat := map[string]interface{}{
+		"context_switches": int64(412345678),
+		"forks":            int64(1234567),
+		"procs_running":    int64(3),
+		"procs_blocked":    int64(1),
+	}
+	cpu0 := map[string]interface{}{
+		"yields": int64(12), "schedules": int64(98765432), "idle_schedules": int64(40123456),
+		"wakeups": int64(51234567), "local_wakeups": int64(32123456),
+		"running_ns": int64(7123456789012), "waiting_ns": int64(912345678901), "timeslices": int64(97654321),
+	}
+	cpu1 := map[string]interface{}{
+		"yields": int64(7), "schedules": int64(97654321), "idle_schedules": int64(39987654),
+		"wakeups": int64(50123456), "local_wakeups": int64(31987654),
+		"running_ns": int64(7012345678901), "waiting_ns": int64(887654321098), "timeslices": int64(96543210),
+	}
+	withCPUs := make(map[string]interface{})
+	for k, v := range stat {
+		withCPUs[k] = v
+	}
+	withCPUs["running_ns"] = int64(7123456789012 + 7012345678901)
+	withCPUs["waiting_ns"] = int64(912345678901 + 887654321098)
+
+	// The directories of testdata hold captures of /proc/stat and of the
+	// version 15 of /proc/schedstat, that of a kernel built without
+	// CONFIG_SCHEDSTATS missing.
+	tests := []struct {
+		name     string
+		dir      string
+		perCPU   bool
+		expected map[string]map[string]interface{}
+		err      bool
+	}{
+		{
+			name:   "per cpu",
+			dir:    "testdata/v15",
+			perCPU: true,
+			expected: map[string]map[string]interface{}{
+				"schedstat":          withCPUs,
+				"schedstat_cpu/cpu0": cpu0,
+				"schedstat_cpu/cpu1": cpu1,
+			},
+		},
+		{
+			name:     "host only",
+			dir:      "testdata/v15",
+			expected: map[string]map[string]interface{}{"schedstat": stat},
+		},
+		{
+			name:     "without schedstats",
+			dir:      "testdata/no-schedstat",
+			perCPU:   true,
+			expected: map[string]map[string]interface{}{"schedstat": stat},
+		},
+		{
+			name:   "invalid schedstat",
+			dir:    "testdata/invalid",
+			perCPU: true,
+			err:    true,
+		},
+		{
+			name: "missing stat",
+			dir:  "testdata/missing",
+			err:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newSchedstat(tt.dir)
+			s.PerCPU = tt.perCPU
+
+			var acc testutil.Accumulator
+			err := s.Gather(&acc)
+			if tt.err {
+				if err == nil || len(acc.Errors) != 1 || len(acc.Metrics) != 0 {
+					t.Errorf("error %v, errors %v, metrics %v", err, acc.Errors, acc.Metrics)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			metrics := make(map[string]map[string]interface{})
+			for _, m := range acc.Metrics {
+				key := m.Measurement
+				if cpu, ok := m.Tags["cpu"]; ok {
+					key += "/" + cpu
+				}
+				metrics[key] = m.Fields
+			}
+			if !reflect.DeepEqual(metrics, tt.expected) {
+				t.Errorf("\n got %v\nwant %v", metrics, tt.expected)
+			}
+		})
+	}
+}
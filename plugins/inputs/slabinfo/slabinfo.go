@@ -0,0 +1,166 @@
+package slabinfo
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const measurement = `slabinfo`
+
+// Slabinfo reports the largest slab caches of the kernel.
+type Slabinfo struct {
+	procSlabinfo string
+	Caches       []string
+	Top          int
+
+	compiled    bool
+	cacheFilter filter.Filter
+}
+
+// slab is one cache of /proc/slabinfo.
+type slab struct {
+	name         string
+	activeObjs   int64
+	numObjs      int64
+	objSize      int64
+	objsPerSlab  int64
+	pagesPerSlab int64
+	activeSlabs  int64
+	numSlabs     int64
+	size         int64
+}
+
+// init initializes the package.
+func init() {
+	inputs.Add("slabinfo", func() telegraf.Input {
+		return newSlabinfo("/proc/slabinfo")
+	})
+}
+
+// newSlabinfo returns a pointer to a new Slabinfo object reading the file
+// procSlabinfo.
+func newSlabinfo(procSlabinfo string) *Slabinfo {
+	return &Slabinfo{
+		procSlabinfo: procSlabinfo,
+		Top:          10,
+	}
+}
+
+// Description returns a short description about the plugin.
+func (s *Slabinfo) Description() string {
+	return "Read the largest slab caches of the kernel."
+}
+
+// SampleConfig returns a sample configuration for the plugin.
+func (s *Slabinfo) SampleConfig() string {
+	return `
+	## Caches to report; glob patterns are supported. Every cache is
+	## considered if empty.
+	#caches = ["dentry", "inode_cache", "kmalloc-*"]
+
+	## Number of caches to report, the largest first; 0 reports them all.
+	#top = 10
+	`
+}
+
+// Gather reads the slab caches and stores the largest ones in the
+// accumulator acc. /proc/slabinfo is only readable by root.
+func (s *Slabinfo) Gather(acc telegraf.Accumulator) error {
+	if !s.compiled {
+		var err error
+		s.cacheFilter, err = filter.Compile(s.Caches)
+		if err != nil {
+			acc.AddError(err)
+			return fmt.Errorf("slabinfo: invalid caches: %s", err)
+		}
+		s.compiled = true
+	}
+
+	now := time.Now().UTC()
+	slabs, err := s.readSlabinfo()
+	if err != nil {
+		acc.AddError(err)
+		return fmt.Errorf("slabinfo: unable to gather metrics: %s", err)
+	}
+
+	sort.Slice(slabs, func(i, j int) bool {
+		if slabs[i].size != slabs[j].size {
+			return slabs[i].size > slabs[j].size
+		}
+		return slabs[i].name < slabs[j].name
+	})
+	if s.Top > 0 && len(slabs) > s.Top {
+		slabs = slabs[:s.Top]
+	}
+
+	for _, slab := range slabs {
+		fields := map[string]interface{}{
+			"active_objs":    slab.activeObjs,
+			"num_objs":       slab.numObjs,
+			"obj_size":       slab.objSize,
+			"objs_per_slab":  slab.objsPerSlab,
+			"pages_per_slab": slab.pagesPerSlab,
+			"active_slabs":   slab.activeSlabs,
+			"num_slabs":      slab.numSlabs,
+			"size_bytes":     slab.size,
+		}
+		tags := map[string]string{"cache": slab.name}
+		acc.AddFields(measurement, fields, tags, now)
+	}
+
+	return nil
+}
+
+// readSlabinfo parses /proc/slabinfo, whose lines look like
+// "dentry 1365 1512 192 21 1 : tunables 0 0 0 : slabdata 72 72 0".
+func (s *Slabinfo) readSlabinfo() ([]slab, error) {
+	file, err := os.Open(s.procSlabinfo)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	pageSize := int64(os.Getpagesize())
+	var slabs []slab
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "slabinfo") || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.Fields(line)
+		if len(parts) < 16 || (s.cacheFilter != nil && !s.cacheFilter.Match(parts[0])) {
+			continue
+		}
+
+		var values [7]int64
+		for i, part := range []string{parts[1], parts[2], parts[3], parts[4], parts[5], parts[13], parts[14]} {
+			values[i], err = strconv.ParseInt(part, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("cache %s: %s", parts[0], err)
+			}
+		}
+		slabs = append(slabs, slab{
+			name:         parts[0],
+			activeObjs:   values[0],
+			numObjs:      values[1],
+			objSize:      values[2],
+			objsPerSlab:  values[3],
+			pagesPerSlab: values[4],
+			activeSlabs:  values[5],
+			numSlabs:     values[6],
+			size:         values[6] * values[4] * pageSize,
+		})
+	}
+
+	return slabs, scanner.Err()
+}
@@ -0,0 +1,138 @@
+package slabinfo
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestReadSlabinfo(t *testing.T) {
+	pageSize := int64(os.Getpagesize())
+	expected := []slab{
+		{"ext4_inode_cache", 48321, 49104, 1176, 27, 8, 1819, 1819, 1819 * 8 * pageSize},
+		{"dentry", 201345, 215418, 192, 21, 1, 10258, 10258, 10258 * pageSize},
+		{"inode_cache", 31200, 32032, 608, 26, 4, 1232, 1232, 1232 * 4 * pageSize},
+		{"kmalloc-8k", 412, 436, 8192, 4, 8, 109, 109, 109 * 8 * pageSize},
+		{"kmalloc-64", 45678, 46080, 64, 64, 1, 720, 720, 720 * pageSize},
+		{"kmalloc-32", 23456, 23808, 32, 128, 1, 186, 186, 186 * pageSize},
+		{"radix_tree_node", 60417, 61180, 584, 28, 4, 2185, 2185, 2185 * 4 * pageSize},
+		{"fscrypt_inode_info", 0, 0, 120, 34, 1, 0, 0, 0},
+	}
+
+	slabs, err := newSlabinfo("testdata/slabinfo").readSlabinfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(slabs, expected) {
+		t.Errorf("\n got %+v\nwant %+v", slabs, expected)
+	}
+}
+
+func TestGather(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		caches   []string
+		top      int
+		expected []string
+		err      bool
+	}{
+		{
+			name:     "largest first",
+			file:     "testdata/slabinfo",
+			top:      3,
+			expected: []string{"ext4_inode_cache", "dentry", "radix_tree_node"},
+		},
+		{
+			name:     "caches",
+			file:     "testdata/slabinfo",
+			caches:   []string{"kmalloc-*", "dentry"},
+			top:      3,
+			expected: []string{"dentry", "kmalloc-8k", "kmalloc-64"},
+		},
+		{
+			name: "every cache",
+			file: "testdata/slabinfo",
+			expected: []string{
+				"ext4_inode_cache", "dentry", "radix_tree_node", "inode_cache",
+				"kmalloc-8k", "kmalloc-64", "kmalloc-32", "fscrypt_inode_info",
+			},
+		},
+		{
+			// The invalid line is of a cache not reported.
+			name:     "invalid line filtered out",
+			file:     "testdata/slabinfo-invalid",
+			caches:   []string{"dentry"},
+			expected: []string{"dentry"},
+		},
+		{
+			name: "invalid line",
+			file: "testdata/slabinfo-invalid",
+			err:  true,
+		},
+		{
+			name: "missing",
+			file: "testdata/missing",
+			err:  true,
+		},
+		{
+			name:   "invalid caches",
+			file:   "testdata/slabinfo",
+			caches: []string{"kmalloc-[8"},
+			err:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newSlabinfo(tt.file)
+			s.Caches = tt.caches
+			s.Top = tt.top
+
+			var acc testutil.Accumulator
+			err := s.Gather(&acc)
+			if tt.err {
+				if err == nil || len(acc.Errors) != 1 {
+					t.Errorf("error %v, errors %v", err, acc.Errors)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			caches := []string{}
+			for _, m := range acc.Metrics {
+				caches = append(caches, m.Tags["cache"])
+			}
+			if !reflect.DeepEqual(caches, tt.expected) {
+				t.Errorf("caches %v, expected %v", caches, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGatherFields(t *testing.T) {
+	s := newSlabinfo("testdata/slabinfo")
+	s.Top = 1
+	var acc testutil.Accumulator
+	if err := s.Gather(&acc); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]interface{}{
+		"active_objs":    int64(48321),
+		"num_objs":       int64(49104),
+		"obj_size":       int64(1176),
+		"objs_per_slab":  int64(27),
+		"pages_per_slab": int64(8),
+		"active_slabs":   int64(1819),
+		"num_slabs":      int64(1819),
+		"size_bytes":     int64(1819 * 8 * os.Getpagesize()),
+	}
+	if len(acc.Metrics) != 1 || !reflect.DeepEqual(acc.Metrics[0].Fields, expected) {
+		t.Errorf("metrics %v, expected fields %v", acc.Metrics, expected)
+	}
+}
@@ -0,0 +1,159 @@
+package sockstat
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const (
+	measurement    = `sockstat`
+	tcpMeasurement = `sockstat_tcp`
+)
+
+// tcpStates maps the hexadecimal connection states of /proc/net/tcp to the
+// names used as fields.
+var tcpStates = map[string]string{
+	"01": "established",
+	"02": "syn_sent",
+	"03": "syn_recv",
+	"04": "fin_wait1",
+	"05": "fin_wait2",
+	"06": "time_wait",
+	"07": "close",
+	"08": "close_wait",
+	"09": "last_ack",
+	"0A": "listen",
+	"0B": "closing",
+	"0C": "new_syn_recv",
+}
+
+// Sockstat reports the socket usage of the host.
+type Sockstat struct {
+	procNet   string
+	TCPStates bool `toml:"tcp_states"`
+}
+
+// init initializes the package.
+func init() {
+	inputs.Add("sockstat", func() telegraf.Input {
+		return newSockstat("/proc/net")
+	})
+}
+
+// newSockstat returns a pointer to a new Sockstat object reading the files
+// in procNet.
+func newSockstat(procNet string) *Sockstat {
+	return &Sockstat{
+		procNet:   procNet,
+		TCPStates: true,
+	}
+}
+
+// Description returns a short description about the plugin.
+func (s *Sockstat) Description() string {
+	return "Read the socket usage summary of the host."
+}
+
+// SampleConfig returns a sample configuration for the plugin.
+func (s *Sockstat) SampleConfig() string {
+	return `
+	## Count the TCP connections in each state, as ss would. Reading the
+	## connection tables may be slow on hosts with many connections.
+	#tcp_states = true
+	`
+}
+
+// Gather reads the socket statistics and stores them in the accumulator
+// acc.
+func (s *Sockstat) Gather(acc telegraf.Accumulator) error {
+	now := time.Now().UTC()
+
+	fields := make(map[string]interface{})
+	for _, name := range []string{"sockstat", "sockstat6"} {
+		if err := s.readSockstat(name, fields); err != nil && !os.IsNotExist(err) {
+			acc.AddError(err)
+			return fmt.Errorf("sockstat: unable to gather metrics: %s", err)
+		}
+	}
+	acc.AddFields(measurement, fields, map[string]string{}, now)
+
+	if !s.TCPStates {
+		return nil
+	}
+
+	states := make(map[string]interface{}, len(tcpStates))
+	for _, state := range tcpStates {
+		states[state] = 0
+	}
+	for _, name := range []string{"tcp", "tcp6"} {
+		if err := s.countTCPStates(name, states); err != nil && !os.IsNotExist(err) {
+			acc.AddError(err)
+			return fmt.Errorf("sockstat: unable to gather metrics: %s", err)
+		}
+	}
+	acc.AddFields(tcpMeasurement, states, map[string]string{}, now)
+
+	return nil
+}
+
+// readSockstat parses a sockstat file, whose lines look like
+// "TCP: inuse 5 orphan 0 tw 2 alloc 7 mem 1", into fields named after the
+// protocol and the counter, such as tcp_inuse.
+func (s *Sockstat) readSockstat(name string, fields map[string]interface{}) error {
+	file, err := os.Open(s.procNet + "/" + name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) < 3 {
+			continue
+		}
+		protocol := strings.ToLower(strings.TrimSuffix(parts[0], ":"))
+		for i := 1; i+1 < len(parts); i += 2 {
+			value, err := strconv.ParseInt(parts[i+1], 10, 64)
+			if err != nil {
+				continue
+			}
+			fields[protocol+"_"+parts[i]] = value
+		}
+	}
+
+	return scanner.Err()
+}
+
+// countTCPStates adds to states the number of connections in each state
+// listed in a TCP connection table.
+func (s *Sockstat) countTCPStates(name string, states map[string]interface{}) error {
+	file, err := os.Open(s.procNet + "/" + name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Scan() // header
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) < 4 {
+			continue
+		}
+		state, ok := tcpStates[strings.ToUpper(parts[3])]
+		if !ok {
+			continue
+		}
+		states[state] = states[state].(int) + 1
+	}
+
+	return scanner.Err()
+}
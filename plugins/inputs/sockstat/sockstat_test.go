@@ -0,0 +1,101 @@
+package sockstat
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+// connections returns a sockstat_tcp metric counting the connections in
+// the states, and none in the others.
+func connections(states map[string]int) telegraf.Metric {
+	fields := make(map[string]interface{}, len(tcpStates))
+	for _, state := range tcpStates {
+		fields[state] = states[state]
+	}
+	return testutil.MustMetric(tcpMeasurement, nil, fields, time.Unix(0, 0))
+}
+
+func TestGather(t *testing.T) {
+	ipv4 := map[string]interface{}{
+		"sockets_used":  412,
+		"tcp_inuse":     23,
+		"tcp_orphan":    1,
+		"tcp_tw":        57,
+		"tcp_alloc":     31,
+		"tcp_mem":       12,
+		"udp_inuse":     6,
+		"udp_mem":       3,
+		"udplite_inuse": 0,
+		"raw_inuse":     1,
+		"frag_inuse":    0,
+		"frag_memory":   0,
+	}
+	dualStack := map[string]interface{}{
+		"tcp6_inuse":     8,
+		"udp6_inuse":     4,
+		"udplite6_inuse": 0,
+		"raw6_inuse":     0,
+		"frag6_inuse":    0,
+		"frag6_memory":   0,
+	}
+	for k, v := range ipv4 {
+		dualStack[k] = v
+	}
+
+	// The directories of testdata are captures of /proc/net, the IPv6
+	// files missing from hosts booted with ipv6.disable=1.
+	tests := []struct {
+		name      string
+		dir       string
+		tcpStates bool
+		expected  []telegraf.Metric
+	}{
+		{
+			name:      "dual stack",
+			dir:       "testdata/dual-stack",
+			tcpStates: true,
+			expected: []telegraf.Metric{
+				testutil.MustMetric(measurement, nil, dualStack, time.Unix(0, 0)),
+				connections(map[string]int{
+					"listen": 3, "established": 3, "syn_sent": 1,
+					"time_wait": 1, "close_wait": 1, "closing": 1,
+				}),
+			},
+		},
+		{
+			name:      "ipv4 only",
+			dir:       "testdata/ipv4-only",
+			tcpStates: true,
+			expected: []telegraf.Metric{
+				testutil.MustMetric(measurement, nil, ipv4, time.Unix(0, 0)),
+				connections(map[string]int{
+					"listen": 2, "established": 2, "syn_sent": 1,
+					"time_wait": 1, "close_wait": 1,
+				}),
+			},
+		},
+		{
+			name: "without tcp states",
+			dir:  "testdata/dual-stack",
+			expected: []telegraf.Metric{
+				testutil.MustMetric(measurement, nil, dualStack, time.Unix(0, 0)),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newSockstat(tt.dir)
+			s.TCPStates = tt.tcpStates
+
+			var acc testutil.Accumulator
+			if err := s.Gather(&acc); err != nil {
+				t.Fatal(err)
+			}
+			testutil.RequireMetricsEqual(t, tt.expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+		})
+	}
+}
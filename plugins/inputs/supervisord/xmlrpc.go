@@ -0,0 +1,153 @@
+package supervisord
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// client calls the methods of the XML-RPC interface of supervisord, over
+// HTTP or over its unix socket.
+type client struct {
+	endpoint string
+	http     *http.Client
+}
+
+// newClient returns a client for the XML-RPC interface at address, either
+// an http:// URL or a unix:// socket path.
+func newClient(address string, timeout time.Duration) (*client, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &client{
+		endpoint: address,
+		http:     &http.Client{Timeout: timeout},
+	}
+	switch u.Scheme {
+	case "http", "https":
+	case "unix":
+		socket := u.Path
+		c.endpoint = "http://localhost/RPC2"
+		if u.User != nil {
+			c.endpoint = "http://" + u.User.String() + "@localhost/RPC2"
+		}
+		c.http.Transport = &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", socket)
+			},
+		}
+	default:
+		return nil, fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+
+	return c, nil
+}
+
+// value is an XML-RPC value.
+type value struct {
+	String  *string  `xml:"string"`
+	Int     *int64   `xml:"int"`
+	I4      *int64   `xml:"i4"`
+	Boolean *string  `xml:"boolean"`
+	Double  *float64 `xml:"double"`
+	Array   []value  `xml:"array>data>value"`
+	Struct  []member `xml:"struct>member"`
+	Text    string   `xml:",chardata"`
+}
+
+// member is a member of an XML-RPC struct.
+type member struct {
+	Name  string `xml:"name"`
+	Value value  `xml:"value"`
+}
+
+// response is the response to an XML-RPC call.
+type response struct {
+	Params []value `xml:"params>param>value"`
+	Fault  *value  `xml:"fault>value"`
+}
+
+// str returns v as a string; values without a type are strings.
+func (v value) str() string {
+	switch {
+	case v.String != nil:
+		return *v.String
+	case v.Int != nil:
+		return strconv.FormatInt(*v.Int, 10)
+	case v.I4 != nil:
+		return strconv.FormatInt(*v.I4, 10)
+	}
+	return strings.TrimSpace(v.Text)
+}
+
+// int returns v as an integer.
+func (v value) int() int64 {
+	switch {
+	case v.Int != nil:
+		return *v.Int
+	case v.I4 != nil:
+		return *v.I4
+	case v.Boolean != nil:
+		if strings.TrimSpace(*v.Boolean) == "1" {
+			return 1
+		}
+		return 0
+	case v.Double != nil:
+		return int64(*v.Double)
+	}
+	n, _ := strconv.ParseInt(v.str(), 10, 64)
+	return n
+}
+
+// members returns the members of the struct v by name.
+func (v value) members() map[string]value {
+	members := make(map[string]value, len(v.Struct))
+	for _, m := range v.Struct {
+		members[m.Name] = m.Value
+	}
+	return members
+}
+
+// call calls the method without parameters and returns its result.
+func (c *client) call(method string) (value, error) {
+	var body bytes.Buffer
+	body.WriteString(xml.Header)
+	body.WriteString("<methodCall><methodName>")
+	if err := xml.EscapeText(&body, []byte(method)); err != nil {
+		return value{}, err
+	}
+	body.WriteString("</methodName><params/></methodCall>")
+
+	resp, err := c.http.Post(c.endpoint, "text/xml", &body)
+	if err != nil {
+		return value{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return value{}, fmt.Errorf("%s: %s", method, resp.Status)
+	}
+
+	var r response
+	if err := xml.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return value{}, fmt.Errorf("%s: unable to parse response: %s", method, err)
+	}
+	if r.Fault != nil {
+		fault := r.Fault.members()
+		return value{}, fmt.Errorf("%s: fault %d: %s", method, fault["faultCode"].int(), fault["faultString"].str())
+	}
+	if len(r.Params) == 0 {
+		return value{}, fmt.Errorf("%s: empty response", method)
+	}
+
+	return r.Params[0], nil
+}
@@ -0,0 +1,179 @@
+package supervisord
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// methodCall is an XML-RPC call, as sent by client.
+type methodCall struct {
+	MethodName string `xml:"methodName"`
+}
+
+// newSupervisor returns an XML-RPC server answering each method with the
+// content of testdata/<method>.xml, the method being stripped of its
+// "supervisor." prefix, unless responses names another file for it.
+func newSupervisor(t *testing.T, responses map[string]string) *httptest.Server {
+	s := httptest.NewServer(supervisorHandler(t, responses))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// supervisorHandler returns the handler of newSupervisor.
+func supervisorHandler(t *testing.T, responses map[string]string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var call methodCall
+		if err := xml.NewDecoder(r.Body).Decode(&call); err != nil {
+			t.Errorf("invalid call: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		file, ok := responses[call.MethodName]
+		if !ok {
+			file = strings.TrimPrefix(call.MethodName, "supervisor.") + ".xml"
+		}
+		data, err := ioutil.ReadFile(filepath.Join("testdata", file))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/xml")
+		w.Write(data)
+	})
+}
+
+func TestCallAllProcessInfo(t *testing.T) {
+	s := newSupervisor(t, nil)
+	c, err := newClient(s.URL+"/RPC2", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := c.call("supervisor.getAllProcessInfo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Array) != 2 {
+		t.Fatalf("%d processes, expected 2", len(result.Array))
+	}
+
+	expected := []map[string]interface{}{
+		{
+			"name": "web-0", "group": "web", "statename": "RUNNING", "spawnerr": "",
+			"state": int64(20), "pid": int64(4242), "start": int64(1634281200),
+			"now": int64(1634284923), "exitstatus": int64(0),
+		},
+		{
+			// An i4 integer, and an escaped name.
+			"name": "worker & cron", "group": "queue", "statename": "FATAL",
+			"state": int64(200), "pid": int64(0), "exitstatus": int64(1),
+			"spawnerr": "Exited too quickly (process log may have details)",
+		},
+	}
+	for i, process := range result.Array {
+		members := process.members()
+		for name, value := range expected[i] {
+			var got interface{}
+			switch value.(type) {
+			case string:
+				got = members[name].str()
+			default:
+				got = members[name].int()
+			}
+			if got != value {
+				t.Errorf("process %d: %s %#v, expected %#v", i, name, got, value)
+			}
+		}
+	}
+}
+
+func TestCallFault(t *testing.T) {
+	s := newSupervisor(t, map[string]string{"supervisor.getAllProcessInfo": "fault.xml"})
+	c, err := newClient(s.URL+"/RPC2", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.call("supervisor.getAllProcessInfo")
+	if err == nil || err.Error() != "supervisor.getAllProcessInfo: fault 6: SHUTDOWN_STATE" {
+		t.Errorf("error %v", err)
+	}
+}
+
+func TestCallErrors(t *testing.T) {
+	s := newSupervisor(t, nil)
+	c, err := newClient(s.URL+"/RPC2", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// No testdata answers it.
+	if _, err := c.call("supervisor.getPID"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("error %v of a missing method", err)
+	}
+
+	if _, err := newClient("ftp://localhost/RPC2", time.Second); err == nil {
+		t.Error("ftp scheme accepted")
+	}
+}
+
+func TestValue(t *testing.T) {
+	tests := []struct {
+		xml  string
+		str  string
+		int  int64
+		desc string
+	}{
+		{"<value><string> padded </string></value>", " padded ", 0, "string kept as is"},
+		{"<value>untyped</value>", "untyped", 0, "untyped string"},
+		{"<value><int>-3</int></value>", "-3", -3, "int"},
+		{"<value><i4>7</i4></value>", "7", 7, "i4"},
+		{"<value><boolean>1</boolean></value>", "", 1, "true"},
+		{"<value><boolean>0</boolean></value>", "", 0, "false"},
+		{"<value><double>2.9</double></value>", "", 2, "double truncated"},
+		{"<value>42</value>", "42", 42, "untyped number"},
+	}
+	for _, tt := range tests {
+		var v value
+		if err := xml.Unmarshal([]byte(tt.xml), &v); err != nil {
+			t.Errorf("%s: %v", tt.desc, err)
+			continue
+		}
+		if s := v.str(); s != tt.str {
+			t.Errorf("%s: str %q, expected %q", tt.desc, s, tt.str)
+		}
+		if n := v.int(); n != tt.int {
+			t.Errorf("%s: int %d, expected %d", tt.desc, n, tt.int)
+		}
+	}
+}
+
+func TestCallUnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "supervisor.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	s := &http.Server{Handler: supervisorHandler(t, nil)}
+	go s.Serve(l)
+	t.Cleanup(func() { s.Close() })
+
+	c, err := newClient("unix://"+socket, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := c.call("supervisor.getState")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state := result.members()["statename"].str(); state != "RUNNING" {
+		t.Errorf("state %q, expected RUNNING", state)
+	}
+}
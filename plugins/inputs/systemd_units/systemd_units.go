@@ -0,0 +1,135 @@
+package systemd_units
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/coreos/go-systemd/v22/dbus"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const (
+	measurement        = `systemd_units`
+	summaryMeasurement = `systemd_units_summary`
+)
+
+// SystemdUnits queries systemd over D-Bus for the state of its units.
+type SystemdUnits struct {
+	Units         []string
+	RestartCounts bool
+
+	unitFilter filter.Filter
+	compiled   bool
+}
+
+// init initializes the package.
+func init() {
+	inputs.Add("systemd_units", func() telegraf.Input {
+		return newSystemdUnits()
+	})
+}
+
+// newSystemdUnits returns a pointer to a new SystemdUnits object.
+func newSystemdUnits() *SystemdUnits {
+	return &SystemdUnits{
+		RestartCounts: true,
+	}
+}
+
+// Description returns a short description about the plugin.
+func (s *SystemdUnits) Description() string {
+	return "Read the state of the systemd units over D-Bus."
+}
+
+// SampleConfig returns a sample configuration for the plugin.
+func (s *SystemdUnits) SampleConfig() string {
+	return `
+	## Units to report; glob patterns are supported. Every unit loaded by
+	## systemd is reported when empty.
+	#units = ["*.service"]
+
+	## Read the number of automatic restarts of service units.
+	#restart_counts = true
+	`
+}
+
+// Gather lists the units known to systemd and stores their state in the
+// accumulator acc.
+func (s *SystemdUnits) Gather(acc telegraf.Accumulator) error {
+	if !s.compiled {
+		var err error
+		s.unitFilter, err = filter.Compile(s.Units)
+		if err != nil {
+			acc.AddError(err)
+			return fmt.Errorf("systemd_units: invalid units: %s", err)
+		}
+		s.compiled = true
+	}
+
+	conn, err := dbus.New()
+	if err != nil {
+		acc.AddError(err)
+		return fmt.Errorf("systemd_units: unable to connect to systemd: %s", err)
+	}
+	defer conn.Close()
+
+	units, err := conn.ListUnits()
+	if err != nil {
+		acc.AddError(err)
+		return fmt.Errorf("systemd_units: unable to gather metrics: %s", err)
+	}
+
+	now := time.Now().UTC()
+	var reported, failed int
+	for _, unit := range units {
+		if s.unitFilter != nil && !s.unitFilter.Match(unit.Name) {
+			continue
+		}
+		reported++
+		if unit.ActiveState == "failed" {
+			failed++
+		}
+
+		tags := map[string]string{
+			"name":   unit.Name,
+			"load":   unit.LoadState,
+			"active": unit.ActiveState,
+			"sub":    unit.SubState,
+		}
+		fields := map[string]interface{}{
+			"active": unit.ActiveState == "active",
+			"failed": unit.ActiveState == "failed",
+		}
+		if s.RestartCounts && strings.HasSuffix(unit.Name, ".service") {
+			if restarts, ok := restartCount(conn, unit.Name); ok {
+				fields["restarts"] = restarts
+			}
+		}
+		acc.AddFields(measurement, fields, tags, now)
+	}
+
+	fields := map[string]interface{}{
+		"units":  reported,
+		"failed": failed,
+	}
+	acc.AddFields(summaryMeasurement, fields, map[string]string{}, now)
+	return nil
+}
+
+// restartCount returns the number of automatic restarts of service, as
+// counted by systemd 235 and later.
+func restartCount(conn *dbus.Conn, service string) (int64, bool) {
+	property, err := conn.GetServiceProperty(service, "NRestarts")
+	if err != nil {
+		return 0, false
+	}
+	restarts, ok := property.Value.Value().(uint32)
+	if !ok {
+		return 0, false
+	}
+	return int64(restarts), true
+}
@@ -0,0 +1,171 @@
+package thermal_throttle
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const (
+	zoneMeasurement     = `thermal_zone`
+	throttleMeasurement = `thermal_throttle`
+)
+
+// throttleCounters lists the files of the thermal_throttle directory of
+// each CPU, which expose the throttle counters kept by the kernel from the
+// thermal interrupts of the processor.
+var throttleCounters = []string{
+	"core_throttle_count",
+	"core_throttle_total_time_ms",
+	"package_throttle_count",
+	"package_throttle_total_time_ms",
+}
+
+// ThermalThrottle reports the temperature of the thermal zones and the
+// thermal throttling of the CPUs of the host.
+type ThermalThrottle struct {
+	sysRoot   string
+	Throttles bool
+}
+
+// init initializes the package.
+func init() {
+	inputs.Add("thermal_throttle", func() telegraf.Input {
+		return newThermalThrottle("/sys")
+	})
+}
+
+// newThermalThrottle returns a pointer to a new ThermalThrottle object
+// reading the files in sysRoot.
+func newThermalThrottle(sysRoot string) *ThermalThrottle {
+	return &ThermalThrottle{
+		sysRoot:   sysRoot,
+		Throttles: true,
+	}
+}
+
+// Description returns a short description about the plugin.
+func (t *ThermalThrottle) Description() string {
+	return "Read the temperature of the thermal zones and the CPU throttle counters."
+}
+
+// SampleConfig returns a sample configuration for the plugin.
+func (t *ThermalThrottle) SampleConfig() string {
+	return `
+	## Read the thermal throttle counters of each CPU, available on x86
+	## processors only.
+	#throttles = true
+	`
+}
+
+// Gather reads the thermal zones and throttle counters and stores them in
+// the accumulator acc.
+func (t *ThermalThrottle) Gather(acc telegraf.Accumulator) error {
+	now := time.Now().UTC()
+
+	if err := t.gatherZones(acc, now); err != nil {
+		acc.AddError(err)
+		return fmt.Errorf("thermal_throttle: unable to gather metrics: %s", err)
+	}
+	if !t.Throttles {
+		return nil
+	}
+	if err := t.gatherThrottles(acc, now); err != nil {
+		acc.AddError(err)
+		return fmt.Errorf("thermal_throttle: unable to gather metrics: %s", err)
+	}
+
+	return nil
+}
+
+// gatherZones reads the temperature of every zone of /sys/class/thermal.
+func (t *ThermalThrottle) gatherZones(acc telegraf.Accumulator, now time.Time) error {
+	zones, err := filepath.Glob(filepath.Join(t.sysRoot, "class/thermal/thermal_zone*"))
+	if err != nil {
+		return err
+	}
+
+	for _, zone := range zones {
+		// The temperature is in millidegrees Celsius, and reading it fails
+		// for zones whose sensor is disabled.
+		temp, err := readInt(filepath.Join(zone, "temp"))
+		if err != nil {
+			continue
+		}
+		tags := map[string]string{"zone": filepath.Base(zone)}
+		if kind, err := readString(filepath.Join(zone, "type")); err == nil {
+			tags["type"] = kind
+		}
+		fields := map[string]interface{}{
+			"temp_c": float64(temp) / 1000,
+		}
+		acc.AddFields(zoneMeasurement, fields, tags, now)
+	}
+
+	return nil
+}
+
+// gatherThrottles reads the throttle counters of every CPU.
+func (t *ThermalThrottle) gatherThrottles(acc telegraf.Accumulator, now time.Time) error {
+	cpus, err := filepath.Glob(filepath.Join(t.sysRoot, "devices/system/cpu/cpu[0-9]*"))
+	if err != nil {
+		return err
+	}
+
+	for _, cpu := range cpus {
+		fields := make(map[string]interface{})
+		for _, counter := range throttleCounters {
+			value, err := readInt(filepath.Join(cpu, "thermal_throttle", counter))
+			if err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+				return err
+			}
+			fields[counter] = value
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		tags := map[string]string{"cpu": filepath.Base(cpu)}
+		if pkg, err := readString(filepath.Join(cpu, "topology/physical_package_id")); err == nil {
+			tags["package"] = pkg
+		}
+		acc.AddFields(throttleMeasurement, fields, tags, now)
+	}
+
+	return nil
+}
+
+// readString reads the file path, stripped of its trailing newline.
+func readString(path string) (string, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(content)), nil
+}
+
+// readInt reads the integer held by the file path.
+func readInt(path string) (int64, error) {
+	content, err := readString(path)
+	if err != nil {
+		return 0, err
+	}
+
+	value, err := strconv.ParseInt(content, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %s", path, err)
+	}
+
+	return value, nil
+}
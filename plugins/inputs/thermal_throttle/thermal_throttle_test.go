@@ -0,0 +1,92 @@
+package thermal_throttle
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestGather(t *testing.T) {
+	// testdata/sys is laid out as the sysfs of a two package x86 host, the
+	// sensor of thermal_zone2 being disabled, cpu1 running a kernel before
+	// 5.9 and cpu2 lacking throttle counters.
+	zones := []telegraf.Metric{
+		testutil.MustMetric(zoneMeasurement,
+			map[string]string{"zone": "thermal_zone0", "type": "acpitz"},
+			map[string]interface{}{"temp_c": 27.8}, time.Unix(0, 0)),
+		testutil.MustMetric(zoneMeasurement,
+			map[string]string{"zone": "thermal_zone1", "type": "x86_pkg_temp"},
+			map[string]interface{}{"temp_c": 55.0}, time.Unix(0, 0)),
+	}
+	throttles := []telegraf.Metric{
+		testutil.MustMetric(throttleMeasurement,
+			map[string]string{"cpu": "cpu0", "package": "0"},
+			map[string]interface{}{
+				"core_throttle_count":            int64(12),
+				"core_throttle_total_time_ms":    int64(3561),
+				"package_throttle_count":         int64(40),
+				"package_throttle_total_time_ms": int64(20715),
+			}, time.Unix(0, 0)),
+		testutil.MustMetric(throttleMeasurement,
+			map[string]string{"cpu": "cpu1", "package": "0"},
+			map[string]interface{}{
+				"core_throttle_count":    int64(0),
+				"package_throttle_count": int64(40),
+			}, time.Unix(0, 0)),
+	}
+
+	tests := []struct {
+		name      string
+		dir       string
+		throttles bool
+		expected  []telegraf.Metric
+		err       bool
+	}{
+		{
+			name:      "zones and throttles",
+			dir:       "testdata/sys",
+			throttles: true,
+			expected:  append(append([]telegraf.Metric{}, zones...), throttles...),
+		},
+		{
+			name:     "zones only",
+			dir:      "testdata/sys",
+			expected: zones,
+		},
+		{
+			name:      "invalid counter",
+			dir:       "testdata/invalid",
+			throttles: true,
+			err:       true,
+		},
+		{
+			// Hosts without sensors, such as virtual machines.
+			name:      "missing",
+			dir:       "testdata/missing",
+			throttles: true,
+			expected:  []telegraf.Metric{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newThermalThrottle(tt.dir)
+			p.Throttles = tt.throttles
+
+			var acc testutil.Accumulator
+			err := p.Gather(&acc)
+			if tt.err {
+				if err == nil || len(acc.Errors) != 1 {
+					t.Errorf("error %v, errors %v", err, acc.Errors)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			testutil.RequireMetricsEqual(t, tt.expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+		})
+	}
+}
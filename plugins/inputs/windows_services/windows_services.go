@@ -0,0 +1,219 @@
+//go:build windows
+// +build windows
+
+package windows_services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const measurement = `windows_services`
+
+// stateNames maps the states of the services to the names used as fields.
+var stateNames = map[svc.State]string{
+	svc.Stopped:         "stopped",
+	svc.StartPending:    "start_pending",
+	svc.StopPending:     "stop_pending",
+	svc.Running:         "running",
+	svc.ContinuePending: "continue_pending",
+	svc.PausePending:    "pause_pending",
+	svc.Paused:          "paused",
+}
+
+// startTypes maps the start types of the services to the names used as
+// fields.
+var startTypes = map[uint32]string{
+	windows.SERVICE_BOOT_START:   "boot",
+	windows.SERVICE_SYSTEM_START: "system",
+	mgr.StartAutomatic:           "auto",
+	mgr.StartManual:              "manual",
+	mgr.StartDisabled:            "disabled",
+}
+
+// WindowsServices reports the state of the Windows services and the
+// resource usage of their processes.
+type WindowsServices struct {
+	ServiceNames []string
+	ProcessStats bool
+
+	connect       func() (serviceManager, error)
+	compiled      bool
+	serviceFilter filter.Filter
+}
+
+// serviceManager is the part of the service control manager the plugin
+// uses, so that tests can fake it.
+type serviceManager interface {
+	ListServices() ([]string, error)
+	OpenService(name string) (service, error)
+	Disconnect() error
+}
+
+// service is the part of a service the plugin uses.
+type service interface {
+	Query() (svc.Status, error)
+	Config() (mgr.Config, error)
+	Close() error
+}
+
+// scm is the service control manager of the host.
+type scm struct {
+	*mgr.Mgr
+}
+
+// connectSCM connects to the service control manager of the host.
+func connectSCM() (serviceManager, error) {
+	m, err := mgr.Connect()
+	if err != nil {
+		return nil, err
+	}
+	return scm{m}, nil
+}
+
+// OpenService opens the service name.
+func (m scm) OpenService(name string) (service, error) {
+	return m.Mgr.OpenService(name)
+}
+
+// init initializes the package.
+func init() {
+	inputs.Add("windows_services", func() telegraf.Input {
+		return newWindowsServices()
+	})
+}
+
+// newWindowsServices returns a pointer to a new WindowsServices object.
+func newWindowsServices() *WindowsServices {
+	return &WindowsServices{
+		ProcessStats: true,
+		connect:      connectSCM,
+	}
+}
+
+// Description returns a short description about the plugin.
+func (w *WindowsServices) Description() string {
+	return "Read the state of the Windows services and the resource usage of their processes."
+}
+
+// SampleConfig returns a sample configuration for the plugin.
+func (w *WindowsServices) SampleConfig() string {
+	return `
+	## Names of the services to report; glob patterns are supported. Every
+	## service is reported if empty.
+	#service_names = ["MSSQL*", "W3SVC"]
+
+	## Read the cpu time, memory and threads of the process of each running
+	## service.
+	#process_stats = true
+	`
+}
+
+// Gather queries the service control manager for the state of the services
+// and stores it in the accumulator acc.
+func (w *WindowsServices) Gather(acc telegraf.Accumulator) error {
+	if !w.compiled {
+		var err error
+		w.serviceFilter, err = filter.Compile(w.ServiceNames)
+		if err != nil {
+			acc.AddError(err)
+			return fmt.Errorf("windows_services: invalid service_names: %s", err)
+		}
+		w.compiled = true
+	}
+
+	m, err := w.connect()
+	if err != nil {
+		acc.AddError(err)
+		return fmt.Errorf("windows_services: unable to connect to the service control manager: %s", err)
+	}
+	defer m.Disconnect()
+
+	names, err := m.ListServices()
+	if err != nil {
+		acc.AddError(err)
+		return fmt.Errorf("windows_services: unable to gather metrics: %s", err)
+	}
+
+	now := time.Now().UTC()
+	for _, name := range names {
+		if w.serviceFilter != nil && !w.serviceFilter.Match(name) {
+			continue
+		}
+		if err := w.gatherService(acc, m, name, now); err != nil {
+			acc.AddError(fmt.Errorf("windows_services: service %s: %s", name, err))
+		}
+	}
+
+	return nil
+}
+
+// gatherService queries the state and configuration of the service name.
+func (w *WindowsServices) gatherService(acc telegraf.Accumulator, m serviceManager, name string, now time.Time) error {
+	s, err := m.OpenService(name)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	status, err := s.Query()
+	if err != nil {
+		return err
+	}
+	config, err := s.Config()
+	if err != nil {
+		return err
+	}
+
+	startType := startTypes[config.StartType]
+	if config.StartType == mgr.StartAutomatic && config.DelayedAutoStart {
+		startType = "auto_delayed"
+	}
+	fields := map[string]interface{}{
+		"state":      int(status.State),
+		"state_name": stateNames[status.State],
+		"start_type": startType,
+		"pid":        int64(status.ProcessId),
+		"exit_code":  int64(status.Win32ExitCode),
+	}
+	if w.ProcessStats && status.ProcessId != 0 {
+		addProcessStats(fields, int32(status.ProcessId))
+	}
+
+	tags := map[string]string{
+		"service_name": name,
+		"display_name": config.DisplayName,
+	}
+	acc.AddFields(measurement, fields, tags, now)
+
+	return nil
+}
+
+// addProcessStats adds to fields the resource usage of the process pid. As
+// services may share a process, the usage is that of the whole process.
+func addProcessStats(fields map[string]interface{}, pid int32) {
+	p, err := process.NewProcess(pid)
+	if err != nil {
+		return
+	}
+	if times, err := p.Times(); err == nil {
+		fields["cpu_time_user"] = times.User
+		fields["cpu_time_system"] = times.System
+	}
+	if memory, err := p.MemoryInfo(); err == nil {
+		fields["memory_rss"] = memory.RSS
+		fields["memory_vms"] = memory.VMS
+	}
+	if threads, err := p.NumThreads(); err == nil {
+		fields["threads"] = threads
+	}
+}
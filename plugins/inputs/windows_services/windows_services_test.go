@@ -0,0 +1,163 @@
+//go:build windows
+// +build windows
+
+package windows_services
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"syscall"
+	"testing"
+
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+
+	"github.com/influxdata/telegraf/testutil"
+)
+
+// fakeManager is a service control manager holding the services.
+type fakeManager struct {
+	services map[string]*fakeService
+	listErr  error
+}
+
+// fakeService is a service whose status and configuration queries return
+// status and config, or err.
+type fakeService struct {
+	status svc.Status
+	config mgr.Config
+	err    error
+}
+
+func (m *fakeManager) ListServices() ([]string, error) {
+	var names []string
+	for name := range m.services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, m.listErr
+}
+
+func (m *fakeManager) OpenService(name string) (service, error) {
+	s, ok := m.services[name]
+	if !ok {
+		return nil, errors.New("the specified service does not exist")
+	}
+	return s, nil
+}
+
+func (m *fakeManager) Disconnect() error { return nil }
+
+func (s *fakeService) Query() (svc.Status, error)  { return s.status, s.err }
+func (s *fakeService) Config() (mgr.Config, error) { return s.config, s.err }
+func (s *fakeService) Close() error                { return nil }
+
+// testManager holds the services of a host running IIS and SQL Server.
+var testManager = &fakeManager{services: map[string]*fakeService{
+	"W3SVC": {
+		status: svc.Status{State: svc.Running, ProcessId: 4242},
+		config: mgr.Config{DisplayName: "World Wide Web Publishing Service", StartType: mgr.StartAutomatic},
+	},
+	"MSSQLSERVER": {
+		status: svc.Status{State: svc.Running, ProcessId: 3131},
+		config: mgr.Config{DisplayName: "SQL Server (MSSQLSERVER)", StartType: mgr.StartAutomatic, DelayedAutoStart: true},
+	},
+	"MSSQLFDLauncher": {
+		status: svc.Status{State: svc.Stopped, Win32ExitCode: 1067},
+		config: mgr.Config{DisplayName: "SQL Full-text Filter Daemon Launcher (MSSQLSERVER)", StartType: mgr.StartManual},
+	},
+	"Fax": {
+		status: svc.Status{State: svc.Stopped, Win32ExitCode: 1077},
+		config: mgr.Config{DisplayName: "Fax", StartType: mgr.StartDisabled},
+	},
+	"MSSQLBroken": {err: syscall.ERROR_ACCESS_DENIED},
+}}
+
+func TestGather(t *testing.T) {
+	tests := []struct {
+		name     string
+		services []string
+		expected map[string]map[string]interface{}
+		errors   int
+	}{
+		{
+			name:     "sql server",
+			services: []string{"MSSQL*"},
+			expected: map[string]map[string]interface{}{
+				"MSSQLSERVER": {
+					"state": int(svc.Running), "state_name": "running", "start_type": "auto_delayed",
+					"pid": int64(3131), "exit_code": int64(0),
+				},
+				"MSSQLFDLauncher": {
+					"state": int(svc.Stopped), "state_name": "stopped", "start_type": "manual",
+					"pid": int64(0), "exit_code": int64(1067),
+				},
+			},
+			errors: 1,
+		},
+		{
+			name:     "exact names",
+			services: []string{"W3SVC", "Fax"},
+			expected: map[string]map[string]interface{}{
+				"W3SVC": {
+					"state": int(svc.Running), "state_name": "running", "start_type": "auto",
+					"pid": int64(4242), "exit_code": int64(0),
+				},
+				"Fax": {
+					"state": int(svc.Stopped), "state_name": "stopped", "start_type": "disabled",
+					"pid": int64(0), "exit_code": int64(1077),
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newWindowsServices()
+			w.ServiceNames = tt.services
+			w.ProcessStats = false
+			w.connect = func() (serviceManager, error) { return testManager, nil }
+
+			var acc testutil.Accumulator
+			if err := w.Gather(&acc); err != nil {
+				t.Fatal(err)
+			}
+			if len(acc.Errors) != tt.errors {
+				t.Errorf("errors %v, expected %d", acc.Errors, tt.errors)
+			}
+
+			services := make(map[string]map[string]interface{})
+			for _, m := range acc.Metrics {
+				services[m.Tags["service_name"]] = m.Fields
+				if name := testManager.services[m.Tags["service_name"]].config.DisplayName; m.Tags["display_name"] != name {
+					t.Errorf("display name %q, expected %q", m.Tags["display_name"], name)
+				}
+			}
+			if !reflect.DeepEqual(services, tt.expected) {
+				t.Errorf("\n got %v\nwant %v", services, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGatherErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		connect func() (serviceManager, error)
+	}{
+		{"connect", func() (serviceManager, error) { return nil, syscall.ERROR_ACCESS_DENIED }},
+		{"list", func() (serviceManager, error) {
+			return &fakeManager{listErr: errors.New("RPC server unavailable")}, nil
+		}},
+	}
+
+	for _, tt := range tests {
+		w := newWindowsServices()
+		w.connect = tt.connect
+		var acc testutil.Accumulator
+		if err := w.Gather(&acc); err == nil || len(acc.Errors) != 1 {
+			t.Errorf("%s: error %v, errors %v", tt.name, err, acc.Errors)
+		}
+	}
+}
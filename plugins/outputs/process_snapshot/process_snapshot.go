@@ -0,0 +1,314 @@
+package process_snapshot
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+const (
+	filePrefix = `snapshot-`
+	fileSuffix = `.json`
+	gzipSuffix = `.gz`
+	timeLayout = `20060102T150405.000000000Z`
+)
+
+// ProcessSnapshot writes the process tables reported by the ps input to
+// files, one per snapshot, so that what was running at a past moment can be
+// reconstructed.
+type ProcessSnapshot struct {
+	Directory   string
+	Measurement string
+	Interval    internal.Duration
+	Retention   internal.Duration
+	MaxFiles    int
+	Compress    bool
+
+	lastWritten map[string]time.Time
+
+	// pending holds the latest snapshot of each host, written once the
+	// metrics of a later gather arrive or on Close: telegraf splits the
+	// metrics of a gather across writes of metric_batch_size metrics.
+	pending map[string]*snapshot
+}
+
+// snapshot is the process table of a host at a given time.
+type snapshot struct {
+	Time      time.Time         `json:"time"`
+	Host      string            `json:"host,omitempty"`
+	Processes []json.RawMessage `json:"processes"`
+}
+
+// init initializes the package.
+func init() {
+	outputs.Add("process_snapshot", func() telegraf.Output {
+		return newProcessSnapshot()
+	})
+}
+
+// newProcessSnapshot returns a pointer to a new ProcessSnapshot object.
+func newProcessSnapshot() *ProcessSnapshot {
+	return &ProcessSnapshot{
+		Directory:   "/var/lib/telegraf/process_snapshots",
+		Measurement: "ps",
+		Interval:    internal.Duration{Duration: time.Minute},
+		Retention:   internal.Duration{Duration: time.Hour * 24 * 7},
+		Compress:    true,
+		lastWritten: make(map[string]time.Time),
+		pending:     make(map[string]*snapshot),
+	}
+}
+
+// Description returns a short description about the plugin.
+func (p *ProcessSnapshot) Description() string {
+	return "Write the process tables of the ps input to files, one per snapshot."
+}
+
+// SampleConfig returns a sample configuration for the plugin.
+func (p *ProcessSnapshot) SampleConfig() string {
+	return `
+	## Directory the snapshots are written to.
+	#directory = "/var/lib/telegraf/process_snapshots"
+
+	## Measurement of the ps input metrics, in the per_process or the
+	## legacy_json format.
+	#measurement = "ps"
+
+	## Minimum time between two snapshots; the process tables gathered in
+	## between are not written.
+	#interval = "1m"
+
+	## Age after which snapshots are deleted; 0 keeps them forever.
+	#retention = "168h"
+
+	## Maximum number of snapshots kept, the oldest being deleted first;
+	## 0 means no limit.
+	#max_files = 0
+
+	## Compress the snapshots with gzip.
+	#compress = true
+	`
+}
+
+// Connect creates the directory of the snapshots.
+func (p *ProcessSnapshot) Connect() error {
+	if err := os.MkdirAll(p.Directory, 0750); err != nil {
+		return fmt.Errorf("process_snapshot: unable to create directory: %s", err)
+	}
+	return nil
+}
+
+// Close writes the pending snapshots, which no later gather completes.
+func (p *ProcessSnapshot) Close() error {
+	hosts := make([]string, 0, len(p.pending))
+	for host := range p.pending {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	for _, host := range hosts {
+		s := p.pending[host]
+		delete(p.pending, host)
+		if err := p.flush(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write groups the process metrics into snapshots by host and time, and
+// deletes the snapshots past their retention. The metrics sharing the time
+// of the pending snapshot of their host are added to it; a later time
+// completes the pending snapshot, written if due, and starts the next one.
+// Metrics older than the pending snapshot arrive too late and are dropped.
+func (p *ProcessSnapshot) Write(metrics []telegraf.Metric) error {
+	snapshots := make(map[string]*snapshot)
+	for _, m := range metrics {
+		if m.Name() != p.Measurement {
+			continue
+		}
+		host, _ := m.GetTag("host")
+		key := host + "/" + m.Time().UTC().Format(timeLayout)
+		s, ok := snapshots[key]
+		if !ok {
+			s = &snapshot{Time: m.Time().UTC(), Host: host}
+			snapshots[key] = s
+		}
+		if err := addProcesses(s, m); err != nil {
+			return fmt.Errorf("process_snapshot: %s", err)
+		}
+	}
+
+	keys := make([]string, 0, len(snapshots))
+	for key := range snapshots {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return snapshots[keys[i]].Time.Before(snapshots[keys[j]].Time)
+	})
+	for _, key := range keys {
+		s := snapshots[key]
+		pending, ok := p.pending[s.Host]
+		switch {
+		case ok && s.Time.Equal(pending.Time):
+			if p.due(pending) {
+				pending.Processes = append(pending.Processes, s.Processes...)
+			}
+			continue
+		case ok && s.Time.Before(pending.Time):
+			continue
+		}
+
+		// The pending snapshot is dropped even if it cannot be written,
+		// so that the metrics sent again are not added to it twice.
+		delete(p.pending, s.Host)
+		if ok {
+			if err := p.flush(pending); err != nil {
+				return err
+			}
+		}
+		if !p.due(s) {
+			s.Processes = nil
+		}
+		p.pending[s.Host] = s
+	}
+
+	if err := p.prune(time.Now()); err != nil {
+		return fmt.Errorf("process_snapshot: unable to delete snapshots: %s", err)
+	}
+	return nil
+}
+
+// due reports whether s is written, interval after the latest snapshot of
+// its host. The processes of the snapshots not due are not kept.
+func (p *ProcessSnapshot) due(s *snapshot) bool {
+	return s.Time.Sub(p.lastWritten[s.Host]) >= p.Interval.Duration
+}
+
+// flush writes the complete snapshot s if it is due.
+func (p *ProcessSnapshot) flush(s *snapshot) error {
+	if len(s.Processes) == 0 || !p.due(s) {
+		return nil
+	}
+	if err := p.writeSnapshot(s); err != nil {
+		return fmt.Errorf("process_snapshot: unable to write snapshot: %s", err)
+	}
+	p.lastWritten[s.Host] = s.Time
+	return nil
+}
+
+// addProcesses adds to s the processes of the metric m: the table held by
+// the fields field of the legacy_json format, or the single process of a
+// per_process metric.
+func addProcesses(s *snapshot, m telegraf.Metric) error {
+	if table, ok := m.GetField("fields"); ok {
+		if table, ok := table.(string); ok && !m.HasTag("pid") {
+			var processes []json.RawMessage
+			if err := json.Unmarshal([]byte(table), &processes); err != nil {
+				return fmt.Errorf("unable to decode process table: %s", err)
+			}
+			s.Processes = append(s.Processes, processes...)
+			return nil
+		}
+	}
+	if !m.HasTag("pid") {
+		return nil
+	}
+
+	process := make(map[string]interface{}, len(m.Tags())+len(m.Fields()))
+	for key, value := range m.Tags() {
+		if key != "host" {
+			process[key] = value
+		}
+	}
+	for key, value := range m.Fields() {
+		process[key] = value
+	}
+	encoded, err := json.Marshal(process)
+	if err != nil {
+		return err
+	}
+	s.Processes = append(s.Processes, encoded)
+	return nil
+}
+
+// writeSnapshot writes s to a temporary file renamed once complete, so that
+// readers never see a partial snapshot.
+func (p *ProcessSnapshot) writeSnapshot(s *snapshot) error {
+	name := filePrefix + s.Time.Format(timeLayout)
+	if s.Host != "" {
+		name += "-" + strings.Replace(s.Host, string(filepath.Separator), "_", -1)
+	}
+	name += fileSuffix
+	if p.Compress {
+		name += gzipSuffix
+	}
+
+	f, err := ioutil.TempFile(p.Directory, ".tmp-"+filePrefix)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	var w io.Writer = f
+	var gz *gzip.Writer
+	if p.Compress {
+		gz = gzip.NewWriter(f)
+		w = gz
+	}
+	if err := json.NewEncoder(w).Encode(s); err != nil {
+		f.Close()
+		return err
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), filepath.Join(p.Directory, name))
+}
+
+// prune deletes the snapshots older than the retention, and the oldest
+// snapshots beyond max_files.
+func (p *ProcessSnapshot) prune(now time.Time) error {
+	files, err := ioutil.ReadDir(p.Directory)
+	if err != nil {
+		return err
+	}
+
+	// The names start with the time of the snapshot, so ReadDir lists the
+	// oldest first.
+	var snapshots []os.FileInfo
+	for _, file := range files {
+		if strings.HasPrefix(file.Name(), filePrefix) && file.Mode().IsRegular() {
+			snapshots = append(snapshots, file)
+		}
+	}
+
+	for i, file := range snapshots {
+		expired := p.Retention.Duration > 0 && now.Sub(file.ModTime()) > p.Retention.Duration
+		excess := p.MaxFiles > 0 && len(snapshots)-i > p.MaxFiles
+		if !expired && !excess {
+			continue
+		}
+		if err := os.Remove(filepath.Join(p.Directory, file.Name())); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
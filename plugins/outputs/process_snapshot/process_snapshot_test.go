@@ -0,0 +1,225 @@
+package process_snapshot
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+// process returns a per_process metric of the ps input gathered at tm on
+// host.
+func process(host, pid, comm string, rss int, tm time.Time) telegraf.Metric {
+	return testutil.MustMetric("ps",
+		map[string]string{"host": host, "pid": pid, "comm": comm},
+		map[string]interface{}{"rss": rss},
+		tm)
+}
+
+// readSnapshots returns the snapshots written to dir by their file name,
+// with their processes as strings.
+func readSnapshots(t *testing.T, dir string) map[string][]string {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshots := make(map[string][]string)
+	for _, file := range files {
+		f, err := os.Open(filepath.Join(dir, file.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var r io.Reader = f
+		if strings.HasSuffix(file.Name(), gzipSuffix) {
+			if r, err = gzip.NewReader(f); err != nil {
+				t.Fatalf("%s: %v", file.Name(), err)
+			}
+		}
+		var s snapshot
+		err = json.NewDecoder(r).Decode(&s)
+		f.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", file.Name(), err)
+		}
+
+		processes := []string{}
+		for _, process := range s.Processes {
+			processes = append(processes, string(process))
+		}
+		snapshots[file.Name()] = processes
+	}
+	return snapshots
+}
+
+func TestWrite(t *testing.T) {
+	start := time.Unix(1600000000, 0).UTC()
+	at := func(d time.Duration) time.Time { return start.Add(d) }
+
+	p := newProcessSnapshot()
+	p.Directory = t.TempDir()
+	p.Compress = false
+	if err := p.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The gathers of web1 split across writes, those of web2 holding the
+	// table of the legacy_json format.
+	writes := [][]telegraf.Metric{
+		{
+			process("web1", "1", "systemd", 11264, at(0)),
+			testutil.MustMetric("cpu", map[string]string{"host": "web1"}, map[string]interface{}{"usage_idle": 99.5}, at(0)),
+		},
+		{
+			process("web1", "4242", "nginx", 524288, at(0)),
+			testutil.MustMetric("ps", map[string]string{"host": "web2"},
+				map[string]interface{}{"fields": `[{"pid":1,"command":"systemd"},{"pid":7,"command":"sshd"}]`}, at(0)),
+		},
+		{
+			// Before the interval elapsed.
+			process("web1", "1", "systemd", 11264, at(30*time.Second)),
+		},
+		{
+			process("web1", "1", "systemd", 11264, at(time.Minute)),
+			// Too late for the pending snapshot.
+			process("web1", "4242", "nginx", 524288, at(30*time.Second)),
+		},
+	}
+	for i, metrics := range writes {
+		if err := p.Write(metrics); err != nil {
+			t.Fatalf("write %d: %v", i, err)
+		}
+	}
+
+	// The snapshots pending are written on close only.
+	expected := map[string][]string{
+		"snapshot-20200913T122640.000000000Z-web1.json": {
+			`{"comm":"systemd","pid":"1","rss":11264}`,
+			`{"comm":"nginx","pid":"4242","rss":524288}`,
+		},
+	}
+	if snapshots := readSnapshots(t, p.Directory); !reflect.DeepEqual(snapshots, expected) {
+		t.Errorf("snapshots before close\n got %v\nwant %v", snapshots, expected)
+	}
+
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	expected["snapshot-20200913T122640.000000000Z-web2.json"] = []string{
+		`{"pid":1,"command":"systemd"}`,
+		`{"pid":7,"command":"sshd"}`,
+	}
+	expected["snapshot-20200913T122740.000000000Z-web1.json"] = []string{
+		`{"comm":"systemd","pid":"1","rss":11264}`,
+	}
+	if snapshots := readSnapshots(t, p.Directory); !reflect.DeepEqual(snapshots, expected) {
+		t.Errorf("snapshots after close\n got %v\nwant %v", snapshots, expected)
+	}
+}
+
+func TestWriteCompressed(t *testing.T) {
+	now := time.Unix(1600000000, 0).UTC()
+	p := newProcessSnapshot()
+	p.Directory = t.TempDir()
+
+	if err := p.Write([]telegraf.Metric{process("", "1", "systemd", 11264, now)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string][]string{
+		"snapshot-20200913T122640.000000000Z.json.gz": {`{"comm":"systemd","pid":"1","rss":11264}`},
+	}
+	if snapshots := readSnapshots(t, p.Directory); !reflect.DeepEqual(snapshots, expected) {
+		t.Errorf("snapshots\n got %v\nwant %v", snapshots, expected)
+	}
+}
+
+func TestWriteInvalidTable(t *testing.T) {
+	p := newProcessSnapshot()
+	p.Directory = t.TempDir()
+
+	err := p.Write([]telegraf.Metric{testutil.MustMetric("ps", nil,
+		map[string]interface{}{"fields": `[{"pid":`}, time.Unix(1600000000, 0))})
+	if err == nil {
+		t.Error("table that cannot be decoded accepted")
+	}
+}
+
+func TestPrune(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+
+	// Snapshots taken every hour for the last five hours.
+	var names []string
+	for i := 5; i > 0; i-- {
+		names = append(names, filePrefix+now.Add(time.Duration(-i)*time.Hour).UTC().Format(timeLayout)+fileSuffix)
+	}
+
+	tests := []struct {
+		name      string
+		retention time.Duration
+		maxFiles  int
+		expected  []string
+	}{
+		{"retention", 3*time.Hour + 30*time.Minute, 0, names[2:]},
+		{"max_files", 0, 2, names[3:]},
+		{"both", 4*time.Hour + 30*time.Minute, 3, names[2:]},
+		{"forever", 0, 0, names},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for i, name := range names {
+				path := filepath.Join(dir, name)
+				if err := ioutil.WriteFile(path, []byte("{}\n"), 0640); err != nil {
+					t.Fatal(err)
+				}
+				tm := now.Add(time.Duration(i-5) * time.Hour)
+				if err := os.Chtimes(path, tm, tm); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// Files other than snapshots are left alone.
+			if err := ioutil.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0640); err != nil {
+				t.Fatal(err)
+			}
+
+			p := newProcessSnapshot()
+			p.Directory = dir
+			p.Retention.Duration = tt.retention
+			p.MaxFiles = tt.maxFiles
+			if err := p.prune(now); err != nil {
+				t.Fatal(err)
+			}
+
+			files, err := ioutil.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			kept := []string{}
+			for _, file := range files {
+				if file.Name() != "notes.txt" {
+					kept = append(kept, file.Name())
+				}
+			}
+			if len(kept) == len(files) {
+				t.Error("notes.txt deleted")
+			}
+			if !reflect.DeepEqual(kept, tt.expected) {
+				t.Errorf("kept %v, expected %v", kept, tt.expected)
+			}
+		})
+	}
+}
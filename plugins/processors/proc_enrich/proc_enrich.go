@@ -0,0 +1,167 @@
+package proc_enrich
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/gpapag/telegraf-plugins/pkg/psinfo"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+// Attributes of the processes the metrics can be tagged with.
+const (
+	attributeComm        = `comm`
+	attributeUser        = `user`
+	attributeUID         = `uid`
+	attributeCgroup      = `cgroup`
+	attributeContainerID = `container_id`
+)
+
+// ProcEnrich tags the metrics carrying a pid with the identity of the
+// process, looked up in the process cache shared with the ps input plugin.
+type ProcEnrich struct {
+	PidTag     string
+	PidField   string
+	Attributes []string
+	TagPrefix  string
+	ProcRoot   string
+
+	cache *psinfo.Cache
+}
+
+// init initializes the package.
+func init() {
+	processors.Add("proc_enrich", func() telegraf.Processor {
+		return newProcEnrich()
+	})
+}
+
+// newProcEnrich returns a pointer to a new ProcEnrich object.
+func newProcEnrich() *ProcEnrich {
+	return &ProcEnrich{
+		PidTag:     "pid",
+		Attributes: []string{attributeComm, attributeUser, attributeCgroup, attributeContainerID},
+	}
+}
+
+// Description returns a short description about the plugin.
+func (p *ProcEnrich) Description() string {
+	return "Tag the metrics carrying a pid with the name, user, cgroup and container of the process."
+}
+
+// SampleConfig returns a sample configuration for the plugin.
+func (p *ProcEnrich) SampleConfig() string {
+	return `
+	## Tag holding the pid of the process.
+	#pid_tag = "pid"
+
+	## Field holding the pid of the process, used when set and the metric
+	## has no pid_tag.
+	#pid_field = ""
+
+	## Attributes of the process to add as tags, among comm, user, uid,
+	## cgroup and container_id.
+	#attributes = ["comm", "user", "cgroup", "container_id"]
+
+	## Prefix of the added tag names, such as "process_".
+	#tag_prefix = ""
+
+	## Mount point of the proc filesystem the pids belong to, such as
+	## "/host/proc" to enrich the metrics of the host from a container; it
+	## must match the proc_root of the ps input plugin for the two to share
+	## a cache. Defaults to the HOST_PROC environment variable, or else
+	## /proc.
+	#proc_root = "/proc"
+	`
+}
+
+// Apply adds the identity of the process to every metric carrying a pid.
+// Tags already present are left alone, as are the metrics whose process
+// no longer exists.
+func (p *ProcEnrich) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	if p.cache == nil {
+		p.cache = psinfo.SharedCache(p.procFS())
+	}
+
+	for _, m := range in {
+		pid, ok := p.pid(m)
+		if !ok {
+			continue
+		}
+		identity, ok := p.cache.Lookup(pid)
+		if !ok {
+			continue
+		}
+
+		for _, attribute := range p.Attributes {
+			var value string
+			switch attribute {
+			case attributeComm:
+				value = identity.Comm
+			case attributeUser:
+				value = identity.User
+			case attributeUID:
+				value = strconv.Itoa(identity.UID)
+			case attributeCgroup:
+				value = identity.Cgroup
+			case attributeContainerID:
+				value = identity.ContainerID
+			}
+			key := p.TagPrefix + attribute
+			if value == "" || m.HasTag(key) {
+				continue
+			}
+			m.AddTag(key, value)
+		}
+	}
+
+	return in
+}
+
+// procFS returns the proc filesystem the pids belong to.
+func (p *ProcEnrich) procFS() psinfo.ProcFS {
+	switch {
+	case p.ProcRoot != "":
+		return psinfo.ProcFS{Root: p.ProcRoot}
+	case os.Getenv("HOST_PROC") != "":
+		return psinfo.ProcFS{Root: os.Getenv("HOST_PROC")}
+	}
+	return psinfo.DefaultProcFS
+}
+
+// pid returns the pid carried by the metric m.
+func (p *ProcEnrich) pid(m telegraf.Metric) (int, bool) {
+	if value, ok := m.GetTag(p.PidTag); ok {
+		pid, err := strconv.Atoi(value)
+		return pid, err == nil && pid > 0
+	}
+	if p.PidField == "" {
+		return 0, false
+	}
+
+	value, ok := m.GetField(p.PidField)
+	if !ok {
+		return 0, false
+	}
+	var pid int
+	switch v := value.(type) {
+	case int64:
+		pid = int(v)
+	case uint64:
+		pid = int(v)
+	case float64:
+		pid = int(v)
+	case string:
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, false
+		}
+		pid = n
+	default:
+		pid, _ = strconv.Atoi(fmt.Sprint(v))
+	}
+	return pid, pid > 0
+}
@@ -0,0 +1,137 @@
+package proc_enrich
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gpapag/telegraf-plugins/pkg/psinfo"
+	"github.com/gpapag/telegraf-plugins/pkg/psinfo/psinfotest"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+const testContainerID = "4f3b2c1d0e9f8a7b6c5d4e3f2a1b0c9d8e7f6a5b4c3d2e1f0a9b8c7d6e5f4a3b"
+
+// newTestProc returns the root of a synthetic proc filesystem holding an
+// nginx worker run in a container, recorded in the shared cache of the
+// root by a snapshot of the ps input as run by alice.
+func newTestProc(t *testing.T) string {
+	proc := psinfotest.NewProc(t, psinfotest.Host{Uptime: 1000, BootTime: 1600000000, MemTotal: 8 << 20})
+	proc.Add(psinfotest.Process{
+		Pid: 4242, Ppid: 1, Comm: "nginx", Args: []string{"nginx"},
+		Ruid: 1000, Euid: 1000,
+		Cgroup: "/system.slice/docker-" + testContainerID + ".scope",
+	})
+	psinfo.SharedCache(proc.ProcFS()).Update([]psinfo.Process{{Pid: 4242, Comm: "nginx", Ruser: "alice", Ruid: 1000}})
+	return proc.Root
+}
+
+func TestApply(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	cgroup := "/system.slice/docker-" + testContainerID + ".scope"
+
+	tests := []struct {
+		name     string
+		enrich   func(p *ProcEnrich)
+		in       telegraf.Metric
+		expected telegraf.Metric
+	}{
+		{
+			name: "pid tag",
+			in: testutil.MustMetric("procstat",
+				map[string]string{"pid": "4242"},
+				map[string]interface{}{"cpu_usage": 1.5}, now),
+			expected: testutil.MustMetric("procstat",
+				map[string]string{
+					"pid": "4242", "comm": "nginx", "user": "alice",
+					"cgroup": cgroup, "container_id": testContainerID,
+				},
+				map[string]interface{}{"cpu_usage": 1.5}, now),
+		},
+		{
+			name: "tags present kept",
+			in: testutil.MustMetric("procstat",
+				map[string]string{"pid": "4242", "user": "www-data"},
+				map[string]interface{}{"cpu_usage": 1.5}, now),
+			expected: testutil.MustMetric("procstat",
+				map[string]string{
+					"pid": "4242", "comm": "nginx", "user": "www-data",
+					"cgroup": cgroup, "container_id": testContainerID,
+				},
+				map[string]interface{}{"cpu_usage": 1.5}, now),
+		},
+		{
+			name: "pid field with prefix and uid",
+			enrich: func(p *ProcEnrich) {
+				p.PidField = "pid"
+				p.Attributes = []string{attributeComm, attributeUID}
+				p.TagPrefix = "process_"
+			},
+			in: testutil.MustMetric("syscalls", nil,
+				map[string]interface{}{"pid": int64(4242), "count": int64(7)}, now),
+			expected: testutil.MustMetric("syscalls",
+				map[string]string{"process_comm": "nginx", "process_uid": "1000"},
+				map[string]interface{}{"pid": int64(4242), "count": int64(7)}, now),
+		},
+		{
+			name:   "pid field as a string",
+			enrich: func(p *ProcEnrich) { p.PidField = "pid"; p.Attributes = []string{attributeComm} },
+			in: testutil.MustMetric("syscalls", nil,
+				map[string]interface{}{"pid": "4242"}, now),
+			expected: testutil.MustMetric("syscalls",
+				map[string]string{"comm": "nginx"},
+				map[string]interface{}{"pid": "4242"}, now),
+		},
+		{
+			name: "process gone",
+			in: testutil.MustMetric("procstat",
+				map[string]string{"pid": "5000"},
+				map[string]interface{}{"cpu_usage": 1.5}, now),
+			expected: testutil.MustMetric("procstat",
+				map[string]string{"pid": "5000"},
+				map[string]interface{}{"cpu_usage": 1.5}, now),
+		},
+		{
+			// The pid is of a process of another proc filesystem, such as
+			// that of the container of Telegraf.
+			name:   "other proc root",
+			enrich: func(p *ProcEnrich) { p.ProcRoot = t.TempDir() },
+			in: testutil.MustMetric("procstat",
+				map[string]string{"pid": "4242"},
+				map[string]interface{}{"cpu_usage": 1.5}, now),
+			expected: testutil.MustMetric("procstat",
+				map[string]string{"pid": "4242"},
+				map[string]interface{}{"cpu_usage": 1.5}, now),
+		},
+		{
+			name: "invalid pid",
+			in: testutil.MustMetric("procstat",
+				map[string]string{"pid": "-1"},
+				map[string]interface{}{"cpu_usage": 1.5}, now),
+			expected: testutil.MustMetric("procstat",
+				map[string]string{"pid": "-1"},
+				map[string]interface{}{"cpu_usage": 1.5}, now),
+		},
+		{
+			name: "no pid",
+			in: testutil.MustMetric("cpu",
+				map[string]string{"cpu": "cpu0"},
+				map[string]interface{}{"pid": int64(4242)}, now),
+			expected: testutil.MustMetric("cpu",
+				map[string]string{"cpu": "cpu0"},
+				map[string]interface{}{"pid": int64(4242)}, now),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newProcEnrich()
+			p.ProcRoot = newTestProc(t)
+			if tt.enrich != nil {
+				tt.enrich(p)
+			}
+			testutil.RequireMetricsEqual(t, []telegraf.Metric{tt.expected}, p.Apply(tt.in))
+		})
+	}
+}
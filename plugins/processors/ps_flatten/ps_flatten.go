@@ -0,0 +1,124 @@
+package ps_flatten
+
+import (
+	"encoding/json"
+	"log"
+	"strconv"
+
+	"github.com/gpapag/telegraf-plugins/pkg/psinfo"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+// PSFlatten expands the process table the ps input plugin encodes as json
+// in its legacy format into one metric per process, as the per_process
+// format reports them.
+type PSFlatten struct {
+	Measurement  string
+	Field        string
+	KeepOriginal bool
+}
+
+// init initializes the package.
+func init() {
+	processors.Add("ps_flatten", func() telegraf.Processor {
+		return newPSFlatten()
+	})
+}
+
+// newPSFlatten returns a pointer to a new PSFlatten object.
+func newPSFlatten() *PSFlatten {
+	return &PSFlatten{
+		Measurement: "ps",
+		Field:       "fields",
+	}
+}
+
+// Description returns a short description about the plugin.
+func (p *PSFlatten) Description() string {
+	return "Expand the json process table of the ps input into one metric per process."
+}
+
+// SampleConfig returns a sample configuration for the plugin.
+func (p *PSFlatten) SampleConfig() string {
+	return `
+	## Measurement and field holding the json process table.
+	#measurement = "ps"
+	#field = "fields"
+
+	## Keep the metric holding the json process table along with the
+	## expanded metrics.
+	#keep_original = false
+	`
+}
+
+// Apply replaces every metric holding a json process table with one metric
+// per process. Other metrics, and those whose table cannot be decoded, are
+// passed through unchanged.
+func (p *PSFlatten) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	out := make([]telegraf.Metric, 0, len(in))
+	for _, m := range in {
+		if m.Name() != p.Measurement {
+			out = append(out, m)
+			continue
+		}
+		value, ok := m.GetField(p.Field)
+		table, isString := value.(string)
+		if !ok || !isString {
+			out = append(out, m)
+			continue
+		}
+
+		var processes []psinfo.Process
+		if err := json.Unmarshal([]byte(table), &processes); err != nil {
+			log.Printf("E! [processors.ps_flatten] unable to decode the process table: %s", err)
+			out = append(out, m)
+			continue
+		}
+
+		if p.KeepOriginal {
+			out = append(out, m)
+		}
+		out = append(out, p.flatten(m, processes)...)
+	}
+
+	return out
+}
+
+// flatten returns one metric per process, tagged like the per_process
+// format of the ps input and with the tags of the original metric m.
+func (p *PSFlatten) flatten(m telegraf.Metric, processes []psinfo.Process) []telegraf.Metric {
+	metrics := make([]telegraf.Metric, 0, len(processes))
+	for _, process := range processes {
+		tags := make(map[string]string, len(m.Tags())+3)
+		for key, value := range m.Tags() {
+			tags[key] = value
+		}
+		tags["pid"] = strconv.Itoa(process.Pid)
+		tags["comm"] = process.Comm
+		tags["user"] = process.Ruser
+
+		fields := map[string]interface{}{
+			"ppid":      process.Ppid,
+			"args":      process.Args,
+			"threads":   process.Nlwp,
+			"rss":       process.Rss,
+			"vsz":       process.Vsz,
+			"mem":       process.Mem,
+			"cpu":       process.CPU,
+			"processor": process.Psr,
+			"status":    process.Stat,
+		}
+
+		flattened, err := metric.New(m.Name(), tags, fields, m.Time())
+		if err != nil {
+			log.Printf("E! [processors.ps_flatten] unable to create metric: %s", err)
+			continue
+		}
+		metrics = append(metrics, flattened)
+	}
+
+	return metrics
+}
@@ -0,0 +1,101 @@
+package ps_flatten
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+// testTable is a process table as encoded by the legacy_json format of the
+// ps input.
+const testTable = `[` +
+	`{"pid":1,"ppid":0,"command":"systemd","args":"/sbin/init splash","threads":1,"rss":11264,"vsize":169352,"mem":0.1,"cpu":0,"processor":3,"user":"root","status":"Ss"},` +
+	`{"pid":4242,"ppid":1,"command":"nginx","args":"nginx: worker process","threads":12,"rss":524288,"vsize":2097152,"mem":3.2,"cpu":45.5,"processor":7,"user":"www-data","status":"Rl+"}` +
+	`]`
+
+func TestApply(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	table := testutil.MustMetric("ps",
+		map[string]string{"plugin": "ps", "host": "web1"},
+		map[string]interface{}{"fields": testTable},
+		now)
+	flattened := []telegraf.Metric{
+		testutil.MustMetric("ps",
+			map[string]string{"plugin": "ps", "host": "web1", "pid": "1", "comm": "systemd", "user": "root"},
+			map[string]interface{}{
+				"ppid": 0, "args": "/sbin/init splash", "threads": 1, "rss": 11264, "vsz": 169352,
+				"mem": 0.1, "cpu": 0.0, "processor": 3, "status": "Ss",
+			},
+			now),
+		testutil.MustMetric("ps",
+			map[string]string{"plugin": "ps", "host": "web1", "pid": "4242", "comm": "nginx", "user": "www-data"},
+			map[string]interface{}{
+				"ppid": 1, "args": "nginx: worker process", "threads": 12, "rss": 524288, "vsz": 2097152,
+				"mem": 3.2, "cpu": 45.5, "processor": 7, "status": "Rl+",
+			},
+			now),
+	}
+	other := testutil.MustMetric("cpu",
+		map[string]string{"cpu": "cpu-total"},
+		map[string]interface{}{"usage_idle": 99.5},
+		now)
+
+	tests := []struct {
+		name     string
+		flatten  func(p *PSFlatten)
+		in       []telegraf.Metric
+		expected []telegraf.Metric
+	}{
+		{
+			name:     "table expanded",
+			in:       []telegraf.Metric{other, table},
+			expected: append([]telegraf.Metric{other}, flattened...),
+		},
+		{
+			name:     "original kept",
+			flatten:  func(p *PSFlatten) { p.KeepOriginal = true },
+			in:       []telegraf.Metric{table},
+			expected: append([]telegraf.Metric{table}, flattened...),
+		},
+		{
+			name: "empty table",
+			in: []telegraf.Metric{testutil.MustMetric("ps", nil,
+				map[string]interface{}{"fields": "[]"}, now)},
+			expected: []telegraf.Metric{},
+		},
+		{
+			name: "table that cannot be decoded passed through",
+			in: []telegraf.Metric{testutil.MustMetric("ps", nil,
+				map[string]interface{}{"fields": `[{"pid":`}, now)},
+			expected: []telegraf.Metric{testutil.MustMetric("ps", nil,
+				map[string]interface{}{"fields": `[{"pid":`}, now)},
+		},
+		{
+			name: "per_process metric passed through",
+			in: []telegraf.Metric{testutil.MustMetric("ps",
+				map[string]string{"pid": "1"},
+				map[string]interface{}{"rss": 11264}, now)},
+			expected: []telegraf.Metric{testutil.MustMetric("ps",
+				map[string]string{"pid": "1"},
+				map[string]interface{}{"rss": 11264}, now)},
+		},
+		{
+			name:     "other measurement",
+			flatten:  func(p *PSFlatten) { p.Measurement = "processes" },
+			in:       []telegraf.Metric{table},
+			expected: []telegraf.Metric{table},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newPSFlatten()
+			if tt.flatten != nil {
+				tt.flatten(p)
+			}
+			testutil.RequireMetricsEqual(t, tt.expected, p.Apply(tt.in...))
+		})
+	}
+}